@@ -0,0 +1,444 @@
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ariel-frischer/claude-clean/display"
+	"github.com/ariel-frischer/claude-clean/parser"
+	"github.com/ariel-frischer/claude-clean/stats"
+	"github.com/fatih/color"
+)
+
+// projectConfigName is the name of the per-project config file, looked up in
+// the working directory and its parents
+const projectConfigName = ".cclean.toml"
+
+// defaultRedaction replaces matches of a redact pattern without a replacement
+const defaultRedaction = "[REDACTED]"
+
+// settings are the options that can be set in config files. Command line
+// flags override them.
+type settings struct {
+	Style       string           `toml:"style"`
+	Verbose     bool             `toml:"verbose"`
+	LineNumbers bool             `toml:"line_numbers"`
+	Timestamps  bool             `toml:"timestamps"`
+	Thinking    bool             `toml:"thinking"`
+	Markdown    bool             `toml:"markdown"`
+	Width       int              `toml:"width"`
+	Full        bool             `toml:"full"`
+	Color       string           `toml:"color"`
+	Theme       string           `toml:"theme"`
+	HideTools   []string         `toml:"hide_tools"`
+	OnlyTools   []string         `toml:"only_tools"`
+	HideTypes   []string         `toml:"hide_types"`
+	OnlyTypes   []string         `toml:"only_types"`
+	ErrorsOnly  bool             `toml:"errors_only"`
+	Depth       int              `toml:"depth"` // levels of subagent nesting shown, -1 for all
+	Truncate    truncateSettings `toml:"truncate"`
+	Redact      []redactSetting  `toml:"redact"`
+
+	// Themes are user-defined themes, mapping roles to color specs. A theme's
+	// "base" is the built-in theme coloring the roles it leaves out.
+	Themes map[string]map[string]string `toml:"themes"`
+
+	// Prices are model prices in USD per million tokens, by model name, for
+	// estimating the cost of saved sessions. They add models or replace the
+	// built-in prices.
+	Prices map[string]priceSetting `toml:"prices"`
+}
+
+type priceSetting struct {
+	Input         float64 `toml:"input"`
+	Output        float64 `toml:"output"`
+	CacheRead     float64 `toml:"cache_read"`
+	CacheCreation float64 `toml:"cache_creation"`
+}
+
+type truncateSettings struct {
+	HeadLines  int  `toml:"head_lines"`
+	TailLines  int  `toml:"tail_lines"`
+	InputChars int  `toml:"input_chars"`
+	LineChars  int  `toml:"line_chars"`
+	FullErrors bool `toml:"full_errors"`
+
+	// Tools override the settings above for the results of tools, by name or
+	// glob pattern
+	Tools map[string]toolTruncateSettings `toml:"tools"`
+}
+
+// toolTruncateSettings are the truncation settings of a tool. Those left out
+// are taken from the truncate settings.
+type toolTruncateSettings struct {
+	HeadLines  *int  `toml:"head_lines"`
+	TailLines  *int  `toml:"tail_lines"`
+	LineChars  *int  `toml:"line_chars"`
+	Full       bool  `toml:"full"`
+	FullErrors *bool `toml:"full_errors"`
+}
+
+type redactSetting struct {
+	Pattern     string `toml:"pattern"`
+	Replacement string `toml:"replacement"`
+}
+
+// styles maps style names to output styles
+var styles = map[string]display.OutputStyle{
+	"default":  display.StyleDefault,
+	"compact":  display.StyleCompact,
+	"minimal":  display.StyleMinimal,
+	"plain":    display.StylePlain,
+	"html":     display.StyleHTML,
+	"markdown": display.StyleMarkdown,
+}
+
+func defaultSettings() settings {
+	return settings{
+		Style:    "default",
+		Markdown: true,
+		Color:    "auto",
+		Theme:    display.DefaultTheme,
+		Depth:    -1,
+		Truncate: truncateSettings{
+			HeadLines:  parser.FirstLines,
+			TailLines:  parser.LastLines,
+			InputChars: display.DefaultInputChars,
+			LineChars:  display.DefaultLineChars,
+			FullErrors: true,
+		},
+	}
+}
+
+// userConfigPath returns the path of the per-user config file
+func userConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "cclean", "config.toml")
+}
+
+// projectConfigPath returns the path of the nearest project config file in
+// dir or its parents, or "" if there is none
+func projectConfigPath(dir string) string {
+	for {
+		path := filepath.Join(dir, projectConfigName)
+		if fileExists(path) {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// loadSettings reads the user config file and then the project config file
+// found from dir, each overriding the settings it contains. It returns the
+// settings and the files that were read.
+func loadSettings(dir string) (settings, []string, error) {
+	s := defaultSettings()
+	var files []string
+	for _, path := range []string{userConfigPath(), projectConfigPath(dir)} {
+		if path == "" || !fileExists(path) {
+			continue
+		}
+		md, err := toml.DecodeFile(path, &s)
+		if err != nil {
+			return s, files, fmt.Errorf("%s: %w", path, err)
+		}
+		if keys := md.Undecoded(); len(keys) > 0 {
+			return s, files, fmt.Errorf("%s: unknown setting %q", path, keys[0].String())
+		}
+		files = append(files, path)
+	}
+	return s, files, nil
+}
+
+// applyFlags overrides settings with the command line flags that were given
+func applyFlags(s *settings) {
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "s":
+			s.Style = *styleFlag
+		case "V":
+			s.Verbose = *verbose
+		case "n":
+			s.LineNumbers = *showLineNum
+		case "t":
+			s.Timestamps = *showTimestamps
+		case "thinking":
+			s.Thinking = *showThinking
+		case "no-markdown":
+			s.Markdown = !*noMarkdown
+		case "width":
+			s.Width = *width
+		case "full":
+			s.Full = *full
+		case "only-type":
+			s.OnlyTypes = splitList(*onlyTypes)
+		case "hide-type":
+			s.HideTypes = splitList(*hideTypes)
+		case "only-tool":
+			s.OnlyTools = splitList(*onlyTools)
+		case "hide-tool":
+			s.HideTools = splitList(*hideTools)
+		case "errors-only":
+			s.ErrorsOnly = *errorsOnly
+		case "depth":
+			s.Depth = *depth
+		case "color":
+			s.Color = *colorFlag
+		case "theme":
+			s.Theme = *themeFlag
+		}
+	})
+}
+
+// splitList splits a comma-separated flag value
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// displayConfig returns the display configuration for the settings
+func (s *settings) displayConfig() (*display.Config, error) {
+	style, ok := styles[s.Style]
+	if !ok {
+		return nil, fmt.Errorf("unknown style: %s", s.Style)
+	}
+
+	cfg := &display.Config{
+		Style:          style,
+		Verbose:        s.Verbose,
+		ShowLineNum:    s.LineNumbers,
+		ShowTimestamps: s.Timestamps,
+		ShowThinking:   s.Thinking,
+		NoMarkdown:     !s.Markdown,
+		Width:          s.Width,
+		HeadLines:      s.Truncate.HeadLines,
+		TailLines:      s.Truncate.TailLines,
+		InputChars:     s.Truncate.InputChars,
+		LineChars:      s.Truncate.LineChars,
+		TruncateErrors: !s.Truncate.FullErrors,
+		Full:           s.Full,
+		HideTools:      s.HideTools,
+		OnlyTools:      s.OnlyTools,
+		HideTypes:      s.HideTypes,
+		OnlyTypes:      s.OnlyTypes,
+		ErrorsOnly:     s.ErrorsOnly,
+	}
+	if s.Depth >= 0 {
+		cfg.HideDepth = s.Depth + 1
+	}
+	for _, typ := range slices.Concat(s.HideTypes, s.OnlyTypes) {
+		if !slices.Contains(display.MessageTypes, typ) {
+			return nil, fmt.Errorf("unknown message type: %s (want %s)", typ, strings.Join(display.MessageTypes, ", "))
+		}
+	}
+	for _, pattern := range slices.Concat(s.HideTools, s.OnlyTools) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("bad tool pattern %q", pattern)
+		}
+	}
+
+	limits := []struct {
+		key   string
+		value int
+	}{
+		{"head_lines", s.Truncate.HeadLines}, {"tail_lines", s.Truncate.TailLines},
+		{"input_chars", s.Truncate.InputChars}, {"line_chars", s.Truncate.LineChars},
+	}
+	for _, l := range limits {
+		if l.value < 0 {
+			return nil, fmt.Errorf("truncate: %s must not be negative", l.key)
+		}
+	}
+
+	// Tool settings are complete policies, taking what they leave out from
+	// the truncate settings, where 0 stands for the default
+	defaults := display.Truncation{
+		HeadLines:      cmp.Or(s.Truncate.HeadLines, parser.FirstLines),
+		TailLines:      cmp.Or(s.Truncate.TailLines, parser.LastLines),
+		LineChars:      cmp.Or(s.Truncate.LineChars, display.DefaultLineChars),
+		TruncateErrors: !s.Truncate.FullErrors,
+	}
+	for name, ts := range s.Truncate.Tools {
+		if _, err := path.Match(name, ""); err != nil {
+			return nil, fmt.Errorf("truncate.tools: bad pattern %q", name)
+		}
+		t := defaults
+		overrides := []struct {
+			key   string
+			value *int
+			dst   *int
+		}{
+			{"head_lines", ts.HeadLines, &t.HeadLines}, {"tail_lines", ts.TailLines, &t.TailLines},
+			{"line_chars", ts.LineChars, &t.LineChars},
+		}
+		for _, l := range overrides {
+			if l.value == nil {
+				continue
+			}
+			if *l.value < 0 {
+				return nil, fmt.Errorf("truncate.tools.%s: %s must not be negative", name, l.key)
+			}
+			*l.dst = *l.value
+		}
+		if ts.FullErrors != nil {
+			t.TruncateErrors = !*ts.FullErrors
+		}
+		t.Full = ts.Full
+		if cfg.TruncateTools == nil {
+			cfg.TruncateTools = make(map[string]display.Truncation)
+		}
+		cfg.TruncateTools[name] = t
+	}
+
+	for _, r := range s.Redact {
+		pattern, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("redact pattern %q: %w", r.Pattern, err)
+		}
+		if r.Replacement == "" {
+			r.Replacement = defaultRedaction
+		}
+		cfg.Redact = append(cfg.Redact, display.Redaction{Pattern: pattern, Replacement: r.Replacement})
+	}
+	return cfg, nil
+}
+
+// setColors turns colors on or off according to the color setting and
+// selects the theme
+func (s *settings) setColors() error {
+	switch s.Color {
+	case "always":
+		color.NoColor = false
+	case "never":
+		color.NoColor = true
+	case "auto":
+		// fatih/color already turns colors off for NO_COLOR, dumb terminals and pipes
+		if os.Getenv("NO_COLOR") == "" && (envSet("CLICOLOR_FORCE") || envSet("FORCE_COLOR")) {
+			color.NoColor = false
+		}
+	default:
+		return fmt.Errorf("unknown color mode: %s (want auto, always or never)", s.Color)
+	}
+
+	theme, err := s.theme()
+	if err != nil {
+		return err
+	}
+	if err := display.SetTheme(theme); err != nil {
+		return fmt.Errorf("theme %s: %w", s.Theme, err)
+	}
+	return nil
+}
+
+// setPrices sets the model prices from the settings
+func (s *settings) setPrices() error {
+	for model, p := range s.Prices {
+		if p.Input < 0 || p.Output < 0 || p.CacheRead < 0 || p.CacheCreation < 0 {
+			return fmt.Errorf("prices.%s: prices must not be negative", model)
+		}
+		stats.SetPrice(model, stats.Price(p))
+	}
+	return nil
+}
+
+// envSet reports whether an environment variable is set to something other than "0"
+func envSet(name string) bool {
+	v := os.Getenv(name)
+	return v != "" && v != "0"
+}
+
+// theme returns the selected theme, built in or user-defined
+func (s *settings) theme() (display.Theme, error) {
+	user, ok := s.Themes[s.Theme]
+	if !ok {
+		if theme, ok := display.Themes[s.Theme]; ok {
+			return theme, nil
+		}
+		return nil, fmt.Errorf("unknown theme: %s (built-in themes: %s)", s.Theme, strings.Join(display.ThemeNames(), ", "))
+	}
+
+	theme := display.Theme{}
+	if base, ok := user["base"]; ok {
+		builtin, ok := display.Themes[base]
+		if !ok {
+			return nil, fmt.Errorf("theme %s: unknown base theme %s", s.Theme, base)
+		}
+		maps.Copy(theme, builtin)
+	}
+	for role, spec := range user {
+		if role != "base" {
+			theme[display.Role(role)] = spec
+		}
+	}
+	return theme, nil
+}
+
+// runConfig implements the config command, which prints the effective settings
+func runConfig(args []string, cfg *display.Config) int {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS] config show\n\n", binaryName())
+		fmt.Fprintln(os.Stderr, "Print the settings in effect after reading the config files and applying OPTIONS.")
+		fmt.Fprintf(os.Stderr, "\nConfig files, later ones overriding earlier ones:\n  %s\n", userConfigPath())
+		fmt.Fprintf(os.Stderr, "  %s in the working directory or its nearest parent that has one\n", projectConfigName)
+	}
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() != 1 || fs.Arg(0) != "show" {
+		fs.Usage()
+		return 2
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	s, files, err := loadSettings(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading config: %v\n", err)
+		return 1
+	}
+	applyFlags(&s)
+
+	if len(files) == 0 {
+		fmt.Println("# No config files found, showing the defaults")
+	}
+	for _, path := range files {
+		fmt.Printf("# From %s\n", path)
+	}
+	if err := toml.NewEncoder(os.Stdout).Encode(s); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
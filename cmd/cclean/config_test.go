@@ -0,0 +1,245 @@
+package main
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ariel-frischer/claude-clean/display"
+	"github.com/ariel-frischer/claude-clean/parser"
+	"github.com/ariel-frischer/claude-clean/stats"
+	"github.com/fatih/color"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadSettings(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	writeFile(t, filepath.Join(home, "cclean", "config.toml"), `
+style = "minimal"
+thinking = true
+hide_tools = ["TodoWrite"]
+
+[truncate]
+head_lines = 5
+
+[[redact]]
+pattern = 'sk-[a-z0-9]+'
+`)
+
+	project := t.TempDir()
+	writeFile(t, filepath.Join(project, ".cclean.toml"), `
+style = "plain"
+hide_tools = ["mcp__*"]
+
+[truncate]
+tail_lines = 2
+`)
+	dir := filepath.Join(project, "src", "pkg")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	s, files, err := loadSettings(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Errorf("read %q, want the user and project files", files)
+	}
+
+	cfg, err := s.displayConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Style != display.StylePlain {
+		t.Errorf("style %q, want the project's plain", cfg.Style)
+	}
+	if !cfg.ShowThinking || cfg.NoMarkdown {
+		t.Errorf("user settings not kept: thinking %v, no markdown %v", cfg.ShowThinking, cfg.NoMarkdown)
+	}
+	if cfg.HeadLines != 5 || cfg.TailLines != 2 || cfg.InputChars != display.DefaultInputChars {
+		t.Errorf("truncation %d/%d/%d, want 5/2/%d", cfg.HeadLines, cfg.TailLines, cfg.InputChars, display.DefaultInputChars)
+	}
+	if strings.Join(cfg.HideTools, ",") != "mcp__*" {
+		t.Errorf("hidden tools %q, want the project's list", cfg.HideTools)
+	}
+	if len(cfg.Redact) != 1 || cfg.Redact[0].Replacement != defaultRedaction {
+		t.Errorf("redactions %+v, want one with the default replacement", cfg.Redact)
+	}
+}
+
+func TestLoadSettingsDefaults(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	s, files, err := loadSettings(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 {
+		t.Errorf("read %q, want no files", files)
+	}
+	cfg, err := s.displayConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Style != display.StyleDefault || cfg.NoMarkdown || cfg.Width != 0 {
+		t.Errorf("unexpected defaults: %+v", cfg)
+	}
+}
+
+func TestTruncateTools(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	writeFile(t, filepath.Join(home, "cclean", "config.toml"), `
+[truncate]
+head_lines = 10
+line_chars = 500
+
+[truncate.tools.Read]
+head_lines = 5
+tail_lines = 0
+
+[truncate.tools.Bash]
+full_errors = false
+`)
+
+	s, _, err := loadSettings(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := s.displayConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]display.Truncation{
+		"Read": {HeadLines: 5, TailLines: 0, LineChars: 500},
+		"Bash": {HeadLines: 10, TailLines: parser.LastLines, LineChars: 500, TruncateErrors: true},
+	}
+	if !maps.Equal(cfg.TruncateTools, expected) {
+		t.Errorf("tool policies %+v, want %+v", cfg.TruncateTools, expected)
+	}
+}
+
+func TestLoadSettingsErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		expected string
+	}{
+		{"unknown key", "colour = \"red\"\n", `unknown setting "colour"`},
+		{"syntax", "style = \n", "config.toml"},
+		{"bad style", "style = \"fancy\"\n", "unknown style: fancy"},
+		{"bad pattern", "[[redact]]\npattern = \"(\"\n", `redact pattern "("`},
+		{"negative limit", "[truncate]\nhead_lines = -1\n", "head_lines must not be negative"},
+		{"negative tool limit", "[truncate.tools.Read]\ntail_lines = -1\n", "truncate.tools.Read: tail_lines"},
+		{"bad tool pattern", "[truncate.tools.\"[\"]\nfull = true\n", `bad pattern "["`},
+		{"bad message type", "only_types = [\"tool\"]\n", "unknown message type: tool"},
+		{"bad hidden tool", "hide_tools = [\"mcp__[\"]\n", `bad tool pattern "mcp__["`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("XDG_CONFIG_HOME", home)
+			writeFile(t, filepath.Join(home, "cclean", "config.toml"), tt.config)
+
+			s, _, err := loadSettings(t.TempDir())
+			if err == nil {
+				_, err = s.displayConfig()
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("got error %v, want one containing %q", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSettingsTheme(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	writeFile(t, filepath.Join(home, "cclean", "config.toml"), `
+theme = "mine"
+color = "never"
+
+[themes.mine]
+base = "light"
+system = "bold #005f87"
+`)
+
+	s, _, err := loadSettings(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	theme, err := s.theme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if theme[display.RoleSystem] != "bold #005f87" || theme[display.RoleText] != display.Themes["light"][display.RoleText] {
+		t.Errorf("theme %v, want light with the system color replaced", theme)
+	}
+
+	noColor := color.NoColor
+	defer func() {
+		color.NoColor = noColor
+		display.SetTheme(display.Themes[display.DefaultTheme])
+	}()
+	if err := s.setColors(); err != nil {
+		t.Fatal(err)
+	}
+	if !color.NoColor {
+		t.Errorf("color = \"never\" left colors on")
+	}
+
+	tests := []struct {
+		settings settings
+		expected string
+	}{
+		{settings{Color: "auto", Theme: "sepia"}, "unknown theme: sepia"},
+		{settings{Color: "auto", Theme: "x", Themes: map[string]map[string]string{"x": {"base": "neon"}}}, "unknown base theme neon"},
+		{settings{Color: "auto", Theme: "x", Themes: map[string]map[string]string{"x": {"header": "red"}}}, `unknown role "header"`},
+		{settings{Color: "sometimes", Theme: "dark"}, "unknown color mode: sometimes"},
+	}
+	for _, tt := range tests {
+		if err := tt.settings.setColors(); err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("setColors() error %v, want one containing %q", err, tt.expected)
+		}
+	}
+}
+
+func TestSettingsPrices(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	writeFile(t, filepath.Join(home, "cclean", "config.toml"), `
+[prices.claude-test]
+input = 2
+output = 8
+`)
+
+	s, _, err := loadSettings(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.setPrices(); err != nil {
+		t.Fatal(err)
+	}
+	if cost, ok := stats.EstimateCost("claude-test-20270101", stats.Tokens{Input: 1_000_000, Output: 1_000_000}); !ok || cost != 10 {
+		t.Errorf("EstimateCost() = %v, %v, want 10, true", cost, ok)
+	}
+
+	s.Prices = map[string]priceSetting{"claude-test": {Input: -1}}
+	if err := s.setPrices(); err == nil {
+		t.Error("negative price accepted")
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// followInterval is how often a followed file is checked for new data
+const followInterval = 200 * time.Millisecond
+
+// follower reads a file that another process is appending to, like tail -f.
+// It never reaches the end of the file: when there is nothing new to read it
+// waits for more. Only complete lines are returned, so a line that is still
+// being written is held back until its newline arrives. A file that is
+// truncated is read again from the start, and a file that is replaced, as by
+// log rotation, is reopened. Stop ends the stream, as when interrupted.
+type follower struct {
+	path        string
+	file        *os.File
+	offset      int64  // bytes read from file
+	partial     []byte // incomplete last line read so far
+	lines       []byte // complete lines not returned yet
+	untilResult bool   // return io.EOF after the result message
+	done        bool
+	interval    time.Duration
+	stop        chan struct{} // closed by Stop
+	stopOnce    sync.Once
+}
+
+// openFollower opens a file to follow. Compressed files, such as those
+// written by --tee with a .gz name, cannot be followed.
+func openFollower(path string, untilResult bool) (*follower, error) {
+	if strings.HasSuffix(path, ".gz") {
+		return nil, fmt.Errorf("%s is compressed and cannot be followed", path)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &follower{path: path, file: file, untilResult: untilResult, interval: followInterval, stop: make(chan struct{})}, nil
+}
+
+func (f *follower) Read(p []byte) (int, error) {
+	for len(f.lines) == 0 {
+		if f.done || f.stopped() {
+			return 0, io.EOF
+		}
+		if err := f.fill(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, f.lines)
+	f.lines = f.lines[n:]
+	return n, nil
+}
+
+// Stop ends the stream: Read returns the complete lines already read and
+// then io.EOF instead of waiting for more. It may be called from another
+// goroutine.
+func (f *follower) Stop() {
+	f.stopOnce.Do(func() { close(f.stop) })
+}
+
+func (f *follower) stopped() bool {
+	select {
+	case <-f.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close closes the file being followed
+func (f *follower) Close() error {
+	return f.file.Close()
+}
+
+// fill reads what has been appended to the file, waiting if there is nothing
+func (f *follower) fill() error {
+	buf := make([]byte, 32*1024)
+	n, err := f.file.Read(buf)
+	if n > 0 {
+		f.offset += int64(n)
+		f.add(buf[:n])
+		return nil
+	}
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	reopened, err := f.check()
+	if err != nil || reopened {
+		return err
+	}
+	select {
+	case <-time.After(f.interval):
+	case <-f.stop:
+	}
+	return nil
+}
+
+// add appends data to the partial line and moves the lines it completes to
+// f.lines, up to the result message with untilResult
+func (f *follower) add(data []byte) {
+	f.partial = append(f.partial, data...)
+	end := bytes.LastIndexByte(f.partial, '\n') + 1
+	if end == 0 {
+		return
+	}
+	complete := f.partial[:end]
+	f.partial = append([]byte(nil), f.partial[end:]...)
+
+	if f.untilResult {
+		for start := 0; start < len(complete); {
+			next := start + bytes.IndexByte(complete[start:], '\n') + 1
+			if isResultLine(complete[start:next]) {
+				complete = complete[:next]
+				f.done = true
+				break
+			}
+			start = next
+		}
+	}
+	f.lines = append(f.lines, complete...)
+}
+
+// check handles the file being truncated or replaced, reporting whether it
+// starts reading again from the beginning of a file
+func (f *follower) check() (bool, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		// The file is being rotated; wait for the new one
+		return false, nil
+	}
+	current, err := f.file.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	switch {
+	case !os.SameFile(info, current):
+		file, err := os.Open(f.path)
+		if err != nil {
+			return false, nil
+		}
+		f.file.Close()
+		f.file = file
+	case info.Size() < f.offset:
+		if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+			return false, err
+		}
+	default:
+		return false, nil
+	}
+	f.offset = 0
+	f.partial = nil
+	return true, nil
+}
+
+// isResultLine reports whether a line of stream-json is the result message
+func isResultLine(line []byte) bool {
+	var msg struct {
+		Type string `json:"type"`
+	}
+	return json.Unmarshal(line, &msg) == nil && msg.Type == "result"
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ariel-frischer/claude-clean/parser"
+)
+
+// followLines decodes the messages read through f and sends their types
+func followLines(f *follower) <-chan string {
+	types := make(chan string)
+	go func() {
+		defer close(types)
+		for ev, err := range parser.NewDecoder(f).All() {
+			if err != nil {
+				return
+			}
+			types <- ev.Message.Type + ":" + ev.Message.Subtype
+		}
+	}()
+	return types
+}
+
+func expectLine(t *testing.T, types <-chan string, expected string) {
+	t.Helper()
+	select {
+	case got, ok := <-types:
+		if !ok {
+			t.Fatalf("stream ended, want %s", expected)
+		}
+		if got != expected {
+			t.Fatalf("got %s, want %s", got, expected)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for %s", expected)
+	}
+}
+
+func appendFile(t *testing.T, path, data string) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFollower(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	writeFile(t, path, `{"type":"system","subtype":"init"}`+"\n")
+
+	f, err := openFollower(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.interval = 5 * time.Millisecond
+	types := followLines(f)
+	expectLine(t, types, "system:init")
+
+	// A line is held back until its newline arrives
+	appendFile(t, path, `{"type":"assistant","sub`)
+	time.Sleep(20 * time.Millisecond)
+	appendFile(t, path, `type":"first"}`+"\n")
+	expectLine(t, types, "assistant:first")
+
+	// Truncation starts over, dropping the partial line
+	appendFile(t, path, `{"type":"assistant"`)
+	time.Sleep(20 * time.Millisecond)
+	writeFile(t, path, `{"type":"user","subtype":"truncated"}`+"\n")
+	expectLine(t, types, "user:truncated")
+
+	// A replaced file is reopened
+	rotated := path + ".new"
+	writeFile(t, rotated, `{"type":"system","subtype":"rotated"}`+"\n")
+	if err := os.Rename(rotated, path); err != nil {
+		t.Fatal(err)
+	}
+	expectLine(t, types, "system:rotated")
+
+	// Nothing after the result message is read
+	appendFile(t, path, `{"type":"result","subtype":"success"}`+"\n"+`{"type":"assistant","subtype":"late"}`+"\n")
+	expectLine(t, types, "result:success")
+	select {
+	case got, ok := <-types:
+		if ok {
+			t.Errorf("got %s after the result", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("follower did not stop after the result")
+	}
+	if n, err := f.Read(make([]byte, 1)); n != 0 || err != io.EOF {
+		t.Errorf("Read after the result = %d, %v, want io.EOF", n, err)
+	}
+}
+
+func TestFollowerStop(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	writeFile(t, path, `{"type":"system","subtype":"init"}`+"\n")
+
+	f, err := openFollower(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.interval = time.Hour
+	types := followLines(f)
+	expectLine(t, types, "system:init")
+
+	// Stopping ends the stream while it waits for more
+	f.Stop()
+	select {
+	case got, ok := <-types:
+		if ok {
+			t.Errorf("got %s after Stop", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("follower did not end after Stop")
+	}
+}
+
+func TestFollowCompressed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl.gz")
+	writeFile(t, path, "")
+	if _, err := openFollower(path, false); err == nil || !strings.Contains(err.Error(), "compressed") {
+		t.Errorf("openFollower(%s) error = %v, want an error about compression", path, err)
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/ariel-frischer/claude-clean/display"
+	"github.com/ariel-frischer/claude-clean/parser"
+	"golang.org/x/term"
+)
+
+// fieldKinds are the kinds of content grep searches, usable as -field values
+var fieldKinds = []string{"text", "thinking", "input", "result"}
+
+// searchField is a piece of decoded message content searched by grep
+type searchField struct {
+	Kind string // one of fieldKinds
+	Tool string // tool name, for inputs and results
+	Key  string // input parameter name
+	Text string
+}
+
+// runGrep implements the grep command, which searches the decoded content of
+// sessions and renders the messages that match
+func runGrep(args []string, cfg *display.Config) int {
+	fs := flag.NewFlagSet("grep", flag.ContinueOnError)
+	ignoreCase := fs.Bool("i", false, "Ignore case")
+	fixed := fs.Bool("F", false, "Match PATTERN as a fixed string instead of a regular expression")
+	fields := fs.String("field", "", "Search only these fields (comma-separated, see below)")
+	context := fs.Int("C", 0, "Show this many messages before and after each match")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS] grep [-i] [-F] [-field LIST] [-C N] PATTERN [FILE...]\n\n", binaryName())
+		fmt.Fprintln(os.Stderr, "Search the text, tool inputs and tool results of sessions and render the")
+		fmt.Fprintln(os.Stderr, "messages that match. Reads from stdin when no FILE is given.")
+		fmt.Fprintln(os.Stderr, "\nOptions:")
+		fs.PrintDefaults()
+		fmt.Fprintln(os.Stderr, "\nFields:")
+		fmt.Fprintln(os.Stderr, "  text, thinking   Assistant and user text, thinking blocks")
+		fmt.Fprintln(os.Stderr, "  input, result    Tool inputs, tool results")
+		fmt.Fprintln(os.Stderr, "  TOOL             Inputs and results of a tool (globs allowed, e.g. mcp__*)")
+		fmt.Fprintln(os.Stderr, "  TOOL.PARAM       One input of a tool, e.g. Bash.command")
+		fmt.Fprintln(os.Stderr, "  TOOL.result      Results of a tool")
+		fmt.Fprintln(os.Stderr, "\nExamples:")
+		fmt.Fprintf(os.Stderr, "  %s grep -i 'permission denied' *.jsonl\n", binaryName())
+		fmt.Fprintf(os.Stderr, "  %s grep -field Bash.command 'git push' session.jsonl\n", binaryName())
+	}
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	pattern := fs.Arg(0)
+	if *fixed {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if *ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid pattern: %v\n", err)
+		return 2
+	}
+	specs := splitList(*fields)
+	for _, spec := range specs {
+		tool, _, _ := strings.Cut(spec, ".")
+		if _, err := path.Match(tool, ""); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid field %q\n", spec)
+			return 2
+		}
+	}
+
+	c := *cfg
+	c.ShowLineNum = true
+	if c.Width <= 0 {
+		if width, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && width > 0 {
+			c.Width = width
+		}
+	}
+	g := &grep{re: re, fields: specs, context: max(*context, 0), cfg: &c}
+
+	files := fs.Args()[1:]
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	found, failed := false, false
+	for _, name := range files {
+		header := ""
+		if len(files) > 1 {
+			header = name
+		}
+		matched, err := g.searchFile(name, os.Stdout, header)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			failed = true
+		}
+		found = found || matched
+	}
+
+	switch {
+	case failed:
+		return 2
+	case !found:
+		return 1
+	}
+	return 0
+}
+
+// grep searches sessions for a pattern
+type grep struct {
+	re      *regexp.Regexp
+	fields  []string // -field specs, empty to search everything
+	context int      // messages shown around each match
+	cfg     *display.Config
+}
+
+// searchFile searches a session file, or stdin for "-"
+func (g *grep) searchFile(name string, w io.Writer, header string) (bool, error) {
+	if name == "-" {
+		return g.search(os.Stdin, w, header)
+	}
+	file, err := openInput(name)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	matched, err := g.search(file, w, header)
+	if err != nil {
+		err = fmt.Errorf("reading %s: %w", name, err)
+	}
+	return matched, err
+}
+
+// search renders the messages of a session that match, with the messages
+// around them, to w. A header naming the session is written first if it is
+// not empty. It reports whether anything matched.
+func (g *grep) search(r io.Reader, w io.Writer, header string) (bool, error) {
+	var events []parser.Event
+	var readErr error
+	for ev, err := range parser.NewDecoder(r).All() {
+		if err != nil {
+			var lineErr *parser.LineError
+			if errors.As(err, &lineErr) {
+				continue
+			}
+			readErr = err
+			break
+		}
+		// Partial messages are repeated by the complete message that follows
+		if ev.Message.Type != "stream_event" {
+			events = append(events, ev)
+		}
+	}
+
+	shown := make([]bool, len(events))
+	matched := false
+	calls := make(map[string]string)
+	for i, ev := range events {
+		if !g.match(messageFields(ev.Message, calls)) {
+			continue
+		}
+		matched = true
+		for j := max(i-g.context, 0); j <= min(i+g.context, len(events)-1); j++ {
+			shown[j] = true
+		}
+	}
+	if !matched {
+		return false, readErr
+	}
+
+	// Every message goes through the renderer, so that tool results are
+	// labeled and subagents nested as usual, but only the shown ones are written
+	terminal := g.cfg.Style != display.StyleHTML && g.cfg.Style != display.StyleMarkdown
+	if header != "" && terminal {
+		fmt.Fprintf(w, "==> %s <==\n", header)
+	}
+	gate := &gateWriter{w: w, open: true}
+	renderer := display.NewRenderer(gate, g.cfg)
+	renderer.Start()
+	gate.open = false
+	for i, ev := range events {
+		if shown[i] && i > 0 && !shown[i-1] && terminal && gate.written {
+			fmt.Fprintln(w, "--")
+		}
+		gate.open = shown[i]
+		display.Render(renderer, ev.Message, ev.Line)
+	}
+	// Calls left without a result are only warned about for whole sessions
+	gate.open = !terminal
+	renderer.Finish()
+	return true, readErr
+}
+
+// match reports whether any of the fields selected by -field matches
+func (g *grep) match(fields []searchField) bool {
+	for _, f := range fields {
+		if g.selected(f) && g.re.MatchString(f.Text) {
+			return true
+		}
+	}
+	return false
+}
+
+// selected reports whether a field is searched
+func (g *grep) selected(f searchField) bool {
+	if len(g.fields) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(g.fields, func(spec string) bool {
+		if slices.Contains(fieldKinds, spec) {
+			return f.Kind == spec
+		}
+		tool, key, hasKey := strings.Cut(spec, ".")
+		switch {
+		case f.Tool == "" || !display.MatchTool([]string{tool}, f.Tool):
+			return false
+		case !hasKey:
+			return true
+		case key == "result":
+			return f.Kind == "result"
+		default:
+			return f.Kind == "input" && f.Key == key
+		}
+	})
+}
+
+// messageFields returns the searchable content of msg, with system reminders
+// stripped. calls maps tool_use IDs to tool names, to name the tool of each
+// result; the calls made in msg are added to it.
+func messageFields(msg *parser.StreamMessage, calls map[string]string) []searchField {
+	var fields []searchField
+	if msg.Type == "result" && msg.Result != "" {
+		fields = append(fields, searchField{Kind: "text", Text: msg.Result})
+	}
+	if msg.Message == nil {
+		return fields
+	}
+	for _, block := range msg.Message.Content {
+		switch block.Type {
+		case "text":
+			fields = append(fields, searchField{Kind: "text", Text: parser.StripSystemReminders(block.Text)})
+		case "thinking":
+			fields = append(fields, searchField{Kind: "thinking", Text: block.Thinking})
+		case "tool_use":
+			calls[block.ID] = block.Name
+			for key, value := range block.Input {
+				text, ok := value.(string)
+				if !ok {
+					data, _ := json.Marshal(value)
+					text = string(data)
+				}
+				fields = append(fields, searchField{Kind: "input", Tool: block.Name, Key: key, Text: text})
+			}
+		case "tool_result":
+			text := parser.StripSystemReminders(parser.ContentText(block.Content))
+			fields = append(fields, searchField{Kind: "result", Tool: calls[block.ToolUseID], Text: text})
+		}
+	}
+	return fields
+}
+
+// gateWriter passes writes through while open and discards them otherwise
+type gateWriter struct {
+	w       io.Writer
+	open    bool
+	written bool // something has been written through
+}
+
+func (g *gateWriter) Write(p []byte) (int, error) {
+	if !g.open {
+		return len(p), nil
+	}
+	g.written = true
+	return g.w.Write(p)
+}
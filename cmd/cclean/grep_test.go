@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ariel-frischer/claude-clean/display"
+)
+
+const grepSession = `{"type":"system","subtype":"init","model":"claude-test"}
+{"type":"assistant","message":{"content":[{"type":"text","text":"Pushing the branch now"},{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"git push origin main","description":"Push"}}]}}
+{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"Everything up-to-date<system-reminder>git push is risky</system-reminder>"}]}}
+{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t2","name":"Read","input":{"file_path":"push.go"}}]}}
+{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t2","content":"package push\nfunc Push() {}"}]}}
+{"type":"result","subtype":"success","is_error":false,"num_turns":2,"result":"Pushed"}
+`
+
+func TestGrep(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		fields  []string
+		context int
+		shown   []string
+		hidden  []string
+	}{
+		{
+			name:    "decoded content",
+			pattern: `Push\(\)`,
+			shown:   []string{"line 5", "func Push() {}", "TOOL RESULT: Read (push.go)"},
+			hidden:  []string{"line 2", "line 4", "line 6"},
+		},
+		{
+			name:    "case insensitive",
+			pattern: `(?i)pushed`,
+			shown:   []string{"line 6"},
+			hidden:  []string{"line 5", "claude-test"},
+		},
+		{
+			name:    "system reminders stripped",
+			pattern: `risky`,
+		},
+		{
+			name:    "tool input field",
+			pattern: `push`,
+			fields:  []string{"Bash.command"},
+			shown:   []string{"line 2", "git push origin main"},
+			hidden:  []string{"line 4", "line 5", "line 6"},
+		},
+		{
+			name:    "tool results",
+			pattern: `push`,
+			fields:  []string{"R*.result"},
+			shown:   []string{"line 5"},
+			hidden:  []string{"line 2", "line 4"},
+		},
+		{
+			name:    "context",
+			pattern: `up-to-date`,
+			context: 1,
+			shown:   []string{"line 2", "line 3", "line 4"},
+			hidden:  []string{"line 1)", "line 5"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &grep{
+				re:      regexp.MustCompile(tt.pattern),
+				fields:  tt.fields,
+				context: tt.context,
+				cfg:     &display.Config{Style: display.StylePlain, ShowLineNum: true},
+			}
+			var buf bytes.Buffer
+			matched, err := g.search(strings.NewReader(grepSession), &buf, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			output := buf.String()
+
+			if matched != (len(tt.shown) > 0) {
+				t.Errorf("matched %v\nGot:\n%s", matched, output)
+			}
+			for _, want := range tt.shown {
+				if !strings.Contains(output, want) {
+					t.Errorf("output missing %q\nGot:\n%s", want, output)
+				}
+			}
+			for _, hidden := range tt.hidden {
+				if strings.Contains(output, hidden) {
+					t.Errorf("output contains %q\nGot:\n%s", hidden, output)
+				}
+			}
+		})
+	}
+}
+
+func TestGrepSeparatesMatches(t *testing.T) {
+	g := &grep{
+		re:     regexp.MustCompile(`(?i)push`),
+		cfg:    &display.Config{Style: display.StylePlain, ShowLineNum: true},
+		fields: []string{"text"},
+	}
+	var buf bytes.Buffer
+	if _, err := g.search(strings.NewReader(grepSession), &buf, "session.jsonl"); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+	if !strings.HasPrefix(output, "==> session.jsonl <==\n") {
+		t.Errorf("output does not start with the session header:\n%s", output)
+	}
+	if strings.Count(output, "\n--\n") != 1 {
+		t.Errorf("want one separator between lines 2 and 6:\n%s", output)
+	}
+}
@@ -1,15 +1,16 @@
 package main
 
 import (
-	"bufio"
-	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"strings"
-	"time"
+	"syscall"
 
 	"github.com/ariel-frischer/claude-clean/display"
 	"github.com/ariel-frischer/claude-clean/parser"
@@ -22,20 +23,59 @@ var Version = "dev"
 var (
 	verbose        = flag.Bool("V", false, "Show verbose output (usage stats, tool IDs)")
 	showVersion    = flag.Bool("v", false, "Show version")
-	styleFlag      = flag.String("s", "default", "Output style: default, compact, minimal, plain")
+	styleFlag      = flag.String("s", "default", "Output style: default, compact, minimal, plain, html, markdown")
 	showLineNum    = flag.Bool("n", false, "Show line numbers")
 	showTimestamps = flag.Bool("t", false, "Show elapsed time for each message")
+	showThinking   = flag.Bool("thinking", false, "Show the model's thinking blocks")
+	width          = flag.Int("width", 0, "Wrap output at this many columns (default: terminal width, 80 when piped)")
+	colorFlag      = flag.String("color", "auto", "When to use colors: auto, always, never")
+	themeFlag      = flag.String("theme", "dark", "Color theme: dark, light, high-contrast, solarized, or one from the config file")
+	noMarkdown     = flag.Bool("no-markdown", false, "Print assistant text as is instead of rendering its markdown")
+	full           = flag.Bool("full", false, "Show tool inputs and results in full instead of truncating them")
+	onlyTypes      = flag.String("only-type", "", "Show only these message types (comma-separated: system, assistant, user, result)")
+	hideTypes      = flag.String("hide-type", "", "Hide these message types (comma-separated)")
+	onlyTools      = flag.String("only-tool", "", "Show only the calls and results of these tools (comma-separated, globs allowed)")
+	hideTools      = flag.String("hide-tool", "", "Hide the calls and results of these tools (comma-separated, globs allowed)")
+	errorsOnly     = flag.Bool("errors-only", false, "Show only failed tool results and the final result")
+	depth          = flag.Int("depth", -1, "Show subagents nested up to this many levels deep, 0 for none (default: all)")
+	follow         = flag.Bool("f", false, "Follow FILE as it grows, like tail -f")
+	untilResult    = flag.Bool("until-result", false, "With -f, exit after the result message")
+	teePath        = flag.String("tee", "", "Also write the raw input lines to FILE, gzip-compressed if it ends in .gz")
+	teeTime        = flag.Bool("tee-time", false, "With --tee, add the time each line was received in a cclean_received_at field")
 	uninstall      = flag.Bool("uninstall", false, "Uninstall cclean from the system")
 )
 
+// subcommands maps subcommand names to their entry points, which return the
+// exit code. Options given before the subcommand name apply to it as well.
+var subcommands = map[string]func(args []string, cfg *display.Config) int{
+	"config":   runConfig,
+	"grep":     runGrep,
+	"replay":   runReplay,
+	"run":      runClaude,
+	"sessions": runSessions,
+	"stats":    runStats,
+	"view":     runView,
+}
+
 func main() {
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS] [FILE]\n\n", binaryName())
+		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS] [FILE]\n", binaryName())
+		fmt.Fprintf(os.Stderr, "       %s [OPTIONS] COMMAND [ARGS]\n\n", binaryName())
 		fmt.Fprintln(os.Stderr, "Transform Claude Code's stream-json output into readable terminal output.")
 		fmt.Fprintln(os.Stderr)
 		fmt.Fprintln(os.Stderr, "Arguments:")
 		fmt.Fprintln(os.Stderr, "  FILE             JSONL file to process (optional)")
 		fmt.Fprintln(os.Stderr, "  No arguments     Reads from stdin")
+		fmt.Fprintln(os.Stderr, "\nCommands:")
+		fmt.Fprintln(os.Stderr, "  config show      Print the settings in effect")
+		fmt.Fprintln(os.Stderr, "  grep PATTERN [FILE...]")
+		fmt.Fprintln(os.Stderr, "                   Search sessions and render the messages that match")
+		fmt.Fprintln(os.Stderr, "  replay FILE      Render a recorded session at its original pace")
+		fmt.Fprintln(os.Stderr, "  run -- ARGS      Run claude with ARGS and render its output")
+		fmt.Fprintln(os.Stderr, "  sessions [show ID]")
+		fmt.Fprintln(os.Stderr, "                   List the sessions saved by Claude Code, or render one")
+		fmt.Fprintln(os.Stderr, "  stats [FILE...]  Report tool, token and cost statistics")
+		fmt.Fprintln(os.Stderr, "  view [FILE]      Browse a session interactively")
 		fmt.Fprintln(os.Stderr, "\nOptions:")
 		flag.PrintDefaults()
 		fmt.Fprintln(os.Stderr, "\nStyles:")
@@ -43,10 +83,17 @@ func main() {
 		fmt.Fprintln(os.Stderr, "  compact  - Single-line summaries for each message")
 		fmt.Fprintln(os.Stderr, "  minimal  - Clean output without box-drawing characters")
 		fmt.Fprintln(os.Stderr, "  plain    - No colors, suitable for piping")
+		fmt.Fprintln(os.Stderr, "  html     - Self-contained HTML document for sharing")
+		fmt.Fprintln(os.Stderr, "  markdown - GitHub-flavored Markdown for PRs and wikis")
 		fmt.Fprintln(os.Stderr, "\nExamples:")
 		fmt.Fprintf(os.Stderr, "  claude -p 'prompt' --output-format stream-json | %s\n", binaryName())
 		fmt.Fprintf(os.Stderr, "  %s output.jsonl             # Process a JSONL file\n", binaryName())
 		fmt.Fprintf(os.Stderr, "  %s -s compact output.jsonl  # Use compact style\n", binaryName())
+		fmt.Fprintf(os.Stderr, "  %s -f output.jsonl          # Follow a file as it grows\n", binaryName())
+		fmt.Fprintf(os.Stderr, "  %s --tee run.jsonl.gz       # Render stdin and archive it\n", binaryName())
+		fmt.Fprintf(os.Stderr, "  %s view output.jsonl        # Browse interactively\n", binaryName())
+		fmt.Fprintf(os.Stderr, "  %s run -- 'prompt'          # Run claude and render its output\n", binaryName())
+		fmt.Fprintf(os.Stderr, "  %s grep -i error *.jsonl    # Search sessions\n", binaryName())
 	}
 
 	flag.Parse()
@@ -61,32 +108,50 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Determine output style
-	var style display.OutputStyle
-	switch *styleFlag {
-	case "default":
-		style = display.StyleDefault
-	case "compact":
-		style = display.StyleCompact
-	case "minimal":
-		style = display.StyleMinimal
-	case "plain":
-		style = display.StylePlain
-	default:
-		fmt.Fprintf(os.Stderr, "Unknown style: %s\n", *styleFlag)
-		flag.Usage()
+	dir, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	s, _, err := loadSettings(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading config: %v\n", err)
 		os.Exit(1)
 	}
+	applyFlags(&s)
 
-	cfg := &display.Config{
-		Style:          style,
-		Verbose:        *verbose,
-		ShowLineNum:    *showLineNum,
-		ShowTimestamps: *showTimestamps,
+	cfg, err := s.displayConfig()
+	if err == nil {
+		err = s.setColors()
+	}
+	if err == nil {
+		err = s.setPrices()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid settings: %v\n", err)
+		flag.Usage()
+		os.Exit(1)
 	}
 
 	args := flag.Args()
 
+	if *untilResult && !*follow {
+		fmt.Fprintln(os.Stderr, "--until-result needs -f")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if len(args) > 0 {
+		if run, ok := subcommands[args[0]]; ok {
+			os.Exit(run(args[1:], cfg))
+		}
+	}
+
+	if *follow && (len(args) != 1 || args[0] == "-") {
+		fmt.Fprintln(os.Stderr, "-f needs a FILE to follow")
+		os.Exit(1)
+	}
+
 	switch len(args) {
 	case 0:
 		// No file argument - read from stdin
@@ -95,6 +160,8 @@ func main() {
 		if args[0] == "-" {
 			// Read from stdin (explicit)
 			processStream(os.Stdin, cfg)
+		} else if *follow && fileExists(args[0]) {
+			followFile(args[0], cfg)
 		} else if fileExists(args[0]) {
 			// Process file
 			processFile(args[0], cfg)
@@ -122,7 +189,7 @@ func fileExists(path string) bool {
 }
 
 func processFile(filename string, cfg *display.Config) {
-	file, err := os.Open(filename)
+	file, err := openInput(filename)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error opening file: %v\n", err)
 		os.Exit(1)
@@ -132,28 +199,86 @@ func processFile(filename string, cfg *display.Config) {
 	processStream(file, cfg)
 }
 
-func processStream(r *os.File, cfg *display.Config) {
-	if cfg.ShowTimestamps {
-		cfg.StartTime = time.Now()
+// followFile renders a file and then the lines appended to it, until
+// interrupted or, with --until-result, until the result message
+func followFile(filename string, cfg *display.Config) {
+	f, err := openFollower(filename, *untilResult)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening file: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	processStream(f, cfg)
+}
+
+func processStream(r io.Reader, cfg *display.Config) {
+	tee, err := startTee(r)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	// Interrupting a followed file ends its stream, so the output is
+	// finished as at the end of a file. Otherwise an interrupt still leaves
+	// a complete tee file.
+	f, following := r.(*follower)
+	if tee != nil {
+		r = tee
+	}
+	if following || tee != nil {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-signals
+			if following {
+				// A second interrupt exits at once
+				signal.Stop(signals)
+				f.Stop()
+				return
+			}
+			tee.Close()
+			os.Exit(130)
+		}()
+	}
+
+	_, readErr := renderStream(r, os.Stdout, cfg)
+	if tee != nil {
+		if err := tee.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", *teePath, err)
+			os.Exit(1)
+		}
+	}
+	if readErr != nil {
+		fmt.Fprintf(os.Stderr, "Error reading: %v\n", readErr)
+		os.Exit(1)
+	}
+	if following && f.stopped() {
+		os.Exit(130)
 	}
+}
 
-	scanner := bufio.NewScanner(r)
-	scanner.Buffer(make([]byte, 0, parser.MaxBufferCapacity), parser.MaxBufferCapacity)
+// renderStream renders a stream-json stream to w, reporting whether its
+// result message was an error
+func renderStream(r io.Reader, w io.Writer, cfg *display.Config) (failed bool, readErr error) {
+	renderer := display.NewRenderer(w, cfg)
+	renderer.Start()
 
-	lineNum := 0
 	var lastAssistantContent string
 
-	for scanner.Scan() {
-		lineNum++
-		line := scanner.Text()
-		if line == "" {
-			continue
+	for ev, err := range parser.NewDecoder(r).All() {
+		if err != nil {
+			var lineErr *parser.LineError
+			if errors.As(err, &lineErr) {
+				fmt.Fprintf(os.Stderr, "Error parsing line %d: %v\n", lineErr.Line, lineErr.Err)
+				continue
+			}
+			readErr = err
+			break
 		}
+		msg := ev.Message
 
-		var msg parser.StreamMessage
-		if err := json.Unmarshal([]byte(line), &msg); err != nil {
-			fmt.Fprintf(os.Stderr, "Error parsing line %d: %v\n", lineNum, err)
-			continue
+		if msg.Type == "result" {
+			failed = msg.IsError
 		}
 
 		// Skip duplicate result messages that contain the same content as the last assistant message
@@ -170,13 +295,11 @@ func processStream(r *os.File, cfg *display.Config) {
 			}
 		}
 
-		display.DisplayMessage(&msg, lineNum, cfg)
+		display.Render(renderer, msg, ev.Line)
 	}
 
-	if err := scanner.Err(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading: %v\n", err)
-		os.Exit(1)
-	}
+	renderer.Finish()
+	return failed, readErr
 }
 
 func runUninstall() {
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ariel-frischer/claude-clean/display"
+	"github.com/ariel-frischer/claude-clean/parser"
+)
+
+// runReplay implements the replay command, which renders a recorded session
+// with the pauses between its messages
+func runReplay(args []string, cfg *display.Config) int {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	speedFlag := fs.String("speed", "1x", "Playback speed, e.g. 2x for twice as fast or 0.5x for half")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS] replay [-speed N] FILE\n\n", binaryName())
+		fmt.Fprintln(os.Stderr, "Render a session with the original pauses between its messages, using the")
+		fmt.Fprintln(os.Stderr, "timestamps of a saved session or the receive times recorded by --tee-time.")
+		fmt.Fprintln(os.Stderr, "Reads from stdin when FILE is -.")
+		fmt.Fprintln(os.Stderr, "\nOptions:")
+		fs.PrintDefaults()
+	}
+
+	// Options may also follow FILE, as in replay session.jsonl --speed 2x
+	var files []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if err == flag.ErrHelp {
+				return 0
+			}
+			return 2
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		files = append(files, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(files) != 1 {
+		fs.Usage()
+		return 2
+	}
+	speed, err := parseSpeed(*speedFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid speed: %v\n", err)
+		return 2
+	}
+
+	var r io.Reader = os.Stdin
+	if files[0] != "-" {
+		file, err := openInput(files[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening file: %v\n", err)
+			return 1
+		}
+		defer file.Close()
+		r = file
+	}
+
+	p := &pacer{r: bufio.NewReader(r), speed: speed, sleep: time.Sleep}
+	if _, err := renderStream(p, os.Stdout, cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading: %v\n", err)
+		return 1
+	}
+	if p.untimed() {
+		fmt.Fprintf(os.Stderr, "Warning: %s has no recorded times, so it was replayed without pauses; record them with --tee-time\n", files[0])
+	}
+	return 0
+}
+
+// parseSpeed parses a playback speed such as 2x, 0.5x or 3
+func parseSpeed(s string) (float64, error) {
+	speed, err := strconv.ParseFloat(strings.TrimSuffix(strings.ToLower(s), "x"), 64)
+	if err != nil || speed <= 0 {
+		return 0, fmt.Errorf("%q is not a positive number", s)
+	}
+	return speed, nil
+}
+
+// pacer returns the lines of a recorded session one at a time, each after
+// the time that passed since the line before it, divided by speed. Lines
+// without a time are returned right away.
+type pacer struct {
+	r     *bufio.Reader
+	speed float64
+	sleep func(time.Duration)
+	last  time.Time // time of the last line that had one
+	line  []byte    // rest of the line being returned
+	lines int       // lines read
+}
+
+// untimed reports whether lines were read but none of them had a time
+func (p *pacer) untimed() bool {
+	return p.lines > 0 && p.last.IsZero()
+}
+
+func (p *pacer) Read(b []byte) (int, error) {
+	if len(p.line) == 0 {
+		line, err := p.r.ReadBytes('\n')
+		if len(line) == 0 {
+			return 0, err
+		}
+		p.lines++
+		if t := parser.LineTime(line); !t.IsZero() {
+			if !p.last.IsZero() && t.After(p.last) {
+				p.sleep(time.Duration(float64(t.Sub(p.last)) / p.speed))
+			}
+			p.last = t
+		}
+		p.line = line
+	}
+	n := copy(b, p.line)
+	p.line = p.line[n:]
+	return n, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ariel-frischer/claude-clean/parser"
+)
+
+func TestPacer(t *testing.T) {
+	session := `{"type":"system","subtype":"init","cclean_received_at":"2025-10-20T14:00:00Z"}
+{"type":"assistant","message":{"content":[{"type":"text","text":"Hi"}]},"cclean_received_at":"2025-10-20T14:00:01.5Z"}
+{"type":"user","message":{"content":[{"type":"text","text":"no time"}]}}
+{"type":"result","subtype":"success","cclean_received_at":"2025-10-20T14:00:01.8Z"}`
+
+	// Each message must be decoded before the pause that precedes the next
+	var log []string
+	p := &pacer{r: bufio.NewReader(strings.NewReader(session)), speed: 2, sleep: func(d time.Duration) {
+		log = append(log, fmt.Sprintf("sleep %v", d))
+	}}
+	for ev, err := range parser.NewDecoder(p).All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		log = append(log, ev.Message.Type)
+	}
+
+	want := "system, sleep 750ms, assistant, user, sleep 150ms, result"
+	if got := strings.Join(log, ", "); got != want {
+		t.Errorf("got %s\nwant %s", got, want)
+	}
+	if p.untimed() {
+		t.Error("untimed() = true for a session with times")
+	}
+}
+
+func TestPacerUntimed(t *testing.T) {
+	session := `{"type":"system","subtype":"init"}
+{"type":"result","subtype":"success"}`
+
+	slept := false
+	p := &pacer{r: bufio.NewReader(strings.NewReader(session)), speed: 1, sleep: func(time.Duration) { slept = true }}
+	for _, err := range parser.NewDecoder(p).All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if slept || !p.untimed() {
+		t.Errorf("slept = %v, untimed() = %v; want no pauses and untimed", slept, p.untimed())
+	}
+
+	empty := &pacer{r: bufio.NewReader(strings.NewReader("")), speed: 1, sleep: time.Sleep}
+	for range parser.NewDecoder(empty).All() {
+	}
+	if empty.untimed() {
+		t.Error("untimed() = true for an empty session")
+	}
+}
+
+func TestParseSpeed(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		ok   bool
+	}{
+		{"2x", 2, true},
+		{"0.5X", 0.5, true},
+		{"3", 3, true},
+		{"0x", 0, false},
+		{"-1x", 0, false},
+		{"fast", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := parseSpeed(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseSpeed(%q) = %v, %v", tt.in, got, err)
+		}
+	}
+}
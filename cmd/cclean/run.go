@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/ariel-frischer/claude-clean/display"
+)
+
+// runClaude runs claude in print mode with stream-json output and renders
+// its output live. It exits with claude's exit code, or 1 when claude exits
+// cleanly but the session ended in an error.
+func runClaude(args []string, cfg *display.Config) int {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	claude := fs.String("claude", "claude", "Path to the claude binary")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS] run [-claude PATH] -- [CLAUDE ARGS]\n\n", binaryName())
+		fmt.Fprintln(os.Stderr, "Run claude and render its output. -p, --verbose and --output-format stream-json")
+		fmt.Fprintln(os.Stderr, "are added to the arguments; stdin and stderr are passed through.")
+		fmt.Fprintln(os.Stderr, "\nOptions:")
+		fs.PrintDefaults()
+		fmt.Fprintln(os.Stderr, "\nExamples:")
+		fmt.Fprintf(os.Stderr, "  %s run -- 'explain main.go'\n", binaryName())
+		fmt.Fprintf(os.Stderr, "  %s -s compact run -- --model sonnet 'fix the failing test'\n", binaryName())
+	}
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	cmd := exec.Command(*claude, claudeArgs(fs.Args())...)
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	return runCommand(cmd, os.Stdout, cfg)
+}
+
+// runCommand runs cmd, rendering its stream-json output to w, and returns
+// the exit code for cclean
+func runCommand(cmd *exec.Cmd, w io.Writer, cfg *display.Config) int {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	var input io.Reader = stdout
+	tee, err := startTee(stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if tee != nil {
+		input = tee
+	}
+
+	// Signals are passed to claude rather than handled, so it can finish its
+	// output and the rendered transcript is complete. An interrupt from the
+	// terminal already reaches claude through the process group, so it is
+	// only ignored; other signals are forwarded.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error starting %s: %v\n", cmd.Path, err)
+		if tee != nil {
+			tee.Close()
+		}
+		return 127
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				if sig != os.Interrupt {
+					cmd.Process.Signal(sig)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	failed, readErr := renderStream(input, w, cfg)
+	if readErr != nil {
+		fmt.Fprintf(os.Stderr, "Error reading: %v\n", readErr)
+	}
+	if tee != nil {
+		if err := tee.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", *teePath, err)
+		}
+	}
+	// Drain the rest so claude is not blocked writing to a full pipe
+	io.Copy(io.Discard, stdout)
+
+	if err := cmd.Wait(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal())
+		}
+		return exitErr.ExitCode()
+	}
+	if failed || readErr != nil {
+		return 1
+	}
+	return 0
+}
+
+// claudeArgs returns the arguments for claude with print mode, verbose
+// output and the stream-json output format added. An --output-format given
+// by the user is replaced, since only stream-json can be rendered.
+func claudeArgs(args []string) []string {
+	out := []string{"-p", "--verbose", "--output-format", "stream-json"}
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--":
+			return append(out, args[i:]...)
+		case arg == "-p", arg == "--print", arg == "--verbose":
+		case arg == "--output-format":
+			i++
+		case strings.HasPrefix(arg, "--output-format="):
+		default:
+			out = append(out, arg)
+		}
+	}
+	return out
+}
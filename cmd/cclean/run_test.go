@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/ariel-frischer/claude-clean/display"
+)
+
+// fakeClaude installs a claude script on PATH that records its arguments in
+// the returned file and then runs body
+func fakeClaude(t *testing.T, body string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake claude is a shell script")
+	}
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args")
+	script := "#!/bin/sh\nprintf '%s\\n' \"$@\" > " + argsFile + "\n" + body + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "claude"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return argsFile
+}
+
+func runFake(t *testing.T, args ...string) (int, string) {
+	t.Helper()
+	var out bytes.Buffer
+	cmd := exec.Command("claude", claudeArgs(args)...)
+	code := runCommand(cmd, &out, &display.Config{Style: display.StylePlain})
+	return code, out.String()
+}
+
+const (
+	assistantLine = `{"type":"assistant","message":{"content":[{"type":"text","text":"Hello from claude"}]}}`
+	successLine   = `{"type":"result","subtype":"success","is_error":false,"num_turns":1,"result":"Done"}`
+	errorLine     = `{"type":"result","subtype":"error_during_execution","is_error":true,"num_turns":1}`
+)
+
+func TestClaudeArgs(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected []string
+	}{
+		{nil, []string{"-p", "--verbose", "--output-format", "stream-json"}},
+		{[]string{"explain main.go"}, []string{"-p", "--verbose", "--output-format", "stream-json", "explain main.go"}},
+		{[]string{"--print", "--model", "sonnet", "hi"}, []string{"-p", "--verbose", "--output-format", "stream-json", "--model", "sonnet", "hi"}},
+		{[]string{"--output-format", "text", "--verbose", "hi"}, []string{"-p", "--verbose", "--output-format", "stream-json", "hi"}},
+		{[]string{"--output-format=json", "hi"}, []string{"-p", "--verbose", "--output-format", "stream-json", "hi"}},
+		{[]string{"--", "-p is a prompt"}, []string{"-p", "--verbose", "--output-format", "stream-json", "--", "-p is a prompt"}},
+	}
+
+	for _, tt := range tests {
+		got := claudeArgs(tt.args)
+		if strings.Join(got, " ") != strings.Join(tt.expected, " ") {
+			t.Errorf("claudeArgs(%q) = %q, want %q", tt.args, got, tt.expected)
+		}
+	}
+}
+
+func TestRunClaude(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{"success", "echo '" + assistantLine + "'\necho '" + successLine + "'", 0},
+		{"session error", "echo '" + errorLine + "'", 1},
+		{"exit code", "echo '" + assistantLine + "'\nexit 3", 3},
+		{"exit code wins over session error", "echo '" + errorLine + "'\nexit 2", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			argsFile := fakeClaude(t, tt.body)
+			code, output := runFake(t, "--model", "sonnet", "say hello")
+			if code != tt.expected {
+				t.Errorf("exit code %d, want %d", code, tt.expected)
+			}
+
+			args, err := os.ReadFile(argsFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := "-p\n--verbose\n--output-format\nstream-json\n--model\nsonnet\nsay hello\n"; string(args) != want {
+				t.Errorf("claude called with %q, want %q", args, want)
+			}
+			if strings.Contains(tt.body, "Hello") && !strings.Contains(output, "Hello from claude") {
+				t.Errorf("output missing the assistant text:\n%s", output)
+			}
+		})
+	}
+}
+
+func TestRunClaudeNotFound(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	if code, _ := runFake(t, "hi"); code != 127 {
+		t.Errorf("exit code %d, want 127 when claude is not installed", code)
+	}
+}
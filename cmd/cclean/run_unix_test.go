@@ -0,0 +1,59 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// signalWhenReady sends sig to cclean once the fake claude has created ready
+func signalWhenReady(ready string, sig syscall.Signal) {
+	for i := 0; i < 200; i++ {
+		if _, err := os.Stat(ready); err == nil {
+			syscall.Kill(os.Getpid(), sig)
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunClaudeForwardsSignals(t *testing.T) {
+	dir := t.TempDir()
+	ready := filepath.Join(dir, "ready")
+	fakeClaude(t, "trap 'echo \""+strings.ReplaceAll(errorLine, `"`, `\"`)+"\"; exit 143' TERM\n"+
+		"echo '"+assistantLine+"'\ntouch "+ready+"\nwhile :; do sleep 0.05; done")
+
+	go signalWhenReady(ready, syscall.SIGTERM)
+
+	code, output := runFake(t, "hi")
+	if code != 143 {
+		t.Errorf("exit code %d, want claude's 143", code)
+	}
+	if !strings.Contains(output, "Hello from claude") || !strings.Contains(output, "RESULT") {
+		t.Errorf("output rendered before and after the interrupt is missing:\n%s", output)
+	}
+}
+
+func TestRunClaudeDoesNotForwardInterrupt(t *testing.T) {
+	dir := t.TempDir()
+	ready := filepath.Join(dir, "ready")
+	fakeClaude(t, "trap 'exit 130' INT\n"+
+		"echo '"+assistantLine+"'\ntouch "+ready+"\nsleep 0.3\necho '"+successLine+"'")
+
+	// A terminal interrupt already reaches claude through the process
+	// group, so one sent to cclean alone must not be passed on
+	go signalWhenReady(ready, syscall.SIGINT)
+
+	code, output := runFake(t, "hi")
+	if code != 0 {
+		t.Errorf("exit code %d, want 0 from claude finishing uninterrupted", code)
+	}
+	if !strings.Contains(output, "Done") {
+		t.Errorf("output after the interrupt is missing:\n%s", output)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ariel-frischer/claude-clean/display"
+	"github.com/ariel-frischer/claude-clean/sessions"
+)
+
+// runSessions implements the sessions command, which lists the sessions
+// Claude Code has saved, and sessions show, which renders one of them
+func runSessions(args []string, cfg *display.Config) int {
+	fs := flag.NewFlagSet("sessions", flag.ContinueOnError)
+	root := fs.String("root", "", "Directory of project session folders (default: ~/.claude/projects)")
+	project := fs.String("project", "", "Only sessions whose project path contains this")
+	since := fs.String("since", "", "Only sessions started on or after this date (YYYY-MM-DD, RFC 3339, or a duration ago such as 48h)")
+	until := fs.String("until", "", "Only sessions started on or before this date")
+	asJSON := fs.Bool("json", false, "List the sessions as JSON")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS] sessions [-root DIR] [-project TEXT] [-since DATE] [-until DATE] [-json]\n", binaryName())
+		fmt.Fprintf(os.Stderr, "       %s [OPTIONS] sessions [-root DIR] show ID\n\n", binaryName())
+		fmt.Fprintln(os.Stderr, "List the interactive sessions saved by Claude Code, most recent first, or")
+		fmt.Fprintln(os.Stderr, "render the session whose ID starts with ID in the selected style.")
+		fmt.Fprintln(os.Stderr, "\nOptions:")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	var f sessions.Filter
+	var err error
+	f.Project = *project
+	if f.Since, err = parseDate(*since, false); err == nil {
+		f.Until, err = parseDate(*until, true)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid date: %v\n", err)
+		return 2
+	}
+
+	dir := *root
+	if dir == "" {
+		if dir, err = sessions.DefaultRoot(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+
+	showID := ""
+	switch {
+	case fs.NArg() == 0:
+	case fs.NArg() == 2 && fs.Arg(0) == "show":
+		showID = fs.Arg(1)
+	default:
+		fs.Usage()
+		return 2
+	}
+
+	found, skipped, err := sessions.Find(dir, f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	for _, err := range skipped {
+		fmt.Fprintf(os.Stderr, "Warning: skipped %v\n", err)
+	}
+
+	if showID != "" {
+		s, err := sessions.Lookup(found, showID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		processFile(s.Path, cfg)
+		return 0
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if found == nil {
+			found = []sessions.Session{}
+		}
+		err = enc.Encode(found)
+	} else {
+		err = sessions.WriteTable(os.Stdout, found)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// parseDate parses a -since or -until value: a date, which ends at
+// midnight for endOfDay, an RFC 3339 time, or a duration before now
+func parseDate(s string, endOfDay bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date, time or duration", s)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ariel-frischer/claude-clean/display"
+	"github.com/ariel-frischer/claude-clean/parser"
+	"github.com/ariel-frischer/claude-clean/stats"
+)
+
+// runStats implements the stats command, which prints a report aggregated
+// over one or more sessions
+func runStats(args []string, cfg *display.Config) int {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS] stats [-json] [FILE...]\n\n", binaryName())
+		fmt.Fprintln(os.Stderr, "Report turns, tool calls and errors per tool, the most read and edited files,")
+		fmt.Fprintln(os.Stderr, "tokens per model, cache hit ratio, cost, duration and permission denials,")
+		fmt.Fprintln(os.Stderr, "summed over the sessions given. Reads from stdin when no FILE is given.")
+		fmt.Fprintln(os.Stderr, "\nOptions:")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	c := stats.NewCollector()
+	for _, name := range files {
+		if err := collectFile(c, name); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+
+	st := c.Stats()
+	var err error
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(st)
+	} else {
+		err = stats.WriteTable(os.Stdout, st)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// collectFile adds the session in a file, or stdin for "-", to c
+func collectFile(c *stats.Collector, name string) error {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		file, err := openInput(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	c.Session()
+	for ev, err := range parser.NewDecoder(r).All() {
+		if err != nil {
+			var lineErr *parser.LineError
+			if errors.As(err, &lineErr) {
+				fmt.Fprintf(os.Stderr, "Error parsing %s line %d: %v\n", name, lineErr.Line, lineErr.Err)
+				continue
+			}
+			return fmt.Errorf("reading %s: %w", name, err)
+		}
+		c.Add(ev.Message)
+	}
+	return nil
+}
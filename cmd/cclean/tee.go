@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ariel-frischer/claude-clean/parser"
+)
+
+// receivedLayout formats receive times like the timestamps of session transcripts
+const receivedLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// teeReader copies the lines read through it to a file, unchanged or with
+// the time they were received added in the parser.ReceivedAtField field.
+// A file whose name ends in .gz is gzip-compressed. Each batch of lines is
+// flushed as it is read, so the file is complete up to the last line even if
+// cclean is killed.
+type teeReader struct {
+	r       io.Reader
+	file    *os.File
+	gz      *gzip.Writer // nil if not compressing
+	w       io.Writer    // file or gz
+	stamp   bool         // add receive times
+	now     func() time.Time
+	partial []byte // incomplete last line read so far
+
+	mu     sync.Mutex // guards writes against Close from a signal handler
+	err    error      // first error writing the file
+	closed bool
+}
+
+// startTee returns a teeReader on r writing to the --tee file, nil if no
+// file was given
+func startTee(r io.Reader) (*teeReader, error) {
+	if *teePath == "" {
+		return nil, nil
+	}
+	return createTee(r, *teePath, *teeTime)
+}
+
+// createTee creates the file path and returns a teeReader on r writing to it
+func createTee(r io.Reader, path string, stamp bool) (*teeReader, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	t := &teeReader{r: r, file: file, w: file, stamp: stamp, now: time.Now}
+	if strings.HasSuffix(path, ".gz") {
+		t.gz = gzip.NewWriter(file)
+		t.w = t.gz
+	}
+	return t, nil
+}
+
+// Read reads from the underlying reader and copies the lines completed by
+// what was read to the file. An error writing the file does not stop the
+// reading; Close returns it.
+func (t *teeReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if n > 0 {
+		t.mu.Lock()
+		if t.err == nil && !t.closed {
+			t.err = t.write(p[:n])
+		}
+		t.mu.Unlock()
+	}
+	return n, err
+}
+
+// write appends data to the partial line and writes the lines it completes
+func (t *teeReader) write(data []byte) error {
+	t.partial = append(t.partial, data...)
+	end := bytes.LastIndexByte(t.partial, '\n') + 1
+	if end == 0 {
+		return nil
+	}
+	err := t.writeLines(t.partial[:end])
+	t.partial = append(t.partial[:0], t.partial[end:]...)
+	return err
+}
+
+// writeLines writes complete lines, stamped with the current time if
+// requested, and flushes them to the file
+func (t *teeReader) writeLines(lines []byte) error {
+	if t.stamp {
+		received := t.now()
+		var stamped []byte
+		for len(lines) > 0 {
+			line, rest, _ := bytes.Cut(lines, []byte("\n"))
+			stamped = append(stamped, stampLine(line, received)...)
+			if len(rest) > 0 || lines[len(lines)-1] == '\n' {
+				stamped = append(stamped, '\n')
+			}
+			lines = rest
+		}
+		lines = stamped
+	}
+	if _, err := t.w.Write(lines); err != nil {
+		return err
+	}
+	if t.gz != nil {
+		return t.gz.Flush()
+	}
+	return nil
+}
+
+// Close writes the last line if it had no newline and closes the file,
+// returning the first error writing it. It may be called more than once.
+func (t *teeReader) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return t.err
+	}
+	t.closed = true
+	if len(t.partial) > 0 && t.err == nil {
+		t.err = t.writeLines(t.partial)
+	}
+	if t.gz != nil {
+		if err := t.gz.Close(); t.err == nil {
+			t.err = err
+		}
+	}
+	if err := t.file.Close(); t.err == nil {
+		t.err = err
+	}
+	return t.err
+}
+
+// stampLine adds the receive time to a line holding a JSON object as its
+// first field. Other lines, and lines that already have a receive time, are
+// returned unchanged.
+func stampLine(line []byte, received time.Time) []byte {
+	body := bytes.TrimLeft(line, " \t")
+	if len(body) == 0 || body[0] != '{' || bytes.Contains(line, []byte(`"`+parser.ReceivedAtField+`"`)) {
+		return line
+	}
+	indent := line[:len(line)-len(body)]
+	rest := body[1:]
+
+	out := append([]byte(nil), indent...)
+	out = append(out, `{"`+parser.ReceivedAtField+`":"`+received.UTC().Format(receivedLayout)+`"`...)
+	if r := bytes.TrimLeft(rest, " \t\r"); len(r) > 0 && r[0] != '}' {
+		out = append(out, ',')
+	}
+	return append(out, rest...)
+}
+
+// openInput opens a session file, decompressing it if its name ends in .gz
+// as for the files written by --tee
+func openInput(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil || !strings.HasSuffix(path, ".gz") {
+		return file, err
+	}
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &gzipFile{Reader: gz, file: file}, nil
+}
+
+// gzipFile reads a gzip-compressed file
+type gzipFile struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (g *gzipFile) Close() error {
+	g.Reader.Close()
+	return g.file.Close()
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/ariel-frischer/claude-clean/parser"
+)
+
+const teeInput = `{"type":"system","subtype":"init"}
+not json
+{"type":"result","cclean_received_at":"2025-10-20T14:00:00Z"}
+{"type":"assistant"}`
+
+// teeThrough reads teeInput a byte at a time through a teeReader writing to
+// name and returns what was written
+func teeThrough(t *testing.T, name string, stamp bool) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	tee, err := createTee(iotest.OneByteReader(strings.NewReader(teeInput)), path, stamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tee.now = func() time.Time {
+		return time.Date(2025, 10, 20, 16, 30, 1, 250_000_000, time.FixedZone("CEST", 2*60*60))
+	}
+
+	read, err := io.ReadAll(tee)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(read) != teeInput {
+		t.Errorf("read %q through the tee, want the input", read)
+	}
+	if err := tee.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := openInput(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestTee(t *testing.T) {
+	for _, name := range []string{"run.jsonl", "run.jsonl.gz"} {
+		if got := teeThrough(t, name, false); got != teeInput {
+			t.Errorf("%s holds %q, want the input", name, got)
+		}
+	}
+}
+
+func TestTeeReceiveTimes(t *testing.T) {
+	got := teeThrough(t, "run.jsonl.gz", true)
+	want := `{"cclean_received_at":"2025-10-20T14:30:01.250Z","type":"system","subtype":"init"}
+not json
+{"type":"result","cclean_received_at":"2025-10-20T14:00:00Z"}
+{"cclean_received_at":"2025-10-20T14:30:01.250Z","type":"assistant"}`
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// The stamped lines still decode, with their receive time
+	var times []time.Time
+	for ev, err := range parser.NewDecoder(strings.NewReader(got)).All() {
+		if err == nil {
+			times = append(times, ev.Message.Time())
+		}
+	}
+	if len(times) != 3 || times[0].IsZero() || !times[1].Equal(time.Date(2025, 10, 20, 14, 0, 0, 0, time.UTC)) {
+		t.Errorf("decoded times %v", times)
+	}
+}
+
+func TestStampLine(t *testing.T) {
+	at := time.Date(2025, 10, 20, 14, 0, 0, 0, time.UTC)
+	tests := []struct {
+		line, want string
+	}{
+		{`{}`, `{"cclean_received_at":"2025-10-20T14:00:00.000Z"}`},
+		{`  { "type":"user"}`, `  {"cclean_received_at":"2025-10-20T14:00:00.000Z", "type":"user"}`},
+		{``, ``},
+		{`[1,2]`, `[1,2]`},
+	}
+	for _, tt := range tests {
+		if got := string(stampLine([]byte(tt.line), at)); got != tt.want {
+			t.Errorf("stampLine(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
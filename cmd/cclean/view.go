@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ariel-frischer/claude-clean/display"
+	"github.com/ariel-frischer/claude-clean/viewer"
+	"github.com/mattn/go-isatty"
+)
+
+// runView opens the interactive viewer on a JSONL file or stdin
+func runView(args []string, cfg *display.Config) int {
+	fs := flag.NewFlagSet("view", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS] view [FILE]\n\n", binaryName())
+		fmt.Fprintln(os.Stderr, "Browse a session interactively. Reads from stdin when FILE is omitted or \"-\",")
+		fmt.Fprintln(os.Stderr, "so a running session can be piped in and followed live.")
+		fmt.Fprintln(os.Stderr, "\nKeys:")
+		fmt.Fprintln(os.Stderr, "  j/k, arrows      Move the cursor")
+		fmt.Fprintln(os.Stderr, "  J/K, tab         Jump to the next/previous entry")
+		fmt.Fprintln(os.Stderr, "  enter, space     Expand, show all lines, collapse")
+		fmt.Fprintln(os.Stderr, "  a                Expand or collapse all entries")
+		fmt.Fprintln(os.Stderr, "  e/E              Jump to the next/previous error")
+		fmt.Fprintln(os.Stderr, "  /, n/N           Search, next/previous match")
+		fmt.Fprintln(os.Stderr, "  f                Filter by type or tool (e.g. \"Bash Edit\", \"-thinking\", \"error\")")
+		fmt.Fprintln(os.Stderr, "  q                Quit")
+	}
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	var r io.Reader = os.Stdin
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		if fs.Arg(0) == "-" {
+			break
+		}
+		file, err := openInput(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening file: %v\n", err)
+			return 1
+		}
+		defer file.Close()
+		r = file
+	default:
+		fmt.Fprintln(os.Stderr, "Too many arguments")
+		fs.Usage()
+		return 2
+	}
+
+	if r == os.Stdin && isatty.IsTerminal(os.Stdin.Fd()) {
+		fmt.Fprintln(os.Stderr, "No input: pass a FILE or pipe a stream into view")
+		return 2
+	}
+
+	err := viewer.Run(r, viewer.Builder{
+		Verbose:      cfg.Verbose,
+		ShowThinking: cfg.ShowThinking,
+		HeadLines:    cfg.HeadLines,
+		TailLines:    cfg.TailLines,
+		HideTools:    cfg.HideTools,
+		OnlyTools:    cfg.OnlyTools,
+		Redact:       cfg.Redact,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
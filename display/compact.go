@@ -3,169 +3,248 @@ package display
 import (
 	"fmt"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/ariel-frischer/claude-clean/parser"
 )
 
-func displayMessageCompact(msg *parser.StreamMessage, lineNum int, cfg *Config) {
-	switch msg.Type {
-	case "system":
-		displaySystemMessageCompact(msg, lineNum, cfg)
-	case "assistant":
-		displayAssistantMessageCompact(msg, lineNum, cfg)
-	case "user":
-		displayUserMessageCompact(msg, lineNum, cfg)
-	case "result":
-		displayResultMessageCompact(msg, lineNum, cfg)
-	}
+// compactRenderer renders each message as a single-line summary
+type compactRenderer struct {
+	*base
 }
 
-func displaySystemMessageCompact(msg *parser.StreamMessage, lineNum int, cfg *Config) {
-	BoldCyan.Print("SYS")
+func (r *compactRenderer) system(msg *parser.StreamMessage, lineNum int) {
+	BoldCyan.Fprint(r.w, "SYS")
 	if msg.Subtype != "" {
-		Cyan.Printf("[%s]", msg.Subtype)
+		Cyan.Fprintf(r.w, "[%s]", msg.Subtype)
 	}
-	Gray.Printf("%s%s", FormatElapsed(cfg), FormatLineNumCompact(lineNum, cfg.ShowLineNum))
+	Gray.Fprintf(r.w, "%s%s", r.elapsed(), FormatLineNumCompact(lineNum, r.cfg.ShowLineNum))
 	if msg.Model != "" {
-		Cyan.Printf(" %s", msg.Model)
+		Cyan.Fprintf(r.w, " %s", msg.Model)
 	}
 	if msg.CWD != "" {
-		Cyan.Printf(" @%s", msg.CWD)
+		Cyan.Fprintf(r.w, " @%s", msg.CWD)
+	}
+	if msg.Content != "" {
+		Cyan.Fprintf(r.w, " %s", oneLine(msg.Content))
 	}
-	fmt.Println()
+	fmt.Fprintln(r.w)
 }
 
-func displayAssistantMessageCompact(msg *parser.StreamMessage, lineNum int, cfg *Config) {
+func (r *compactRenderer) assistant(msg *parser.StreamMessage, lineNum int) {
 	if msg.Message == nil || len(msg.Message.Content) == 0 {
 		return
 	}
 
-	for _, block := range msg.Message.Content {
+	for _, block := range r.unstreamed(msg) {
 		switch block.Type {
 		case "text":
 			if block.Text != "" {
-				BoldGreen.Print("AST")
-				Gray.Printf("%s%s ", FormatElapsed(cfg), FormatLineNumCompact(lineNum, cfg.ShowLineNum))
-				// Truncate long text to single line
-				text := strings.ReplaceAll(block.Text, "\n", " ")
-				if len(text) > 100 {
-					White.Printf("%s...\n", text[:100])
-				} else {
-					White.Println(text)
-				}
+				BoldGreen.Fprint(r.w, "AST")
+				Gray.Fprintf(r.w, "%s%s ", r.elapsed(), FormatLineNumCompact(lineNum, r.cfg.ShowLineNum))
+				White.Fprintln(r.w, oneLine(block.Text))
+			}
+		case "thinking", "redacted_thinking":
+			if r.cfg.ShowThinking {
+				r.thinking(&block, lineNum)
 			}
 		case "tool_use":
-			displayToolUseCompact(&block, lineNum, cfg)
+			r.toolUse(&block, lineNum)
 		}
 	}
 }
 
-func displayToolUseCompact(tool *parser.ContentBlock, lineNum int, cfg *Config) {
-	BoldYellow.Printf("TOOL")
-	Gray.Printf("%s%s ", FormatElapsed(cfg), FormatLineNumCompact(lineNum, cfg.ShowLineNum))
-	Yellow.Printf("%s", tool.Name)
+func (r *compactRenderer) beginText(lineNum int) {
+	BoldGreen.Fprint(r.w, "AST")
+	Gray.Fprintf(r.w, "%s%s ", r.elapsed(), FormatLineNumCompact(lineNum, r.cfg.ShowLineNum))
+}
+
+func (r *compactRenderer) writeText(text string) {
+	// Keep the streamed text on a single line, truncated like a complete message
+	s := &r.stream
+	if s.chars > 100 {
+		return
+	}
+	text = strings.ReplaceAll(text, "\n", " ")
+	n := utf8.RuneCountInString(text)
+	if s.chars+n > 100 {
+		White.Fprintf(r.w, "%s...", string([]rune(text)[:100-s.chars]))
+		s.chars = 101
+		return
+	}
+	White.Fprint(r.w, text)
+	s.chars += n
+}
+
+func (r *compactRenderer) endText() {
+	fmt.Fprintln(r.w)
+}
+
+func (r *compactRenderer) thinking(block *parser.ContentBlock, lineNum int) {
+	text := thinkingText(block)
+	if text == "" {
+		return
+	}
+
+	Gray.Fprint(r.w, "THINK")
+	Gray.Fprintf(r.w, "%s%s ", r.elapsed(), FormatLineNumCompact(lineNum, r.cfg.ShowLineNum))
+	Gray.Fprintln(r.w, oneLine(text))
+}
+
+func (r *compactRenderer) subagentStart(a *subagent, lineNum int) {
+	BoldCyan.Fprint(r.w, "AGENT")
+	Gray.Fprintf(r.w, "%s%s ", r.elapsed(), FormatLineNumCompact(lineNum, r.cfg.ShowLineNum))
+	Cyan.Fprint(r.w, subagentName(a))
+	if a.started {
+		Cyan.Fprintln(r.w, " (continued)")
+	} else if a.Description != "" {
+		Cyan.Fprintf(r.w, " %q\n", a.Description)
+	} else {
+		fmt.Fprintln(r.w)
+	}
+}
+
+func (r *compactRenderer) subagentEnd(a *subagent) {
+	BoldCyan.Fprint(r.w, "DONE")
+	Cyan.Fprintf(r.w, " %s turns=%d tools=%d errors=%d\n", subagentName(a), a.Turns, a.ToolCalls, a.Errors)
+}
+
+func (r *compactRenderer) unansweredCalls(calls []*toolCall) {
+	names := make([]string, len(calls))
+	for i, call := range calls {
+		names[i] = call.Name + FormatLineNumCompact(call.LineNum, true)
+	}
+	BoldYellow.Fprint(r.w, "WARN")
+	Yellow.Fprintf(r.w, " %s without result: %s\n", plural(len(calls), "tool call"), strings.Join(names, ", "))
+}
 
-	// Show key inputs in compact form
-	if tool.Input != nil {
-		Yellow.Print(" {")
+func (r *compactRenderer) toolUse(tool *parser.ContentBlock, lineNum int) {
+	BoldYellow.Fprintf(r.w, "TOOL")
+	Gray.Fprintf(r.w, "%s%s ", r.elapsed(), FormatLineNumCompact(lineNum, r.cfg.ShowLineNum))
+	Yellow.Fprintf(r.w, "%s", tool.Name)
+
+	// Show key inputs in compact form; file changes as line counts and
+	// tools with a registered summary as that summary
+	renderer, _ := LookupTool(tool.Name)
+	switch {
+	case tool.Name == "Edit" || tool.Name == "MultiEdit":
+		var added, removed int
+		path, edits := toolEdits(tool.Input)
+		for _, e := range edits {
+			add, del := diffStats(lineDiff(e.Old, e.New))
+			added, removed = added+add, removed+del
+		}
+		Yellow.Fprintf(r.w, " %s ", path)
+		Green.Fprintf(r.w, "+%d", added)
+		Red.Fprintf(r.w, " -%d", removed)
+	case tool.Name == "Write":
+		content := inputString(tool.Input, "content")
+		Yellow.Fprintf(r.w, " %s (%s)", inputString(tool.Input, "file_path"), plural(len(splitLines(content)), "line"))
+	case renderer.Summary != nil:
+		Yellow.Fprintf(r.w, " %s", ToolSummary(tool))
+	case tool.Input != nil:
+		Yellow.Fprint(r.w, " {")
 		first := true
 		for key, value := range tool.Input {
 			if !first {
-				Yellow.Print(", ")
+				Yellow.Fprint(r.w, ", ")
 			}
 			first = false
 
 			switch v := value.(type) {
 			case string:
 				if len(v) > 50 {
-					Yellow.Printf("%s: \"%.50s...\"", key, v)
+					Yellow.Fprintf(r.w, "%s: \"%.50s...\"", key, v)
 				} else {
-					Yellow.Printf("%s: \"%s\"", key, v)
+					Yellow.Fprintf(r.w, "%s: \"%s\"", key, v)
 				}
 			case []interface{}:
-				Yellow.Printf("%s: [%d items]", key, len(v))
+				Yellow.Fprintf(r.w, "%s: [%d items]", key, len(v))
 			default:
-				Yellow.Printf("%s: %v", key, v)
+				Yellow.Fprintf(r.w, "%s: %v", key, v)
 			}
 		}
-		Yellow.Print("}")
+		Yellow.Fprint(r.w, "}")
 	}
-	fmt.Println()
+	fmt.Fprintln(r.w)
 }
 
-func displayUserMessageCompact(msg *parser.StreamMessage, lineNum int, cfg *Config) {
+func (r *compactRenderer) user(msg *parser.StreamMessage, lineNum int) {
 	if msg.Message == nil || len(msg.Message.Content) == 0 {
 		return
 	}
 
+	for _, text := range r.prompts(msg) {
+		BoldBlue.Fprint(r.w, "USR")
+		Gray.Fprintf(r.w, "%s%s ", r.elapsed(), FormatLineNumCompact(lineNum, r.cfg.ShowLineNum))
+		White.Fprintln(r.w, oneLine(text))
+	}
+
 	for _, block := range msg.Message.Content {
 		if block.Type == "tool_result" {
-			displayToolResultCompact(&block, lineNum, cfg)
+			r.toolResult(&block, lineNum)
 		}
 	}
 }
 
-func displayToolResultCompact(block *parser.ContentBlock, lineNum int, cfg *Config) {
+func (r *compactRenderer) toolResult(block *parser.ContentBlock, lineNum int) {
 	if block.IsError {
-		BoldRed.Print("ERR")
+		BoldRed.Fprint(r.w, "ERR")
 	} else {
-		BoldMagenta.Print("RES")
+		BoldMagenta.Fprint(r.w, "RES")
 	}
-	Gray.Printf("%s%s ", FormatElapsed(cfg), FormatLineNumCompact(lineNum, cfg.ShowLineNum))
-
-	contentStr := ""
-	switch v := block.Content.(type) {
-	case string:
-		contentStr = v
-	default:
-		contentStr = fmt.Sprintf("%v", v)
+	Gray.Fprintf(r.w, "%s%s ", r.elapsed(), FormatLineNumCompact(lineNum, r.cfg.ShowLineNum))
+	if call := r.takeCall(block.ToolUseID); call != nil {
+		Yellow.Fprint(r.w, call.Name)
+		if call.Summary != "" {
+			Yellow.Fprintf(r.w, "(%s)", truncateEnd(call.Summary, 30))
+		}
+		Gray.Fprintf(r.w, " %s ", formatDuration(call.Latency))
 	}
 
+	contentStr := parser.ContentText(block.Content)
+
 	// Strip system reminders in non-verbose mode
-	if !cfg.Verbose {
+	if !r.cfg.Verbose {
 		contentStr = parser.StripSystemReminders(contentStr)
 	}
 
 	// Compact output - single line summary
 	contentStr = strings.ReplaceAll(contentStr, "\n", " ")
 	if contentStr == "" {
-		Gray.Println("(no output)")
+		Gray.Fprintln(r.w, "(no output)")
 	} else if len(contentStr) > 100 {
-		White.Printf("%.100s...\n", contentStr)
+		White.Fprintf(r.w, "%.100s...\n", contentStr)
 	} else {
-		White.Println(contentStr)
+		White.Fprintln(r.w, contentStr)
 	}
 }
 
-func displayResultMessageCompact(msg *parser.StreamMessage, lineNum int, cfg *Config) {
+func (r *compactRenderer) result(msg *parser.StreamMessage, lineNum int) {
 	if msg.IsError {
-		BoldRed.Print("FAIL")
+		BoldRed.Fprint(r.w, "FAIL")
 	} else {
-		BoldBlue.Print("OK")
+		BoldBlue.Fprint(r.w, "OK")
 	}
-	Gray.Printf("%s%s", FormatElapsed(cfg), FormatLineNumCompact(lineNum, cfg.ShowLineNum))
+	Gray.Fprintf(r.w, "%s%s", r.elapsed(), FormatLineNumCompact(lineNum, r.cfg.ShowLineNum))
 
 	if msg.NumTurns > 0 {
-		Blue.Printf(" turns=%d", msg.NumTurns)
+		Blue.Fprintf(r.w, " turns=%d", msg.NumTurns)
 	}
 	if msg.DurationMS > 0 {
-		Blue.Printf(" %.2fs", float64(msg.DurationMS)/1000.0)
+		Blue.Fprintf(r.w, " %.2fs", float64(msg.DurationMS)/1000.0)
 	}
 	if msg.TotalCostUSD > 0 {
-		Blue.Printf(" $%.4f", msg.TotalCostUSD)
+		Blue.Fprintf(r.w, " $%.4f", msg.TotalCostUSD)
 	}
 	if msg.Usage != nil {
-		Blue.Printf(" in=%d out=%d", msg.Usage.InputTokens, msg.Usage.OutputTokens)
+		Blue.Fprintf(r.w, " in=%d out=%d", msg.Usage.InputTokens, msg.Usage.OutputTokens)
 	}
-	fmt.Println()
+	fmt.Fprintln(r.w)
 
 	// Show result text if present
 	if msg.Result != "" {
 		result := strings.ReplaceAll(msg.Result, "\n", " ")
-		if len(result) > 200 {
-			White.Printf("  %s...\n", result[:200])
-		} else {
-			White.Printf("  %s\n", result)
-		}
+		White.Fprintf(r.w, "  %s\n", truncateEnd(result, 203))
 	}
 }
@@ -5,59 +5,54 @@ import (
 	"strings"
 
 	"github.com/ariel-frischer/claude-clean/parser"
+	"github.com/fatih/color"
 )
 
-func displayMessageDefault(msg *parser.StreamMessage, lineNum int, cfg *Config) {
-	switch msg.Type {
-	case "system":
-		displaySystemMessage(msg, lineNum, cfg)
-	case "assistant":
-		displayAssistantMessage(msg, lineNum, cfg)
-	case "user":
-		displayUserMessage(msg, lineNum, cfg)
-	case "result":
-		displayResultMessage(msg, lineNum, cfg)
-	default:
-		Gray.Printf("│ [Line %d] Unknown message type: %s\n", lineNum, msg.Type)
-	}
+// defaultRenderer renders messages as colored boxes with box-drawing borders
+type defaultRenderer struct {
+	*base
 }
 
-func displaySystemMessage(msg *parser.StreamMessage, lineNum int, cfg *Config) {
-	BoldCyan.Print("┌─ ")
-	BoldCyan.Print("SYSTEM")
+func (r *defaultRenderer) system(msg *parser.StreamMessage, lineNum int) {
+	BoldCyan.Fprint(r.w, "┌─ ")
+	BoldCyan.Fprint(r.w, "SYSTEM")
 	if msg.Subtype != "" {
-		Cyan.Printf(" [%s]", msg.Subtype)
+		Cyan.Fprintf(r.w, " [%s]", msg.Subtype)
 	}
-	Gray.Printf("%s%s\n", FormatElapsed(cfg), FormatLineNum(lineNum, cfg.ShowLineNum))
+	Gray.Fprintf(r.w, "%s%s\n", r.elapsed(), FormatLineNum(lineNum, r.cfg.ShowLineNum))
 
 	if msg.CWD != "" {
-		Cyan.Printf("│ Working Directory: %s\n", msg.CWD)
+		Cyan.Fprintf(r.w, "│ Working Directory: %s\n", msg.CWD)
 	}
 	if msg.Model != "" {
-		Cyan.Printf("│ Model: %s\n", msg.Model)
+		Cyan.Fprintf(r.w, "│ Model: %s\n", msg.Model)
 	}
 	if msg.ClaudeCodeVersion != "" {
-		Cyan.Printf("│ Claude Code: v%s\n", msg.ClaudeCodeVersion)
+		Cyan.Fprintf(r.w, "│ Claude Code: v%s\n", msg.ClaudeCodeVersion)
 	}
 	if len(msg.Tools) > 0 {
-		Cyan.Printf("│ Tools: %d available\n", len(msg.Tools))
+		Cyan.Fprintf(r.w, "│ Tools: %d available\n", len(msg.Tools))
+	}
+	for _, line := range contentLines(msg) {
+		Cyan.Fprintf(r.w, "│ %s\n", line)
 	}
 
-	Cyan.Println("└─")
+	Cyan.Fprintln(r.w, "└─")
 }
 
-func displayAssistantMessage(msg *parser.StreamMessage, lineNum int, cfg *Config) {
+func (r *defaultRenderer) assistant(msg *parser.StreamMessage, lineNum int) {
 	if msg.Message == nil {
 		return
 	}
 
-	content := msg.Message.Content
+	content := r.unstreamed(msg)
 	if len(content) == 0 {
 		return
 	}
 
 	// Group consecutive text blocks together
 	var textBlocks []string
+	var thinkingBlocks []parser.ContentBlock
 	var toolUses []parser.ContentBlock
 
 	for _, block := range content {
@@ -66,78 +61,164 @@ func displayAssistantMessage(msg *parser.StreamMessage, lineNum int, cfg *Config
 			if block.Text != "" {
 				textBlocks = append(textBlocks, block.Text)
 			}
+		case "thinking", "redacted_thinking":
+			if r.cfg.ShowThinking {
+				thinkingBlocks = append(thinkingBlocks, block)
+			}
 		case "tool_use":
 			toolUses = append(toolUses, block)
 		}
 	}
 
+	// Display thinking blocks
+	for _, block := range thinkingBlocks {
+		r.thinking(&block, lineNum)
+	}
+
 	// Display text blocks
 	if len(textBlocks) > 0 {
-		BoldGreen.Print("┌─ ")
-		BoldGreen.Print("ASSISTANT")
-		Gray.Printf("%s%s\n", FormatElapsed(cfg), FormatLineNum(lineNum, cfg.ShowLineNum))
+		BoldGreen.Fprint(r.w, "┌─ ")
+		BoldGreen.Fprint(r.w, "ASSISTANT")
+		Gray.Fprintf(r.w, "%s%s\n", r.elapsed(), FormatLineNum(lineNum, r.cfg.ShowLineNum))
 
 		for _, text := range textBlocks {
-			Green.Print("│ ")
-			White.Println(text)
+			if r.cfg.NoMarkdown {
+				Green.Fprint(r.w, "│ ")
+				White.Fprintln(r.w, text)
+				continue
+			}
+			writeMarkdown(text, r.textWidth(2), r.textLine)
 		}
 
-		if cfg.Verbose && msg.Message.Usage != nil {
-			DisplayUsage(msg.Message.Usage)
+		if r.cfg.Verbose && msg.Message.Usage != nil {
+			DisplayUsage(r.w, msg.Message.Usage)
 		}
-		Green.Println("└─")
+		Green.Fprintln(r.w, "└─")
 	}
 
 	// Display tool uses
 	for _, tool := range toolUses {
-		displayToolUse(&tool, lineNum, cfg)
+		r.toolUse(&tool, lineNum)
+	}
+}
+
+func (r *defaultRenderer) beginText(lineNum int) {
+	BoldGreen.Fprint(r.w, "┌─ ")
+	BoldGreen.Fprint(r.w, "ASSISTANT")
+	Gray.Fprintf(r.w, "%s%s\n", r.elapsed(), FormatLineNum(lineNum, r.cfg.ShowLineNum))
+}
+
+// textLine writes a rendered line of assistant text
+func (r *defaultRenderer) textLine(line string) {
+	Green.Fprint(r.w, "│ ")
+	fmt.Fprintln(r.w, line)
+}
+
+func (r *defaultRenderer) writeText(text string) {
+	r.stream.writeLines(r.w, text,
+		func() { Green.Fprint(r.w, "│ ") },
+		func(s string) { White.Fprint(r.w, s) })
+}
+
+func (r *defaultRenderer) endText() {
+	if r.stream.lineOpen {
+		fmt.Fprintln(r.w)
+	}
+	Green.Fprintln(r.w, "└─")
+}
+
+func (r *defaultRenderer) thinking(block *parser.ContentBlock, lineNum int) {
+	text := thinkingText(block)
+	if text == "" {
+		return
+	}
+
+	Gray.Fprint(r.w, "┌─ THINKING")
+	Gray.Fprintf(r.w, "%s%s\n", r.elapsed(), FormatLineNum(lineNum, r.cfg.ShowLineNum))
+
+	for _, line := range strings.Split(text, "\n") {
+		Gray.Fprintf(r.w, "│ %s\n", line)
+	}
+
+	if r.cfg.Verbose && block.Signature != "" {
+		Gray.Fprintf(r.w, "│ Signature: %d bytes\n", len(block.Signature))
+	}
+	Gray.Fprintln(r.w, "└─")
+}
+
+func (r *defaultRenderer) subagentStart(a *subagent, lineNum int) {
+	BoldCyan.Fprint(r.w, "┌─ ")
+	BoldCyan.Fprintf(r.w, "SUBAGENT: %s", subagentName(a))
+	if a.started {
+		Cyan.Fprint(r.w, " (continued)")
+	} else if a.Description != "" {
+		Cyan.Fprintf(r.w, " - %s", a.Description)
 	}
+	Gray.Fprintf(r.w, "%s%s\n", r.elapsed(), FormatLineNum(lineNum, r.cfg.ShowLineNum))
 }
 
-func displayToolUse(tool *parser.ContentBlock, lineNum int, cfg *Config) {
-	BoldYellow.Print("┌─ ")
-	BoldYellow.Printf("TOOL: %s", tool.Name)
-	Gray.Printf("%s%s\n", FormatElapsed(cfg), FormatLineNum(lineNum, cfg.ShowLineNum))
+func (r *defaultRenderer) subagentEnd(a *subagent) {
+	Cyan.Fprintf(r.w, "└─ SUBAGENT DONE: %s (%s)\n", subagentName(a), subagentSummary(a))
+}
 
-	if cfg.Verbose {
-		Yellow.Printf("│ ID: %s\n", tool.ID)
+func (r *defaultRenderer) unansweredCalls(calls []*toolCall) {
+	BoldYellow.Fprint(r.w, "┌─ ")
+	BoldYellow.Fprintf(r.w, "WARNING: %s never received a result\n", plural(len(calls), "tool call"))
+	for _, call := range calls {
+		Yellow.Fprintf(r.w, "│ %s", callLabel(call))
+		Gray.Fprintf(r.w, "%s\n", FormatLineNum(call.LineNum, true))
 	}
+	Yellow.Fprintln(r.w, "└─")
+}
 
-	if tool.Input != nil {
-		Yellow.Println("│ Input:")
+func (r *defaultRenderer) toolUse(tool *parser.ContentBlock, lineNum int) {
+	BoldYellow.Fprint(r.w, "┌─ ")
+	BoldYellow.Fprintf(r.w, "TOOL: %s", tool.Name)
+	Gray.Fprintf(r.w, "%s%s\n", r.elapsed(), FormatLineNum(lineNum, r.cfg.ShowLineNum))
+
+	if r.cfg.Verbose {
+		Yellow.Fprintf(r.w, "│ ID: %s\n", tool.ID)
+	}
+
+	if lines := inputLines(tool.Name, tool.Input); lines != nil {
+		r.toolLines(Yellow, TruncateLongOutput(lines, r.cfg.inputTruncation()))
+	} else if tool.Input != nil {
+		Yellow.Fprintln(r.w, "│ Input:")
 		for key, value := range tool.Input {
 			// Pretty print the value
-			Yellow.Printf("│   %s: ", key)
+			Yellow.Fprintf(r.w, "│   %s: ", key)
 
 			switch v := value.(type) {
 			case string:
-				// Show more context for strings - first 200 + last 100 chars
-				if len(v) > 300 {
-					White.Printf("%s ... (%d chars omitted) ... %s\n",
-						v[:200], len(v)-300, v[len(v)-100:])
-				} else {
-					White.Println(v)
-				}
+				White.Fprintln(r.w, truncateChars(v, r.cfg.inputChars()))
 			case []interface{}:
 				// Special handling for todos array in TodoWrite tool
 				if tool.Name == "TodoWrite" && key == "todos" {
-					White.Println()
-					DisplayTodos(v)
+					White.Fprintln(r.w)
+					DisplayTodos(r.w, v)
 				} else {
-					White.Printf("[%d items]\n", len(v))
+					White.Fprintf(r.w, "[%d items]\n", len(v))
 				}
 			case map[string]interface{}:
-				White.Println("{...}")
+				White.Fprintln(r.w, "{...}")
 			default:
-				White.Printf("%v\n", v)
+				White.Fprintf(r.w, "%v\n", v)
 			}
 		}
 	}
 
-	Yellow.Println("└─")
+	Yellow.Fprintln(r.w, "└─")
 }
 
-func displayUserMessage(msg *parser.StreamMessage, lineNum int, cfg *Config) {
+// toolLines writes tool input or result lines after a "│ " border
+func (r *defaultRenderer) toolLines(border *color.Color, lines []ToolLine) {
+	for _, l := range lines {
+		border.Fprint(r.w, "│ ")
+		printToolLine(r.w, l)
+	}
+}
+
+func (r *defaultRenderer) user(msg *parser.StreamMessage, lineNum int) {
 	if msg.Message == nil {
 		return
 	}
@@ -147,152 +228,139 @@ func displayUserMessage(msg *parser.StreamMessage, lineNum int, cfg *Config) {
 		return
 	}
 
+	for _, text := range r.prompts(msg) {
+		BoldBlue.Fprint(r.w, "┌─ ")
+		BoldBlue.Fprint(r.w, "USER")
+		Gray.Fprintf(r.w, "%s%s\n", r.elapsed(), FormatLineNum(lineNum, r.cfg.ShowLineNum))
+		for _, line := range strings.Split(text, "\n") {
+			Blue.Fprint(r.w, "│ ")
+			White.Fprintln(r.w, line)
+		}
+		Blue.Fprintln(r.w, "└─")
+	}
+
 	for _, block := range content {
 		if block.Type == "tool_result" {
-			displayToolResult(&block, lineNum, cfg)
+			r.toolResult(&block, lineNum)
 		}
 	}
 }
 
-func displayToolResult(block *parser.ContentBlock, lineNum int, cfg *Config) {
-	if block.IsError {
-		BoldRed.Print("┌─ ")
-		BoldRed.Print("TOOL RESULT ERROR")
-		Gray.Printf("%s%s\n", FormatElapsed(cfg), FormatLineNum(lineNum, cfg.ShowLineNum))
+func (r *defaultRenderer) toolResult(block *parser.ContentBlock, lineNum int) {
+	call := r.takeCall(block.ToolUseID)
 
-		if cfg.Verbose {
-			Red.Printf("│ Tool ID: %s\n", block.ToolUseID)
+	if block.IsError {
+		BoldRed.Fprint(r.w, "┌─ ")
+		BoldRed.Fprint(r.w, "TOOL RESULT ERROR")
+		if call != nil {
+			BoldRed.Fprintf(r.w, ": %s", call.Name)
+			if call.Summary != "" {
+				Red.Fprintf(r.w, " (%s)", call.Summary)
+			}
+			Gray.Fprintf(r.w, " [%s]", formatDuration(call.Latency))
 		}
+		Gray.Fprintf(r.w, "%s%s\n", r.elapsed(), FormatLineNum(lineNum, r.cfg.ShowLineNum))
 
-		contentStr := ""
-		switch v := block.Content.(type) {
-		case string:
-			contentStr = v
-		default:
-			contentStr = fmt.Sprintf("%v", v)
+		if r.cfg.Verbose {
+			Red.Fprintf(r.w, "│ Tool ID: %s\n", block.ToolUseID)
 		}
 
+		contentStr := parser.ContentText(block.Content)
+
 		// Strip system reminders in non-verbose mode
-		if !cfg.Verbose {
+		if !r.cfg.Verbose {
 			contentStr = parser.StripSystemReminders(contentStr)
 		}
 
-		Red.Print("│ ")
-		White.Println(contentStr)
-		Red.Println("└─")
+		r.toolLines(Red, TruncateLongOutput(textLines(ToolLineText, contentStr), r.resultTruncation(call, true)))
+		Red.Fprintln(r.w, "└─")
 	} else {
-		BoldMagenta.Print("┌─ ")
-		BoldMagenta.Print("TOOL RESULT")
-		Gray.Printf("%s%s\n", FormatElapsed(cfg), FormatLineNum(lineNum, cfg.ShowLineNum))
-
-		if cfg.Verbose {
-			Gray.Printf("│ Tool ID: %s\n", block.ToolUseID)
+		BoldMagenta.Fprint(r.w, "┌─ ")
+		BoldMagenta.Fprint(r.w, "TOOL RESULT")
+		if call != nil {
+			BoldMagenta.Fprintf(r.w, ": %s", call.Name)
+			if call.Summary != "" {
+				Magenta.Fprintf(r.w, " (%s)", call.Summary)
+			}
+			Gray.Fprintf(r.w, " [%s]", formatDuration(call.Latency))
 		}
+		Gray.Fprintf(r.w, "%s%s\n", r.elapsed(), FormatLineNum(lineNum, r.cfg.ShowLineNum))
 
-		contentStr := ""
-		switch v := block.Content.(type) {
-		case string:
-			contentStr = v
-		default:
-			contentStr = fmt.Sprintf("%v", v)
+		if r.cfg.Verbose {
+			Gray.Fprintf(r.w, "│ Tool ID: %s\n", block.ToolUseID)
 		}
 
+		contentStr := parser.ContentText(block.Content)
+
 		// Strip system reminders in non-verbose mode
-		if !cfg.Verbose {
+		if !r.cfg.Verbose {
 			contentStr = parser.StripSystemReminders(contentStr)
 		}
 
 		if contentStr == "" {
-			Gray.Println("│ (no output)")
+			Gray.Fprintln(r.w, "│ (no output)")
 		} else {
-			// Show first 20 + last 20 lines for long output
-			lines := strings.Split(contentStr, "\n")
-			firstLines := parser.FirstLines
-			lastLines := parser.LastLines
-			totalLines := len(lines)
-
-			if totalLines <= firstLines+lastLines {
-				// Show all lines if content is short enough
-				for _, line := range lines {
-					Gray.Print("│ ")
-					White.Println(line)
-				}
-			} else {
-				// Show first 20 lines
-				for i := 0; i < firstLines; i++ {
-					Gray.Print("│ ")
-					White.Println(lines[i])
-				}
-
-				// Show summary of middle content
-				Gray.Printf("│ ... (%d more lines) ...\n", totalLines-firstLines-lastLines)
-
-				// Show last 20 lines
-				for i := totalLines - lastLines; i < totalLines; i++ {
-					Gray.Print("│ ")
-					White.Println(lines[i])
-				}
-			}
+			r.toolLines(Gray, TruncateLongOutput(resultLines(call, contentStr), r.resultTruncation(call, false)))
 		}
 
-		Gray.Println("└─")
+		Gray.Fprintln(r.w, "└─")
 	}
 }
 
-func displayResultMessage(msg *parser.StreamMessage, lineNum int, cfg *Config) {
+func (r *defaultRenderer) result(msg *parser.StreamMessage, lineNum int) {
 	if msg.IsError {
-		BoldRed.Print("┌─ ")
-		BoldRed.Print("RESULT: ERROR")
+		BoldRed.Fprint(r.w, "┌─ ")
+		BoldRed.Fprint(r.w, "RESULT: ERROR")
 	} else {
-		BoldBlue.Print("┌─ ")
-		BoldBlue.Print("RESULT: SUCCESS")
+		BoldBlue.Fprint(r.w, "┌─ ")
+		BoldBlue.Fprint(r.w, "RESULT: SUCCESS")
 	}
-	Gray.Printf("%s%s\n", FormatElapsed(cfg), FormatLineNum(lineNum, cfg.ShowLineNum))
+	Gray.Fprintf(r.w, "%s%s\n", r.elapsed(), FormatLineNum(lineNum, r.cfg.ShowLineNum))
 
 	// Show summary stats
 	if msg.NumTurns > 0 {
-		Blue.Printf("│ Turns: %d\n", msg.NumTurns)
+		Blue.Fprintf(r.w, "│ Turns: %d\n", msg.NumTurns)
 	}
 	if msg.DurationMS > 0 {
-		Blue.Printf("│ Duration: %.2fs", float64(msg.DurationMS)/1000.0)
+		Blue.Fprintf(r.w, "│ Duration: %.2fs", float64(msg.DurationMS)/1000.0)
 		if msg.DurationAPIMS > 0 {
-			Blue.Printf(" (API: %.2fs)", float64(msg.DurationAPIMS)/1000.0)
+			Blue.Fprintf(r.w, " (API: %.2fs)", float64(msg.DurationAPIMS)/1000.0)
 		}
-		Blue.Println()
+		Blue.Fprintln(r.w)
 	}
 	if msg.TotalCostUSD > 0 {
-		Blue.Printf("│ Cost: $%.4f\n", msg.TotalCostUSD)
+		Blue.Fprintf(r.w, "│ Cost: $%.4f\n", msg.TotalCostUSD)
 	}
 
 	// Show detailed token usage
 	if msg.Usage != nil {
-		Blue.Println("│")
-		Blue.Print("│ ")
-		Blue.Printf("Tokens: in=%d out=%d", msg.Usage.InputTokens, msg.Usage.OutputTokens)
+		Blue.Fprintln(r.w, "│")
+		Blue.Fprint(r.w, "│ ")
+		Blue.Fprintf(r.w, "Tokens: in=%d out=%d", msg.Usage.InputTokens, msg.Usage.OutputTokens)
 		if msg.Usage.CacheReadInputTokens > 0 {
-			Blue.Printf(" cache_read=%d", msg.Usage.CacheReadInputTokens)
+			Blue.Fprintf(r.w, " cache_read=%d", msg.Usage.CacheReadInputTokens)
 		}
 		if msg.Usage.CacheCreationInputTokens > 0 {
-			Blue.Printf(" cache_create=%d", msg.Usage.CacheCreationInputTokens)
+			Blue.Fprintf(r.w, " cache_create=%d", msg.Usage.CacheCreationInputTokens)
 		}
-		Blue.Println()
+		Blue.Fprintln(r.w)
 	}
 
 	// Show per-model usage in verbose mode
-	if cfg.Verbose && msg.ModelUsage != nil && len(msg.ModelUsage) > 0 {
-		Blue.Println("│")
-		Blue.Println("│ Model Usage:")
+	if r.cfg.Verbose && msg.ModelUsage != nil && len(msg.ModelUsage) > 0 {
+		Blue.Fprintln(r.w, "│")
+		Blue.Fprintln(r.w, "│ Model Usage:")
 		for model, usageData := range msg.ModelUsage {
-			Blue.Printf("│   %s:\n", model)
+			Blue.Fprintf(r.w, "│   %s:\n", model)
 			if usageMap, ok := usageData.(map[string]interface{}); ok {
 				if inputTokens, ok := usageMap["inputTokens"].(float64); ok {
-					Blue.Printf("│     Input: %.0f tokens\n", inputTokens)
+					Blue.Fprintf(r.w, "│     Input: %.0f tokens\n", inputTokens)
 				}
 				if outputTokens, ok := usageMap["outputTokens"].(float64); ok {
-					Blue.Printf("│     Output: %.0f tokens\n", outputTokens)
+					Blue.Fprintf(r.w, "│     Output: %.0f tokens\n", outputTokens)
 				}
 				if cost, ok := usageMap["costUSD"].(float64); ok {
-					Blue.Printf("│     Cost: $%.4f\n", cost)
+					Blue.Fprintf(r.w, "│     Cost: $%.4f\n", cost)
 				}
 			}
 		}
@@ -300,25 +368,29 @@ func displayResultMessage(msg *parser.StreamMessage, lineNum int, cfg *Config) {
 
 	// Show permission denials if present
 	if len(msg.PermissionDenials) > 0 {
-		Blue.Println("│")
-		Red.Printf("│ Permission Denials: %d\n", len(msg.PermissionDenials))
-		if cfg.Verbose {
+		Blue.Fprintln(r.w, "│")
+		Red.Fprintf(r.w, "│ Permission Denials: %d\n", len(msg.PermissionDenials))
+		if r.cfg.Verbose {
 			for i, denial := range msg.PermissionDenials {
-				Red.Printf("│   [%d] %v\n", i+1, denial)
+				Red.Fprintf(r.w, "│   [%d] %v\n", i+1, denial)
 			}
 		}
 	}
 
 	// Show result content if present
 	if msg.Result != "" {
-		Blue.Println("│")
+		Blue.Fprintln(r.w, "│")
 		// Split result into lines and display
 		lines := strings.Split(msg.Result, "\n")
 		for _, line := range lines {
-			Blue.Print("│ ")
-			White.Println(line)
+			Blue.Fprint(r.w, "│ ")
+			White.Fprintln(r.w, line)
 		}
 	}
 
-	Blue.Println("└─")
+	Blue.Fprintln(r.w, "└─")
+}
+
+func (r *defaultRenderer) unknown(msg *parser.StreamMessage, lineNum int) {
+	Gray.Fprintf(r.w, "│ [Line %d] Unknown message type: %s\n", lineNum, msg.Type)
 }
@@ -0,0 +1,210 @@
+package display
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines kept around each change
+const diffContext = 3
+
+// maxDiffCells bounds the size of the table used to diff two texts. Larger
+// changes are shown as a removal of the old text followed by the new text.
+const maxDiffCells = 1 << 20
+
+// Diff operations. diffSkip marks a run of unchanged lines left out of the output.
+const (
+	diffEqual  = ' '
+	diffRemove = '-'
+	diffAdd    = '+'
+	diffSkip   = '~'
+)
+
+// diffLine is a line of a unified diff
+type diffLine struct {
+	Op      byte
+	Text    string
+	Skipped int // number of unchanged lines a diffSkip line stands for
+}
+
+// fileEdit is a single replacement made by an Edit or MultiEdit tool call
+type fileEdit struct {
+	Old, New   string
+	ReplaceAll bool
+}
+
+// toolEdits returns the file path and replacements of the input of an Edit
+// call, or of a MultiEdit call with a list of edits
+func toolEdits(input map[string]interface{}) (string, []fileEdit) {
+	edit := func(m map[string]interface{}) fileEdit {
+		var e fileEdit
+		e.Old, _ = m["old_string"].(string)
+		e.New, _ = m["new_string"].(string)
+		e.ReplaceAll, _ = m["replace_all"].(bool)
+		return e
+	}
+
+	items, ok := input["edits"].([]interface{})
+	if !ok {
+		return inputString(input, "file_path"), []fileEdit{edit(input)}
+	}
+	var edits []fileEdit
+	for _, item := range items {
+		if m, ok := item.(map[string]interface{}); ok {
+			edits = append(edits, edit(m))
+		}
+	}
+	return inputString(input, "file_path"), edits
+}
+
+// splitLines splits text into lines, ignoring a final newline
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// lineDiff returns the unified diff of two texts, with unchanged runs longer
+// than the surrounding context collapsed into diffSkip lines
+func lineDiff(oldText, newText string) []diffLine {
+	a, b := splitLines(oldText), splitLines(newText)
+
+	// Common leading and trailing lines need no table
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var lines []diffLine
+	for _, l := range a[:prefix] {
+		lines = append(lines, diffLine{Op: diffEqual, Text: l})
+	}
+	lines = append(lines, middleDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, l := range a[len(a)-suffix:] {
+		lines = append(lines, diffLine{Op: diffEqual, Text: l})
+	}
+	return collapseUnchanged(lines, diffContext)
+}
+
+// middleDiff diffs two runs of lines using their longest common subsequence
+func middleDiff(a, b []string) []diffLine {
+	var lines []diffLine
+	if len(a)*len(b) > maxDiffCells {
+		for _, l := range a {
+			lines = append(lines, diffLine{Op: diffRemove, Text: l})
+		}
+		for _, l := range b {
+			lines = append(lines, diffLine{Op: diffAdd, Text: l})
+		}
+		return lines
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, diffLine{Op: diffEqual, Text: a[i]})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, diffLine{Op: diffRemove, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{Op: diffAdd, Text: b[j]})
+			j++
+		}
+	}
+	return lines
+}
+
+// collapseUnchanged replaces unchanged lines further than context lines from
+// any change with a diffSkip line. Lines without any change are kept as they are.
+func collapseUnchanged(lines []diffLine, context int) []diffLine {
+	var out []diffLine
+	for i := 0; i < len(lines); {
+		if lines[i].Op != diffEqual {
+			out = append(out, lines[i])
+			i++
+			continue
+		}
+		end := i
+		for end < len(lines) && lines[end].Op == diffEqual {
+			end++
+		}
+
+		keepBefore, keepAfter := context, context
+		if i == 0 {
+			keepBefore = 0
+		}
+		if end == len(lines) {
+			keepAfter = 0
+		}
+		if end-i <= keepBefore+keepAfter || end-i == len(lines) {
+			out = append(out, lines[i:end]...)
+		} else {
+			out = append(out, lines[i:i+keepBefore]...)
+			out = append(out, diffLine{Op: diffSkip, Skipped: end - i - keepBefore - keepAfter})
+			out = append(out, lines[end-keepAfter:end]...)
+		}
+		i = end
+	}
+	return out
+}
+
+// diffStats counts the added and removed lines of a diff
+func diffStats(lines []diffLine) (added, removed int) {
+	for _, l := range lines {
+		switch l.Op {
+		case diffAdd:
+			added++
+		case diffRemove:
+			removed++
+		}
+	}
+	return added, removed
+}
+
+// String formats the line as in a unified diff, with its operation as the
+// first character
+func (l diffLine) String() string {
+	if l.Op == diffSkip {
+		return fmt.Sprintf("  ... (%s) ...", plural(l.Skipped, "unchanged line"))
+	}
+	return string(l.Op) + l.Text
+}
+
+// editHeader describes an edit of a MultiEdit call, or of an Edit call with
+// replace_all, and returns "" for a plain single edit
+func editHeader(i, n int, e fileEdit) string {
+	var parts []string
+	if n > 1 {
+		parts = append(parts, fmt.Sprintf("edit %d/%d", i+1, n))
+	}
+	if e.ReplaceAll {
+		parts = append(parts, "all occurrences")
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "@@ " + strings.Join(parts, ", ") + " @@"
+}
@@ -0,0 +1,125 @@
+package display
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ariel-frischer/claude-clean/parser"
+	"github.com/fatih/color"
+)
+
+func diffString(lines []diffLine) string {
+	var sb strings.Builder
+	for _, l := range lines {
+		sb.WriteString(l.String() + "\n")
+	}
+	return sb.String()
+}
+
+func TestLineDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old      string
+		new      string
+		expected string
+	}{
+		{"identical", "a\nb", "a\nb", " a\n b\n"},
+		{"change", "a\nb\nc", "a\nB\nc", " a\n-b\n+B\n c\n"},
+		{"insert", "a\nc", "a\nb\nc", " a\n+b\n c\n"},
+		{"delete", "a\nb\nc\n", "a\nc\n", " a\n-b\n c\n"},
+		{"from empty", "", "x\ny", "+x\n+y\n"},
+		{"to empty", "x", "", "-x\n"},
+		{"interleaved", "a\nb\nc\nd", "b\nx\nd\ne", "-a\n b\n-c\n+x\n d\n+e\n"},
+		{
+			"long unchanged runs collapse",
+			"1\n2\n3\n4\n5\nold\n6\n7\n8\n9\n10\n11\n12\n13\nold",
+			"1\n2\n3\n4\n5\nnew\n6\n7\n8\n9\n10\n11\n12\n13\nnew",
+			"  ... (2 unchanged lines) ...\n 3\n 4\n 5\n-old\n+new\n 6\n 7\n 8\n  ... (2 unchanged lines) ...\n 11\n 12\n 13\n-old\n+new\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffString(lineDiff(tt.old, tt.new))
+			if got != tt.expected {
+				t.Errorf("lineDiff(%q, %q) =\n%s\nwant:\n%s", tt.old, tt.new, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLineDiffLargeInput(t *testing.T) {
+	// Too large for the table: everything is replaced, but nothing is lost
+	var a, b []string
+	for i := 0; i < 1500; i++ {
+		a = append(a, fmt.Sprintf("old %d", i))
+		b = append(b, fmt.Sprintf("new %d", i))
+	}
+	added, removed := diffStats(lineDiff(strings.Join(a, "\n"), strings.Join(b, "\n")))
+	if added != 1500 || removed != 1500 {
+		t.Errorf("got +%d -%d, want +1500 -1500", added, removed)
+	}
+}
+
+func TestFileToolRendering(t *testing.T) {
+	msg := &parser.StreamMessage{Type: "assistant", Message: &parser.MessageContent{Content: []parser.ContentBlock{
+		{Type: "tool_use", ID: "t1", Name: "MultiEdit", Input: map[string]interface{}{
+			"file_path": "/repo/main.go",
+			"edits": []interface{}{
+				map[string]interface{}{"old_string": "a := 1\nb := 2", "new_string": "a := 10\nb := 2"},
+				map[string]interface{}{"old_string": "x", "new_string": "y", "replace_all": true},
+			},
+		}},
+		{Type: "tool_use", ID: "t2", Name: "Write", Input: map[string]interface{}{
+			"file_path": "/repo/new.go", "content": "package main\n\nfunc main() {}\n",
+		}},
+	}}}
+
+	render := func(style OutputStyle) string {
+		var buf bytes.Buffer
+		r := NewRenderer(&buf, &Config{Style: style})
+		Render(r, msg, 1)
+		return buf.String()
+	}
+
+	tests := []struct {
+		style            OutputStyle
+		expectedIncludes []string
+	}{
+		{StylePlain, []string{
+			"    /repo/main.go\n    @@ edit 1/2 @@\n    -a := 1\n    +a := 10\n     b := 2\n",
+			"    @@ edit 2/2, all occurrences @@\n    -x\n    +y\n",
+			"    /repo/new.go (3 lines)\n    1  package main\n    2  \n    3  func main() {}\n",
+		}},
+		{StyleCompact, []string{"MultiEdit /repo/main.go +2 -2", "Write /repo/new.go (3 lines)"}},
+		{StyleHTML, []string{`<span class="del">-a := 1</span>`, `<span class="add">+a := 10</span>`, `<span class="ln">1</span>  package main`, `<span class="subject">/repo/main.go</span>`}},
+		{StyleMarkdown, []string{"```diff\n@@ edit 1/2 @@\n-a := 1\n+a := 10\n b := 2\n"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.style), func(t *testing.T) {
+			output := stripANSI(render(tt.style))
+			for _, want := range tt.expectedIncludes {
+				if !strings.Contains(output, want) {
+					t.Errorf("output missing %q\nGot:\n%s", want, output)
+				}
+			}
+			if strings.Contains(output, "old_string") {
+				t.Errorf("edits shown as raw input:\n%s", output)
+			}
+		})
+	}
+
+	// The terminal styles color removed and added lines
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+	output := render(StyleDefault)
+	for _, want := range []string{Red.Sprint("-a := 1"), Green.Sprint("+a := 10")} {
+		if !strings.Contains(output, want) {
+			t.Errorf("default output missing %q\nGot:\n%q", want, output)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package display
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strings"
 	"time"
 
@@ -13,10 +15,12 @@ import (
 type OutputStyle string
 
 const (
-	StyleDefault OutputStyle = "default"
-	StyleCompact OutputStyle = "compact"
-	StyleMinimal OutputStyle = "minimal"
-	StylePlain   OutputStyle = "plain"
+	StyleDefault  OutputStyle = "default"
+	StyleCompact  OutputStyle = "compact"
+	StyleMinimal  OutputStyle = "minimal"
+	StylePlain    OutputStyle = "plain"
+	StyleHTML     OutputStyle = "html"
+	StyleMarkdown OutputStyle = "markdown"
 )
 
 // Config holds display configuration options
@@ -25,38 +29,108 @@ type Config struct {
 	Verbose        bool
 	ShowLineNum    bool
 	ShowTimestamps bool
+	ShowThinking   bool
+	NoMarkdown     bool // print assistant text as is instead of rendering its markdown
+	Width          int  // output width in columns, 0 for the terminal's width
 	StartTime      time.Time
+
+	// Truncation limits, 0 for the defaults
+	HeadLines  int // lines shown at the start of a long tool result
+	TailLines  int // lines shown at the end of a long tool result
+	InputChars int // characters of a string tool input shown in full
+	LineChars  int // characters of a single line of tool output shown in full
+
+	TruncateErrors bool                  // truncate error results, which are shown in full by default
+	TruncateTools  map[string]Truncation // policies for the results of tools, by name or glob pattern
+	Full           bool                  // show tool inputs and results in full
+
+	// Filters, applied by every style
+	HideTools  []string // names or glob patterns of tools whose calls and results are hidden
+	OnlyTools  []string // if set, the only tools whose calls and results are shown
+	HideTypes  []string // message types hidden, from MessageTypes
+	OnlyTypes  []string // if set, the only message types shown
+	ErrorsOnly bool     // show only error tool results and the result message
+	HideDepth  int      // subagent nesting level from which messages are hidden (1 hides all subagents), 0 for none
+
+	Redact []Redaction // replacements applied to every line of output
+}
+
+// DefaultInputChars is the default length at which string tool inputs are shortened
+const DefaultInputChars = 300
+
+func (c *Config) headLines() int {
+	if c.HeadLines > 0 {
+		return c.HeadLines
+	}
+	return parser.FirstLines
+}
+
+func (c *Config) tailLines() int {
+	if c.TailLines > 0 {
+		return c.TailLines
+	}
+	return parser.LastLines
+}
+
+// inputChars returns the length at which string tool inputs are shortened,
+// or 0 if they are shown in full
+func (c *Config) inputChars() int {
+	if c.Full {
+		return 0
+	}
+	if c.InputChars > 0 {
+		return c.InputChars
+	}
+	return DefaultInputChars
 }
 
-// Color definitions
+// Color definitions. They are named after their colors in the dark theme
+// and set by SetTheme from the roles of the current theme.
 var (
-	BoldCyan    = color.New(color.FgCyan, color.Bold)
-	BoldGreen   = color.New(color.FgGreen, color.Bold)
-	BoldYellow  = color.New(color.FgYellow, color.Bold)
-	BoldRed     = color.New(color.FgRed, color.Bold)
-	BoldMagenta = color.New(color.FgMagenta, color.Bold)
-	BoldBlue    = color.New(color.FgBlue, color.Bold)
-	Cyan        = color.New(color.FgCyan)
-	Green       = color.New(color.FgGreen)
-	Yellow      = color.New(color.FgYellow)
-	Red         = color.New(color.FgRed)
-	Blue        = color.New(color.FgBlue)
-	Gray        = color.New(color.FgHiBlack)
-	White       = color.New(color.FgWhite)
+	BoldCyan    *color.Color // RoleSystem
+	BoldGreen   *color.Color // RoleAssistant
+	BoldYellow  *color.Color // RoleTool
+	BoldRed     *color.Color // RoleError
+	BoldMagenta *color.Color // RoleToolResult
+	BoldBlue    *color.Color // RoleResult
+	Cyan        *color.Color
+	Green       *color.Color
+	Yellow      *color.Color
+	Red         *color.Color
+	Blue        *color.Color
+	Magenta     *color.Color
+	Gray        *color.Color // RoleMuted
+	White       *color.Color // RoleText
 )
 
-// DisplayMessage routes to the appropriate formatter based on style
-func DisplayMessage(msg *parser.StreamMessage, lineNum int, cfg *Config) {
-	switch cfg.Style {
-	case StyleCompact:
-		displayMessageCompact(msg, lineNum, cfg)
-	case StyleMinimal:
-		displayMessageMinimal(msg, lineNum, cfg)
-	case StylePlain:
-		displayMessagePlain(msg, lineNum, cfg)
-	default: // StyleDefault
-		displayMessageDefault(msg, lineNum, cfg)
+// toolLineColor returns the color of a kind of tool input or result line in the terminal styles
+func toolLineColor(kind ToolLineKind) *color.Color {
+	switch kind {
+	case ToolLineMuted:
+		return Gray
+	case ToolLineHeader:
+		return Cyan
+	case ToolLineAdded:
+		return Green
+	case ToolLineRemoved:
+		return Red
+	default:
+		return White
+	}
+}
+
+// printToolLine writes a tool input or result line in its color, after its gutter
+func printToolLine(w io.Writer, l ToolLine) {
+	if l.Gutter != "" {
+		Gray.Fprintf(w, "%s  ", l.Gutter)
 	}
+	toolLineColor(l.Kind).Fprintln(w, l.Text)
+}
+
+// DisplayMessage renders a single message to stdout in the configured style.
+// Use NewRenderer to render a whole stream or to write somewhere other than stdout.
+func DisplayMessage(msg *parser.StreamMessage, lineNum int, cfg *Config) {
+	Render(NewRenderer(os.Stdout, cfg), msg, lineNum)
 }
 
 // FormatLineNum returns a formatted line number string if showLineNum is enabled
@@ -80,7 +154,11 @@ func FormatElapsed(cfg *Config) string {
 	if !cfg.ShowTimestamps || cfg.StartTime.IsZero() {
 		return ""
 	}
-	d := time.Since(cfg.StartTime)
+	return formatElapsed(time.Since(cfg.StartTime))
+}
+
+// formatElapsed formats a time since the start of a session
+func formatElapsed(d time.Duration) string {
 	secs := d.Seconds()
 	if secs < 60 {
 		return fmt.Sprintf(" +%.1fs", secs)
@@ -90,38 +168,83 @@ func FormatElapsed(cfg *Config) string {
 	return fmt.Sprintf(" +%dm%ds", mins, remSecs)
 }
 
+// prompts returns the text of a user message: the prompts of a session
+// transcript, or messages sent with --input-format stream-json. System
+// reminders and the messages Claude Code adds itself are left out unless
+// Verbose.
+func (b *base) prompts(msg *parser.StreamMessage) []string {
+	if msg.IsMeta && !b.cfg.Verbose {
+		return nil
+	}
+	var texts []string
+	for _, block := range msg.Message.Content {
+		if block.Type != "text" {
+			continue
+		}
+		text := block.Text
+		if !b.cfg.Verbose {
+			text = parser.StripSystemReminders(text)
+		}
+		if text = strings.TrimSpace(text); text != "" {
+			texts = append(texts, text)
+		}
+	}
+	return texts
+}
+
+// contentLines returns the lines of the text of a transcript system message
+func contentLines(msg *parser.StreamMessage) []string {
+	if msg.Content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSpace(msg.Content), "\n")
+}
+
+// oneLine returns text on a single line, shortened for the compact style
+func oneLine(text string) string {
+	return truncateEnd(strings.ReplaceAll(text, "\n", " "), 103)
+}
+
+// thinkingText returns the displayable text of a thinking or redacted_thinking block
+func thinkingText(block *parser.ContentBlock) string {
+	if block.Type == "redacted_thinking" {
+		return "(redacted)"
+	}
+	return strings.TrimSpace(block.Thinking)
+}
+
 // DisplayUsage shows token usage statistics
-func DisplayUsage(usage *parser.Usage) {
-	Gray.Print("│ ")
-	Gray.Printf("Tokens: in=%d out=%d", usage.InputTokens, usage.OutputTokens)
+func DisplayUsage(w io.Writer, usage *parser.Usage) {
+	Gray.Fprint(w, "│ ")
+	Gray.Fprintf(w, "Tokens: in=%d out=%d", usage.InputTokens, usage.OutputTokens)
 
 	if usage.CacheReadInputTokens > 0 {
-		Gray.Printf(" cache_read=%d", usage.CacheReadInputTokens)
+		Gray.Fprintf(w, " cache_read=%d", usage.CacheReadInputTokens)
 	}
 	if usage.CacheCreationInputTokens > 0 {
-		Gray.Printf(" cache_create=%d", usage.CacheCreationInputTokens)
+		Gray.Fprintf(w, " cache_create=%d", usage.CacheCreationInputTokens)
 	}
 
-	Gray.Println()
+	Gray.Fprintln(w)
 }
 
 // DisplayUsageInline shows usage inline with a specific color
-func DisplayUsageInline(usage *parser.Usage, c *color.Color) {
-	c.Print("│ ")
-	c.Printf("Tokens: in=%d out=%d", usage.InputTokens, usage.OutputTokens)
+func DisplayUsageInline(w io.Writer, usage *parser.Usage, c *color.Color) {
+	c.Fprint(w, "│ ")
+	c.Fprintf(w, "Tokens: in=%d out=%d", usage.InputTokens, usage.OutputTokens)
 
 	if usage.CacheReadInputTokens > 0 {
-		c.Printf(" cache_read=%d", usage.CacheReadInputTokens)
+		c.Fprintf(w, " cache_read=%d", usage.CacheReadInputTokens)
 	}
 	if usage.CacheCreationInputTokens > 0 {
-		c.Printf(" cache_create=%d", usage.CacheCreationInputTokens)
+		c.Fprintf(w, " cache_create=%d", usage.CacheCreationInputTokens)
 	}
 
-	c.Println()
+	c.Fprintln(w)
 }
 
 // DisplayTodos displays todo items with status icons
-func DisplayTodos(todos []interface{}) {
+func DisplayTodos(w io.Writer, todos []interface{}) {
 	for i, todo := range todos {
 		todoMap, ok := todo.(map[string]interface{})
 		if !ok {
@@ -144,7 +267,7 @@ func DisplayTodos(todos []interface{}) {
 			statusIcon = Gray.Sprint("-")
 		}
 
-		Yellow.Printf("│     %s %s\n", statusIcon, content)
+		Yellow.Fprintf(w, "│     %s %s\n", statusIcon, content)
 		if i < len(todos)-1 {
 			// Continue without extra line between todos
 		}
@@ -152,7 +275,7 @@ func DisplayTodos(todos []interface{}) {
 }
 
 // DisplayTodosMinimal displays todos in minimal style
-func DisplayTodosMinimal(todos []interface{}) {
+func DisplayTodosMinimal(w io.Writer, todos []interface{}) {
 	for _, todo := range todos {
 		todoMap, ok := todo.(map[string]interface{})
 		if !ok {
@@ -174,12 +297,12 @@ func DisplayTodosMinimal(todos []interface{}) {
 			statusIcon = Gray.Sprint("-")
 		}
 
-		Yellow.Printf("      %s %s\n", statusIcon, content)
+		Yellow.Fprintf(w, "      %s %s\n", statusIcon, content)
 	}
 }
 
 // DisplayTodosPlain displays todos in plain style
-func DisplayTodosPlain(todos []interface{}) {
+func DisplayTodosPlain(w io.Writer, todos []interface{}) {
 	for _, todo := range todos {
 		todoMap, ok := todo.(map[string]interface{})
 		if !ok {
@@ -201,34 +324,6 @@ func DisplayTodosPlain(todos []interface{}) {
 			statusIcon = "[-]"
 		}
 
-		fmt.Printf("      %s %s\n", statusIcon, content)
-	}
-}
-
-// TruncateLongOutput shows first N + last N lines for long content
-func TruncateLongOutput(contentStr string, prefix string, printFn func(string)) {
-	lines := strings.Split(contentStr, "\n")
-	firstLines := parser.FirstLines
-	lastLines := parser.LastLines
-	totalLines := len(lines)
-
-	if totalLines <= firstLines+lastLines {
-		// Show all lines if content is short enough
-		for _, line := range lines {
-			printFn(prefix + line + "\n")
-		}
-	} else {
-		// Show first N lines
-		for i := 0; i < firstLines; i++ {
-			printFn(prefix + lines[i] + "\n")
-		}
-
-		// Show summary of middle content
-		printFn(fmt.Sprintf("%s... (%d more lines) ...\n", prefix, totalLines-firstLines-lastLines))
-
-		// Show last N lines
-		for i := totalLines - lastLines; i < totalLines; i++ {
-			printFn(prefix + lines[i] + "\n")
-		}
+		fmt.Fprintf(w, "      %s %s\n", statusIcon, content)
 	}
 }
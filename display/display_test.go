@@ -2,46 +2,14 @@ package display
 
 import (
 	"bytes"
-	"io"
-	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ariel-frischer/claude-clean/parser"
 	"github.com/fatih/color"
 )
 
-// captureStdout captures stdout during function execution
-func captureStdout(f func()) string {
-	oldStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
-	// Set color package output to our pipe
-	color.Output = w
-
-	// Capture output in a goroutine
-	var buf bytes.Buffer
-	done := make(chan struct{})
-	go func() {
-		io.Copy(&buf, r)
-		close(done)
-	}()
-
-	// Run the function
-	f()
-
-	// Close writer and restore stdout
-	w.Close()
-	os.Stdout = oldStdout
-	color.Output = oldStdout
-
-	// Wait for reader to finish
-	<-done
-
-	return buf.String()
-}
-
 // stripANSI removes ANSI escape codes from a string for easier testing
 func stripANSI(s string) string {
 	var result strings.Builder
@@ -187,14 +155,9 @@ func TestDisplayUsageInline(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			var buf bytes.Buffer
 			color.NoColor = true
-			oldOutput := color.Output
-			color.Output = &buf
-			defer func() {
-				color.Output = oldOutput
-			}()
 
 			c := color.New()
-			DisplayUsageInline(&tt.usage, c)
+			DisplayUsageInline(&buf, &tt.usage, c)
 
 			result := buf.String()
 			if result != tt.expected {
@@ -273,13 +236,13 @@ func TestDisplaySystemMessageDefault(t *testing.T) {
 				ShowLineNum: tt.showLineNum,
 			}
 
-			output := captureStdout(func() {
-				displaySystemMessage(tt.msg, tt.lineNum, cfg)
-			})
+			var buf bytes.Buffer
+			NewRenderer(&buf, cfg).System(tt.msg, tt.lineNum)
+			output := buf.String()
 
 			for _, expected := range tt.expectedIncludes {
 				if !strings.Contains(output, expected) {
-					t.Errorf("displaySystemMessage() output missing %q\nGot:\n%s", expected, output)
+					t.Errorf("System() output missing %q\nGot:\n%s", expected, output)
 				}
 			}
 		})
@@ -369,19 +332,19 @@ func TestDisplayAssistantMessageDefault(t *testing.T) {
 				ShowLineNum: tt.showLineNum,
 			}
 
-			output := captureStdout(func() {
-				displayAssistantMessage(tt.msg, tt.lineNum, cfg)
-			})
+			var buf bytes.Buffer
+			NewRenderer(&buf, cfg).Assistant(tt.msg, tt.lineNum)
+			output := buf.String()
 
 			for _, expected := range tt.expectedIncludes {
 				if !strings.Contains(output, expected) {
-					t.Errorf("displayAssistantMessage() output missing %q\nGot:\n%s", expected, output)
+					t.Errorf("Assistant() output missing %q\nGot:\n%s", expected, output)
 				}
 			}
 
 			for _, excluded := range tt.expectedExcludes {
 				if strings.Contains(output, excluded) {
-					t.Errorf("displayAssistantMessage() output should not contain %q\nGot:\n%s", excluded, output)
+					t.Errorf("Assistant() output should not contain %q\nGot:\n%s", excluded, output)
 				}
 			}
 		})
@@ -428,9 +391,9 @@ func TestDisplayTodos(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			output := captureStdout(func() {
-				DisplayTodos(tt.todos)
-			})
+			var buf bytes.Buffer
+			DisplayTodos(&buf, tt.todos)
+			output := buf.String()
 
 			for _, expected := range tt.expectedIncludes {
 				if !strings.Contains(output, expected) {
@@ -507,21 +470,742 @@ func TestDisplayResultMessageDefault(t *testing.T) {
 				ShowLineNum: tt.showLineNum,
 			}
 
-			output := captureStdout(func() {
-				displayResultMessage(tt.msg, tt.lineNum, cfg)
-			})
+			var buf bytes.Buffer
+			NewRenderer(&buf, cfg).Result(tt.msg, tt.lineNum)
+			output := buf.String()
 
 			for _, expected := range tt.expectedIncludes {
 				if !strings.Contains(output, expected) {
-					t.Errorf("displayResultMessage() output missing %q\nGot:\n%s", expected, output)
+					t.Errorf("Result() output missing %q\nGot:\n%s", expected, output)
 				}
 			}
 
 			for _, excluded := range tt.expectedExcludes {
 				if strings.Contains(output, excluded) {
-					t.Errorf("displayResultMessage() output should not contain %q\nGot:\n%s", excluded, output)
+					t.Errorf("Result() output should not contain %q\nGot:\n%s", excluded, output)
+				}
+			}
+		})
+	}
+}
+
+// TestNewRendererStyles tests that every style renders into its own writer
+func TestNewRendererStyles(t *testing.T) {
+	color.NoColor = true
+	defer func() { color.NoColor = false }()
+
+	msg := &parser.StreamMessage{
+		Type: "assistant",
+		Message: &parser.MessageContent{
+			Content: []parser.ContentBlock{
+				{Type: "text", Text: "Hello from the renderer"},
+				{Type: "tool_use", ID: "toolu_1", Name: "Bash", Input: map[string]interface{}{"command": "ls"}},
+			},
+		},
+	}
+
+	tests := []struct {
+		style            OutputStyle
+		expectedIncludes []string
+	}{
+		{style: StyleDefault, expectedIncludes: []string{"┌─ ASSISTANT", "Hello from the renderer", "TOOL: Bash"}},
+		{style: StyleCompact, expectedIncludes: []string{"AST", "Hello from the renderer", "TOOL", "command: \"ls\""}},
+		{style: StyleMinimal, expectedIncludes: []string{"ASSISTANT", "  Hello from the renderer", "TOOL: Bash"}},
+		{style: StylePlain, expectedIncludes: []string{"ASSISTANT", "  Hello from the renderer", "TOOL: Bash"}},
+		{style: StyleHTML, expectedIncludes: []string{"<!DOCTYPE html>", "Hello from the renderer", "TOOL: Bash", "</html>"}},
+		{style: StyleMarkdown, expectedIncludes: []string{"## Assistant", "Hello from the renderer", "### Tool: `Bash`", "```bash\nls\n```"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.style), func(t *testing.T) {
+			var buf bytes.Buffer
+			r := NewRenderer(&buf, &Config{Style: tt.style})
+			r.Start()
+			Render(r, msg, 1)
+			r.Finish()
+
+			output := buf.String()
+			for _, expected := range tt.expectedIncludes {
+				if !strings.Contains(output, expected) {
+					t.Errorf("%s output missing %q\nGot:\n%s", tt.style, expected, output)
+				}
+			}
+		})
+	}
+}
+
+// TestRenderUnknownMessage tests that only the default style reports unknown message types
+func TestRenderUnknownMessage(t *testing.T) {
+	color.NoColor = true
+	defer func() { color.NoColor = false }()
+
+	msg := &parser.StreamMessage{Type: "mystery"}
+
+	var buf bytes.Buffer
+	Render(NewRenderer(&buf, &Config{Style: StyleDefault}), msg, 7)
+	if !strings.Contains(buf.String(), "[Line 7] Unknown message type: mystery") {
+		t.Errorf("default style output = %q, want unknown type notice", buf.String())
+	}
+
+	buf.Reset()
+	Render(NewRenderer(&buf, &Config{Style: StylePlain}), msg, 7)
+	if buf.Len() != 0 {
+		t.Errorf("plain style output = %q, want empty", buf.String())
+	}
+}
+
+// TestThinkingBlocks tests that thinking blocks are hidden by default and shown with ShowThinking
+func TestThinkingBlocks(t *testing.T) {
+	color.NoColor = true
+	defer func() { color.NoColor = false }()
+
+	msg := &parser.StreamMessage{
+		Type: "assistant",
+		Message: &parser.MessageContent{
+			Content: []parser.ContentBlock{
+				{Type: "thinking", Thinking: "Let me consider\nthe options", Signature: "c2lnbmF0dXJl"},
+				{Type: "redacted_thinking", Data: "ZW5jcnlwdGVk"},
+				{Type: "text", Text: "Done thinking"},
+			},
+		},
+	}
+
+	tests := []struct {
+		style            OutputStyle
+		expectedIncludes []string
+	}{
+		{style: StyleDefault, expectedIncludes: []string{"┌─ THINKING", "│ Let me consider", "│ the options", "│ (redacted)"}},
+		{style: StyleCompact, expectedIncludes: []string{"THINK Let me consider the options", "THINK (redacted)"}},
+		{style: StyleMinimal, expectedIncludes: []string{"THINKING", "  Let me consider", "  the options", "  (redacted)"}},
+		{style: StylePlain, expectedIncludes: []string{"THINKING", "  Let me consider", "  the options", "  (redacted)"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.style), func(t *testing.T) {
+			var hidden bytes.Buffer
+			Render(NewRenderer(&hidden, &Config{Style: tt.style}), msg, 1)
+			if strings.Contains(hidden.String(), "THINK") || strings.Contains(hidden.String(), "Let me consider") {
+				t.Errorf("thinking shown without ShowThinking\nGot:\n%s", hidden.String())
+			}
+			if !strings.Contains(hidden.String(), "Done thinking") {
+				t.Errorf("text block missing\nGot:\n%s", hidden.String())
+			}
+
+			var shown bytes.Buffer
+			Render(NewRenderer(&shown, &Config{Style: tt.style, ShowThinking: true}), msg, 1)
+			for _, expected := range tt.expectedIncludes {
+				if !strings.Contains(shown.String(), expected) {
+					t.Errorf("output missing %q\nGot:\n%s", expected, shown.String())
 				}
 			}
 		})
 	}
 }
+
+// TestStreamEvents tests that partial text is rendered as it streams and not repeated by the final message
+func TestStreamEvents(t *testing.T) {
+	color.NoColor = true
+	defer func() { color.NoColor = false }()
+
+	event := func(ev parser.StreamEvent) *parser.StreamMessage {
+		return &parser.StreamMessage{Type: "stream_event", Event: &ev}
+	}
+	messages := []*parser.StreamMessage{
+		event(parser.StreamEvent{Type: "message_start", Message: &parser.MessageContent{ID: "msg_1"}}),
+		event(parser.StreamEvent{Type: "content_block_start", ContentBlock: &parser.ContentBlock{Type: "text"}}),
+		event(parser.StreamEvent{Type: "content_block_delta", Delta: &parser.Delta{Type: "text_delta", Text: "Streaming "}}),
+		event(parser.StreamEvent{Type: "content_block_delta", Delta: &parser.Delta{Type: "text_delta", Text: "text\nsecond line"}}),
+		event(parser.StreamEvent{Type: "content_block_stop"}),
+		{
+			Type: "assistant",
+			Message: &parser.MessageContent{
+				ID: "msg_1",
+				Content: []parser.ContentBlock{
+					{Type: "text", Text: "Streaming text\nsecond line"},
+					{Type: "tool_use", ID: "toolu_1", Name: "Bash", Input: map[string]interface{}{"command": "ls"}},
+				},
+			},
+		},
+		event(parser.StreamEvent{Type: "message_stop"}),
+	}
+
+	tests := []struct {
+		style    OutputStyle
+		expected string
+	}{
+		{style: StyleDefault, expected: "┌─ ASSISTANT\n│ Streaming text\n│ second line\n└─\n"},
+		{style: StyleCompact, expected: "AST Streaming text second line\n"},
+		{style: StyleMinimal, expected: "ASSISTANT\n  Streaming text\n  second line\n\n"},
+		{style: StylePlain, expected: "ASSISTANT\n  Streaming text\n  second line\n\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.style), func(t *testing.T) {
+			var buf bytes.Buffer
+			r := NewRenderer(&buf, &Config{Style: tt.style})
+			for i, msg := range messages {
+				Render(r, msg, i+1)
+			}
+
+			output := buf.String()
+			if !strings.HasPrefix(output, tt.expected) {
+				t.Errorf("streamed output =\n%q\nwant prefix:\n%q", output, tt.expected)
+			}
+			if strings.Count(output, "second line") != 1 {
+				t.Errorf("streamed text rendered more than once\nGot:\n%s", output)
+			}
+			if !strings.Contains(output, "Bash") {
+				t.Errorf("tool call from final message missing\nGot:\n%s", output)
+			}
+			if strings.Contains(output, "Unknown message type") {
+				t.Errorf("stream_event reported as unknown\nGot:\n%s", output)
+			}
+		})
+	}
+}
+
+// TestStreamedTextShowsAsItArrives tests that streamed text shows up as each
+// delta arrives, before its line ends, with and without markdown rendering
+func TestStreamedTextShowsAsItArrives(t *testing.T) {
+	event := func(ev parser.StreamEvent) *parser.StreamMessage {
+		return &parser.StreamMessage{Type: "stream_event", Event: &ev}
+	}
+	delta := func(text string) *parser.StreamMessage {
+		return event(parser.StreamEvent{Type: "content_block_delta", Delta: &parser.Delta{Type: "text_delta", Text: text}})
+	}
+	deltas := []string{"Hel", "lo", " there, **streaming**", " text\nnext"}
+
+	tests := []struct {
+		name     string
+		cfg      Config
+		expected []string // end of the output after each delta
+	}{
+		{
+			"default",
+			Config{Style: StyleDefault, Width: 20},
+			[]string{"│ Hel", "│ Hello", "│ Hello there,\n│ **streaming**", "│ Hello there,\n│ **streaming** text\n│ next"},
+		},
+		{
+			"default without markdown",
+			Config{Style: StyleDefault, Width: 20, NoMarkdown: true},
+			[]string{"│ Hel", "│ Hello", "│ Hello there,\n│ **streaming**", "│ Hello there,\n│ **streaming** text\n│ next"},
+		},
+		{
+			"minimal",
+			Config{Style: StyleMinimal, Width: 20},
+			[]string{"  Hel", "  Hello", "  Hello there, **streaming**", "  Hello there, **streaming** text\n  next"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			r := NewRenderer(&buf, &tt.cfg)
+			Render(r, event(parser.StreamEvent{Type: "message_start", Message: &parser.MessageContent{ID: "msg_1"}}), 1)
+			Render(r, event(parser.StreamEvent{Type: "content_block_start", ContentBlock: &parser.ContentBlock{Type: "text"}}), 2)
+			for i, d := range deltas {
+				Render(r, delta(d), i+3)
+				if got := stripANSI(buf.String()); !strings.HasSuffix(got, tt.expected[i]) {
+					t.Errorf("after %q the output is\n%q\nwant it to end with\n%q", d, got, tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+// TestToolResultBlocks tests that tool results given as a list of content
+// blocks show their text in every style
+func TestToolResultBlocks(t *testing.T) {
+	messages := []*parser.StreamMessage{
+		{Type: "assistant", Message: &parser.MessageContent{Content: []parser.ContentBlock{
+			{Type: "tool_use", ID: "t1", Name: "Task", Input: map[string]interface{}{"description": "look"}},
+			{Type: "tool_use", ID: "t2", Name: "Bash", Input: map[string]interface{}{"command": "make"}},
+		}}},
+		{Type: "user", Message: &parser.MessageContent{Content: []parser.ContentBlock{
+			{Type: "tool_result", ToolUseID: "t1", Content: []interface{}{
+				map[string]interface{}{"type": "text", "text": "found the tests"},
+			}},
+			{Type: "tool_result", ToolUseID: "t2", IsError: true, Content: []interface{}{
+				map[string]interface{}{"type": "text", "text": "build broke"},
+			}},
+		}}},
+	}
+
+	for _, style := range []OutputStyle{StyleDefault, StyleCompact, StyleMinimal, StylePlain, StyleHTML, StyleMarkdown} {
+		t.Run(string(style), func(t *testing.T) {
+			var buf bytes.Buffer
+			r := NewRenderer(&buf, &Config{Style: style})
+			for i, msg := range messages {
+				Render(r, msg, i+1)
+			}
+			output := stripANSI(buf.String())
+			if strings.Contains(output, "map[") {
+				t.Errorf("content blocks shown as Go values\nGot:\n%s", output)
+			}
+			for _, want := range []string{"found the tests", "build broke"} {
+				if !strings.Contains(output, want) {
+					t.Errorf("output missing %q\nGot:\n%s", want, output)
+				}
+			}
+		})
+	}
+}
+
+// TestSubagentHierarchy tests that subagent messages are indented under the Task call that spawned them
+func TestSubagentHierarchy(t *testing.T) {
+	color.NoColor = true
+	defer func() { color.NoColor = false }()
+
+	assistant := func(parent, id string, blocks ...parser.ContentBlock) *parser.StreamMessage {
+		return &parser.StreamMessage{
+			Type:            "assistant",
+			ParentToolUseID: parent,
+			Message:         &parser.MessageContent{ID: id, Content: blocks},
+		}
+	}
+	user := func(parent string, blocks ...parser.ContentBlock) *parser.StreamMessage {
+		return &parser.StreamMessage{
+			Type:            "user",
+			ParentToolUseID: parent,
+			Message:         &parser.MessageContent{Content: blocks},
+		}
+	}
+	task := func(id, agentType, description string) parser.ContentBlock {
+		return parser.ContentBlock{Type: "tool_use", ID: id, Name: "Task", Input: map[string]interface{}{
+			"subagent_type": agentType,
+			"description":   description,
+		}}
+	}
+	bash := func(id, command string) parser.ContentBlock {
+		return parser.ContentBlock{Type: "tool_use", ID: id, Name: "Bash", Input: map[string]interface{}{"command": command}}
+	}
+	result := func(id, content string, isError bool) parser.ContentBlock {
+		return parser.ContentBlock{Type: "tool_result", ToolUseID: id, Content: content, IsError: isError}
+	}
+
+	messages := []*parser.StreamMessage{
+		assistant("", "msg_1", task("toolu_task", "Explore", "Look around")),
+		assistant("toolu_task", "msg_2", bash("toolu_ls", "ls")),
+		user("toolu_task", result("toolu_ls", "ls: permission denied", true)),
+		assistant("toolu_task", "msg_3", task("toolu_nested", "codebase-locator", "Find tests")),
+		assistant("toolu_nested", "msg_4", bash("toolu_find", "find . -name '*_test.go'")),
+		user("toolu_nested", result("toolu_find", "main_test.go", false)),
+		user("toolu_task", result("toolu_nested", "found main_test.go", false)),
+		user("", result("toolu_task", "explored", false)),
+	}
+
+	var buf bytes.Buffer
+	r := NewRenderer(&buf, &Config{Style: StylePlain})
+	for i, msg := range messages {
+		Render(r, msg, i+1)
+	}
+	output := buf.String()
+
+	expectedLines := []string{
+		"SUBAGENT: Explore - Look around",
+		"    TOOL: Bash",
+		"        $ ls",
+		"    TOOL RESULT ERROR: Bash (ls)",
+		"    SUBAGENT: codebase-locator - Find tests",
+		"        TOOL: Bash",
+		"        TOOL RESULT: Bash",
+		"    SUBAGENT DONE: codebase-locator (1 turn, 1 tool call, 0 errors)",
+		"    TOOL RESULT: Task (Find tests)",
+		"SUBAGENT DONE: Explore (2 turns, 2 tool calls, 1 error)",
+		"TOOL RESULT: Task (Look around)",
+	}
+
+	lines := strings.Split(output, "\n")
+	next := 0
+	for _, line := range lines {
+		if next < len(expectedLines) && strings.HasPrefix(line, expectedLines[next]) {
+			next++
+		}
+	}
+	if next < len(expectedLines) {
+		t.Errorf("output missing line %q (in order)\nGot:\n%s", expectedLines[next], output)
+	}
+}
+
+// TestIndentWriter tests that indentation is applied per line and skips leading escape codes
+func TestIndentWriter(t *testing.T) {
+	var buf bytes.Buffer
+	iw := &indentWriter{w: &buf, prefix: "  ", lineStart: true}
+
+	iw.Write([]byte("one\ntw"))
+	iw.Write([]byte("o\n\x1b[0m"))
+	iw.Write([]byte("three\n"))
+
+	expected := "  one\n  two\n\x1b[0m  three\n"
+	if buf.String() != expected {
+		t.Errorf("indentWriter output = %q, want %q", buf.String(), expected)
+	}
+}
+
+// TestToolResultPairing tests that results are labeled with their call and latency, and unanswered calls are reported
+func TestToolResultPairing(t *testing.T) {
+	color.NoColor = true
+	defer func() { color.NoColor = false }()
+
+	call := &parser.StreamMessage{
+		Type: "assistant",
+		Message: &parser.MessageContent{
+			Content: []parser.ContentBlock{
+				{Type: "tool_use", ID: "toolu_1", Name: "Bash", Input: map[string]interface{}{"command": "go test ./..."}},
+				{Type: "tool_use", ID: "toolu_2", Name: "Read", Input: map[string]interface{}{"file_path": "/tmp/main.go"}},
+			},
+		},
+	}
+	result := &parser.StreamMessage{
+		Type: "user",
+		Message: &parser.MessageContent{
+			Content: []parser.ContentBlock{
+				{Type: "tool_result", ToolUseID: "toolu_1", Content: "ok", IsError: false},
+			},
+		},
+	}
+
+	tests := []struct {
+		style            OutputStyle
+		expectedIncludes []string
+	}{
+		{style: StyleDefault, expectedIncludes: []string{
+			"TOOL RESULT: Bash (go test ./...) [1.5s]",
+			"WARNING: 1 tool call never received a result",
+			"│ Read (/tmp/main.go) (line 3)",
+		}},
+		{style: StyleCompact, expectedIncludes: []string{
+			"RES Bash(go test ./...) 1.5s ok",
+			"WARN 1 tool call without result: Read L3",
+		}},
+		{style: StyleMinimal, expectedIncludes: []string{
+			"TOOL RESULT: Bash (go test ./...) [1.5s]",
+			"  Read (/tmp/main.go) (line 3)",
+		}},
+		{style: StylePlain, expectedIncludes: []string{
+			"TOOL RESULT: Bash (go test ./...) [1.5s]",
+			"WARNING: 1 tool call never received a result",
+			"  Read (/tmp/main.go) (line 3)",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.style), func(t *testing.T) {
+			var buf bytes.Buffer
+			r := NewRenderer(&buf, &Config{Style: tt.style})
+
+			now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+			r.(*base).clock = func() time.Time { return now }
+
+			r.Start()
+			Render(r, call, 3)
+			now = now.Add(1500 * time.Millisecond)
+			Render(r, result, 4)
+			r.Finish()
+
+			output := buf.String()
+			for _, expected := range tt.expectedIncludes {
+				if !strings.Contains(output, expected) {
+					t.Errorf("output missing %q\nGot:\n%s", expected, output)
+				}
+			}
+		})
+	}
+}
+
+// TestFormatDuration tests compact duration formatting
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d        time.Duration
+		expected string
+	}{
+		{d: 850 * time.Millisecond, expected: "850ms"},
+		{d: 12300 * time.Millisecond, expected: "12.3s"},
+		{d: 125 * time.Second, expected: "2m5s"},
+	}
+
+	for _, tt := range tests {
+		if got := formatDuration(tt.d); got != tt.expected {
+			t.Errorf("formatDuration(%v) = %q, want %q", tt.d, got, tt.expected)
+		}
+	}
+}
+
+// TestHTMLStyle tests that the html style writes a complete, escaped document
+func TestHTMLStyle(t *testing.T) {
+	messages := []*parser.StreamMessage{
+		{Type: "system", Subtype: "init", Model: "claude-sonnet-4-5", CWD: "/repo"},
+		{Type: "assistant", Message: &parser.MessageContent{ID: "msg_1", Content: []parser.ContentBlock{
+			{Type: "text", Text: "Checking <main.go> & friends"},
+			{Type: "tool_use", ID: "toolu_task", Name: "Task", Input: map[string]interface{}{
+				"subagent_type": "Explore", "description": "Look around",
+			}},
+		}}},
+		{Type: "assistant", ParentToolUseID: "toolu_task", Message: &parser.MessageContent{ID: "msg_2", Content: []parser.ContentBlock{
+			{Type: "tool_use", ID: "toolu_todo", Name: "TodoWrite", Input: map[string]interface{}{
+				"todos": []interface{}{
+					map[string]interface{}{"content": "Read files", "status": "completed"},
+					map[string]interface{}{"content": "Write tests", "status": "pending"},
+				},
+			}},
+		}}},
+		{Type: "user", ParentToolUseID: "toolu_task", Message: &parser.MessageContent{Content: []parser.ContentBlock{
+			{Type: "tool_result", ToolUseID: "toolu_todo", Content: "<script>alert(1)</script>"},
+		}}},
+		{Type: "user", Message: &parser.MessageContent{Content: []parser.ContentBlock{
+			{Type: "tool_result", ToolUseID: "toolu_task", Content: "done"},
+		}}},
+		{Type: "result", Subtype: "success", NumTurns: 2, TotalCostUSD: 0.0123, Result: "All done",
+			Usage: &parser.Usage{InputTokens: 100, OutputTokens: 50}},
+	}
+
+	var buf bytes.Buffer
+	r := NewRenderer(&buf, &Config{Style: StyleHTML})
+	r.Start()
+	for i, msg := range messages {
+		Render(r, msg, i+1)
+	}
+	r.Finish()
+	output := buf.String()
+
+	expected := []string{
+		"<!DOCTYPE html>",
+		`<div class="card system" id="L1">`,
+		"Checking &lt;main.go&gt; &amp; friends",
+		`<div class="card agent" id="L3">`,
+		`<div class="subagent">`,
+		`<li class="completed">✓ Read files</li>`,
+		`<li class="pending">○ Write tests</li>`,
+		"&lt;script&gt;alert(1)&lt;/script&gt;",
+		"SUBAGENT DONE: Explore",
+		"<tr><th>Cost</th><td>$0.0123</td></tr>",
+		"<tr><th>Tokens</th><td>in=100 out=50</td></tr>",
+		`href="#L6"`,
+		"</html>",
+	}
+	for _, want := range expected {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q", want)
+		}
+	}
+
+	if strings.Contains(output, "<script>alert") {
+		t.Error("tool result content was not escaped")
+	}
+	if strings.Contains(output, "\x1b[") {
+		t.Error("output contains ANSI escape codes")
+	}
+	if open, closed := strings.Count(output, "<div"), strings.Count(output, "</div>"); open != closed {
+		t.Errorf("%d <div> elements but %d </div>", open, closed)
+	}
+	if strings.Count(output, `id="L2"`) != 1 {
+		t.Error("line 2 should have exactly one anchor")
+	}
+}
+
+func TestMarkdownStyle(t *testing.T) {
+	messages := []*parser.StreamMessage{
+		{Type: "assistant", Message: &parser.MessageContent{ID: "msg_1", Content: []parser.ContentBlock{
+			{Type: "text", Text: "Writing the **fix**"},
+			{Type: "tool_use", ID: "toolu_write", Name: "Write", Input: map[string]interface{}{
+				"file_path": "/repo/fix.py", "content": "print('```')",
+			}},
+			{Type: "tool_use", ID: "toolu_task", Name: "Task", Input: map[string]interface{}{
+				"subagent_type": "Explore", "description": "Look around",
+			}},
+		}}},
+		{Type: "user", Message: &parser.MessageContent{Content: []parser.ContentBlock{
+			{Type: "tool_result", ToolUseID: "toolu_write", Content: "File created"},
+		}}},
+		{Type: "assistant", ParentToolUseID: "toolu_task", Message: &parser.MessageContent{ID: "msg_2", Content: []parser.ContentBlock{
+			{Type: "tool_use", ID: "toolu_todo", Name: "TodoWrite", Input: map[string]interface{}{
+				"todos": []interface{}{
+					map[string]interface{}{"content": "Read files", "status": "completed"},
+					map[string]interface{}{"content": "Write tests", "status": "pending"},
+				},
+			}},
+		}}},
+		{Type: "user", ParentToolUseID: "toolu_task", Message: &parser.MessageContent{Content: []parser.ContentBlock{
+			{Type: "tool_result", ToolUseID: "toolu_todo", Content: "Permission denied", IsError: true},
+		}}},
+		{Type: "user", Message: &parser.MessageContent{Content: []parser.ContentBlock{
+			{Type: "tool_result", ToolUseID: "toolu_task", Content: "done"},
+		}}},
+		{Type: "result", Subtype: "success", NumTurns: 2, TotalCostUSD: 0.0123, Result: "All done",
+			Usage: &parser.Usage{InputTokens: 100, OutputTokens: 50}},
+	}
+
+	var buf bytes.Buffer
+	r := NewRenderer(&buf, &Config{Style: StyleMarkdown})
+	r.Start()
+	for i, msg := range messages {
+		Render(r, msg, i+1)
+	}
+	r.Finish()
+	output := buf.String()
+
+	expected := []string{
+		"## Assistant\n\nWriting the **fix**\n",
+		"### Tool: `Write`\n\n`/repo/fix.py` (1 line)\n\n````py\nprint('```')\n````\n",
+		"<details>\n<summary>Result: Write (/repo/fix.py)",
+		"### Subagent: `Explore` - Look around",
+		"> ### Tool: `TodoWrite`\n",
+		"> - [x] Read files\n> - [ ] Write tests\n",
+		"> <details open>\n> <summary>Error: TodoWrite (2 todos)",
+		"*Subagent done: `Explore`",
+		"## Result: success",
+		"| Turns | 2 |",
+		"| Cost | $0.0123 |",
+		"All done",
+	}
+	for _, want := range expected {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q\nGot:\n%s", want, output)
+		}
+	}
+
+	if strings.Contains(output, "\x1b[") {
+		t.Error("output contains ANSI escape codes")
+	}
+	if open, closed := strings.Count(output, "<details"), strings.Count(output, "</details>"); open != closed {
+		t.Errorf("%d <details> elements but %d </details>", open, closed)
+	}
+}
+
+func TestSessionTranscript(t *testing.T) {
+	start := time.Date(2025, 10, 20, 14, 0, 0, 0, time.UTC)
+	messages := []*parser.StreamMessage{
+		{Type: "system", Subtype: "summary", Content: "Fix the build"},
+		{Type: "user", IsMeta: true, Timestamp: start, Message: &parser.MessageContent{Content: []parser.ContentBlock{
+			{Type: "text", Text: "Caveat: generated by a local command"},
+		}}},
+		{Type: "user", Timestamp: start.Add(2 * time.Second), Message: &parser.MessageContent{Content: []parser.ContentBlock{
+			{Type: "text", Text: "Why does make fail?<system-reminder>be brief</system-reminder>"},
+		}}},
+		{Type: "assistant", Timestamp: start.Add(5 * time.Second), Message: &parser.MessageContent{Content: []parser.ContentBlock{
+			{Type: "tool_use", ID: "t1", Name: "Bash", Input: map[string]interface{}{"command": "make"}},
+		}}},
+		{Type: "user", Timestamp: start.Add(95 * time.Second), Message: &parser.MessageContent{Content: []parser.ContentBlock{
+			{Type: "tool_result", ToolUseID: "t1", Content: "ok"},
+		}}},
+	}
+
+	var buf bytes.Buffer
+	r := NewRenderer(&buf, &Config{Style: StylePlain, ShowTimestamps: true})
+	r.Start()
+	for i, msg := range messages {
+		Render(r, msg, i+1)
+	}
+	r.Finish()
+	output := buf.String()
+
+	for _, want := range []string{
+		"SYSTEM [summary] +0.0s\n  Fix the build\n",
+		"USER +2.0s Δ2.0s\n  Why does make fail?\n",
+		"TOOL: Bash +5.0s Δ3.0s\n",
+		"TOOL RESULT: Bash (make) [1m30s] +1m35s Δ1m30s\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q\nGot:\n%s", want, output)
+		}
+	}
+	for _, hidden := range []string{"Caveat", "be brief"} {
+		if strings.Contains(output, hidden) {
+			t.Errorf("output contains %q\nGot:\n%s", hidden, output)
+		}
+	}
+}
+
+func TestReceivedTimes(t *testing.T) {
+	start := time.Date(2025, 10, 20, 14, 0, 0, 0, time.UTC)
+	messages := []*parser.StreamMessage{
+		{Type: "system", Subtype: "init", Model: "claude-sonnet-4-5", ReceivedAt: start},
+		{Type: "assistant", ReceivedAt: start.Add(1500 * time.Millisecond), Message: &parser.MessageContent{Content: []parser.ContentBlock{
+			{Type: "text", Text: "Hello"},
+		}}},
+		{Type: "result", Subtype: "success", Result: "Done", ReceivedAt: start.Add(1800 * time.Millisecond)},
+	}
+
+	var buf bytes.Buffer
+	r := NewRenderer(&buf, &Config{Style: StyleCompact, ShowTimestamps: true})
+	r.Start()
+	for i, msg := range messages {
+		Render(r, msg, i+1)
+	}
+	r.Finish()
+	output := buf.String()
+
+	for _, want := range []string{" +0.0s", " +1.5s Δ1.5s", " +1.8s Δ300ms"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q\nGot:\n%s", want, output)
+		}
+	}
+}
+
+func TestMarkdownEscaping(t *testing.T) {
+	messages := []*parser.StreamMessage{
+		{Type: "assistant", Message: &parser.MessageContent{Content: []parser.ContentBlock{
+			{Type: "tool_use", ID: "t1", Name: "mcp__github__create_pr", Input: map[string]interface{}{"title": "Fix"}},
+			{Type: "tool_use", ID: "t2", Name: "Bash", Input: map[string]interface{}{"command": "ls", "description": "List *all* files_here"}},
+		}}},
+		{Type: "result", Subtype: "success"},
+	}
+
+	var buf bytes.Buffer
+	r := NewRenderer(&buf, &Config{Style: StyleMarkdown})
+	r.Start()
+	for i, msg := range messages {
+		Render(r, msg, i+1)
+	}
+	r.Finish()
+	output := buf.String()
+
+	for _, want := range []string{
+		"### Tool: `mcp__github__create_pr`\n",
+		"*List \\*all\\* files\\_here*\n",
+		"## Result: success\n\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q\nGot:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "| Metric | Value |") {
+		t.Errorf("output has a result table without rows\nGot:\n%s", output)
+	}
+}
+
+func TestSidechainTranscript(t *testing.T) {
+	text := func(s string) *parser.MessageContent {
+		return &parser.MessageContent{Content: []parser.ContentBlock{{Type: "text", Text: s}}}
+	}
+	messages := []*parser.StreamMessage{
+		{Type: "assistant", UUID: "m1", Message: &parser.MessageContent{Content: []parser.ContentBlock{
+			{Type: "tool_use", ID: "t1", Name: "Task", Input: map[string]interface{}{"subagent_type": "Explore", "prompt": "Find the parser"}},
+		}}},
+		{Type: "user", UUID: "s1", IsSidechain: true, Message: text("Find the parser")},
+		{Type: "assistant", UUID: "s2", ParentUUID: "s1", IsSidechain: true, Message: text("It is in parser/decoder.go")},
+		{Type: "user", UUID: "m2", ParentUUID: "m1", Message: &parser.MessageContent{Content: []parser.ContentBlock{
+			{Type: "tool_result", ToolUseID: "t1", Content: "parser/decoder.go"},
+		}}},
+		{Type: "assistant", UUID: "m3", ParentUUID: "m2", Message: text("Found it")},
+	}
+
+	var buf bytes.Buffer
+	r := NewRenderer(&buf, &Config{Style: StylePlain})
+	r.Start()
+	for i, msg := range messages {
+		Render(r, msg, i+1)
+	}
+	r.Finish()
+	output := buf.String()
+
+	for _, want := range []string{
+		"\n    USER\n      Find the parser\n",
+		"\n    ASSISTANT\n      It is in parser/decoder.go\n",
+		"\nASSISTANT\n  Found it\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q\nGot:\n%s", want, output)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package display
+
+import (
+	"bytes"
+	"io"
+	"path"
+	"regexp"
+	"slices"
+
+	"github.com/ariel-frischer/claude-clean/parser"
+)
+
+// Redaction replaces the matches of Pattern in the output with Replacement
+type Redaction struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// MatchTool reports whether a tool name is one of names, which may be glob
+// patterns such as "mcp__*"
+func MatchTool(names []string, name string) bool {
+	for _, pattern := range names {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// MessageTypes are the message types that can be shown or hidden.
+// Partial messages count as assistant messages.
+var MessageTypes = []string{"system", "assistant", "user", "result"}
+
+// showMessage reports whether the filters let a message through as a whole,
+// by its type and the nesting of the subagent that sent it
+func (b *base) showMessage(msg *parser.StreamMessage) bool {
+	typ := msg.Type
+	if typ == "stream_event" {
+		typ = "assistant"
+	}
+	switch {
+	case len(b.cfg.OnlyTypes) > 0 && !slices.Contains(b.cfg.OnlyTypes, typ),
+		slices.Contains(b.cfg.HideTypes, typ),
+		b.cfg.ErrorsOnly && typ != "user" && typ != "result":
+		return false
+	}
+	if b.cfg.HideDepth > 0 {
+		if a := b.agentOf(msg); a != nil && a.Depth >= b.cfg.HideDepth {
+			return false
+		}
+	}
+	return true
+}
+
+// hiddenTool reports whether the calls of a tool are hidden by
+// Config.HideTools or OnlyTools
+func (b *base) hiddenTool(name string) bool {
+	if len(b.cfg.OnlyTools) > 0 && !MatchTool(b.cfg.OnlyTools, name) {
+		return true
+	}
+	return MatchTool(b.cfg.HideTools, name)
+}
+
+// hideTools returns msg without the hidden tool calls, remembering their IDs
+// so that their results are hidden too. It returns nil if nothing is left to
+// show.
+func (b *base) hideTools(msg *parser.StreamMessage) *parser.StreamMessage {
+	if len(b.cfg.HideTools) == 0 && len(b.cfg.OnlyTools) == 0 {
+		return msg
+	}
+	return withoutBlocks(msg, func(block *parser.ContentBlock) bool {
+		if block.Type != "tool_use" || !b.hiddenTool(block.Name) {
+			return false
+		}
+		if b.hidden == nil {
+			b.hidden = make(map[string]bool)
+		}
+		b.hidden[block.ID] = true
+		return true
+	})
+}
+
+// hideResults returns the part of a user message that the filters show: the
+// results of calls that are not hidden, only the errors with
+// Config.ErrorsOnly, and nothing if the message is hidden as a whole. It
+// returns nil if nothing is left to show. The calls of hidden results are no
+// longer pending.
+func (b *base) hideResults(msg *parser.StreamMessage) *parser.StreamMessage {
+	show := b.showMessage(msg)
+	if show && len(b.hidden) == 0 && !b.cfg.ErrorsOnly {
+		return msg
+	}
+	return withoutBlocks(msg, func(block *parser.ContentBlock) bool {
+		if block.Type != "tool_result" {
+			return !show || b.cfg.ErrorsOnly
+		}
+		if show && !b.hidden[block.ToolUseID] && (block.IsError || !b.cfg.ErrorsOnly) {
+			return false
+		}
+		delete(b.hidden, block.ToolUseID)
+		b.takeCall(block.ToolUseID)
+		return true
+	})
+}
+
+// withoutBlocks returns a copy of msg without the content blocks for which
+// drop returns true, msg itself if none are dropped, or nil if all are
+func withoutBlocks(msg *parser.StreamMessage, drop func(block *parser.ContentBlock) bool) *parser.StreamMessage {
+	var content []parser.ContentBlock
+	for i := range msg.Message.Content {
+		if !drop(&msg.Message.Content[i]) {
+			content = append(content, msg.Message.Content[i])
+		}
+	}
+	switch len(content) {
+	case len(msg.Message.Content):
+		return msg
+	case 0:
+		return nil
+	}
+
+	m, mc := *msg, *msg.Message
+	mc.Content = content
+	m.Message = &mc
+	return &m
+}
+
+// flusher is implemented by writers that hold back an incomplete line
+type flusher interface {
+	Flush() error
+}
+
+// syncer is implemented by writers that can write an incomplete line early,
+// for streamed text. redactWriter is not one: a secret may be split across
+// writes, so its lines are always held until they are complete.
+type syncer interface {
+	Sync() error
+}
+
+// redactWriter applies redactions to each line written through it
+type redactWriter struct {
+	w      io.Writer
+	redact []Redaction
+	line   []byte // the current line, without its newline
+}
+
+func (rw *redactWriter) Write(p []byte) (int, error) {
+	total := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			rw.line = append(rw.line, p...)
+			break
+		}
+		rw.line = append(rw.line, p[:i+1]...)
+		if _, err := rw.w.Write(rw.apply(rw.line)); err != nil {
+			return 0, err
+		}
+		rw.line = rw.line[:0]
+		p = p[i+1:]
+	}
+	return total, nil
+}
+
+// Flush writes the current line, if any, and flushes the underlying writer
+func (rw *redactWriter) Flush() error {
+	if len(rw.line) > 0 {
+		if _, err := rw.w.Write(rw.apply(rw.line)); err != nil {
+			return err
+		}
+		rw.line = rw.line[:0]
+	}
+	if f, ok := rw.w.(flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+func (rw *redactWriter) apply(line []byte) []byte {
+	for _, r := range rw.redact {
+		line = r.Pattern.ReplaceAll(line, []byte(r.Replacement))
+	}
+	return line
+}
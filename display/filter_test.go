@@ -0,0 +1,227 @@
+package display
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ariel-frischer/claude-clean/parser"
+)
+
+func TestHideTools(t *testing.T) {
+	messages := []*parser.StreamMessage{
+		{Type: "assistant", Message: &parser.MessageContent{Content: []parser.ContentBlock{
+			{Type: "text", Text: "Checking"},
+			{Type: "tool_use", ID: "t1", Name: "mcp__github__get_issue", Input: map[string]interface{}{"number": 1.0}},
+			{Type: "tool_use", ID: "t2", Name: "Bash", Input: map[string]interface{}{"command": "ls"}},
+		}}},
+		{Type: "assistant", Message: &parser.MessageContent{Content: []parser.ContentBlock{
+			{Type: "tool_use", ID: "t3", Name: "TodoWrite", Input: map[string]interface{}{}},
+		}}},
+		{Type: "user", Message: &parser.MessageContent{Content: []parser.ContentBlock{
+			{Type: "tool_result", ToolUseID: "t1", Content: "issue body"},
+			{Type: "tool_result", ToolUseID: "t2", Content: "main.go"},
+		}}},
+		{Type: "user", Message: &parser.MessageContent{Content: []parser.ContentBlock{
+			{Type: "tool_result", ToolUseID: "t3", Content: "todos updated"},
+		}}},
+	}
+
+	var buf bytes.Buffer
+	r := NewRenderer(&buf, &Config{Style: StylePlain, HideTools: []string{"mcp__*", "TodoWrite"}})
+	r.Start()
+	for i, msg := range messages {
+		Render(r, msg, i+1)
+	}
+	r.Finish()
+	output := buf.String()
+
+	for _, want := range []string{"Checking", "TOOL: Bash", "main.go"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q\nGot:\n%s", want, output)
+		}
+	}
+	for _, hidden := range []string{"mcp__github", "issue body", "TodoWrite", "todos updated", "NO RESULT"} {
+		if strings.Contains(output, hidden) {
+			t.Errorf("output contains hidden %q\nGot:\n%s", hidden, output)
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	msg := &parser.StreamMessage{Type: "assistant", Message: &parser.MessageContent{Content: []parser.ContentBlock{
+		{Type: "text", Text: "Using key sk-abc123 for user alice@example.com"},
+	}}}
+	redact := []Redaction{
+		{Pattern: regexp.MustCompile(`sk-[a-z0-9]+`), Replacement: "[REDACTED]"},
+		{Pattern: regexp.MustCompile(`(\w+)@example\.com`), Replacement: "$1@…"},
+	}
+
+	for _, style := range []OutputStyle{StyleDefault, StylePlain, StyleCompact, StyleMarkdown, StyleHTML} {
+		var buf bytes.Buffer
+		r := NewRenderer(&buf, &Config{Style: style, Redact: redact})
+		r.Start()
+		Render(r, msg, 1)
+		r.Finish()
+		output := buf.String()
+
+		if strings.Contains(output, "sk-abc123") || strings.Contains(output, "alice@example.com") {
+			t.Errorf("%s: secrets not redacted:\n%s", style, output)
+		}
+		if !strings.Contains(output, "[REDACTED]") || !strings.Contains(output, "alice@…") {
+			t.Errorf("%s: replacements missing:\n%s", style, output)
+		}
+	}
+}
+
+func TestTruncationLimits(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 10; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	messages := []*parser.StreamMessage{
+		{Type: "assistant", Message: &parser.MessageContent{Content: []parser.ContentBlock{
+			{Type: "tool_use", ID: "t1", Name: "Custom", Input: map[string]interface{}{"text": strings.Repeat("x", 40) + "END"}},
+		}}},
+		{Type: "user", Message: &parser.MessageContent{Content: []parser.ContentBlock{
+			{Type: "tool_result", ToolUseID: "t1", Content: strings.Join(lines, "\n")},
+		}}},
+	}
+
+	var buf bytes.Buffer
+	r := NewRenderer(&buf, &Config{Style: StylePlain, HeadLines: 2, TailLines: 1, InputChars: 30})
+	for i, msg := range messages {
+		Render(r, msg, i+1)
+	}
+	output := buf.String()
+
+	for _, want := range []string{
+		"text: " + strings.Repeat("x", 20) + " ... (13 chars omitted) ... xxxxxxxEND\n",
+		"  line 1\n  line 2\n  ... (7 more lines) ...\n  line 10\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q\nGot:\n%s", want, output)
+		}
+	}
+}
+
+// TestInputTruncationEveryStyle tests that every style shortens long tool
+// inputs, such as commands and written files, with the same limits
+func TestInputTruncationEveryStyle(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 10; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	text := strings.Join(lines, "\n")
+	messages := []*parser.StreamMessage{
+		{Type: "assistant", Message: &parser.MessageContent{Content: []parser.ContentBlock{
+			{Type: "tool_use", ID: "t1", Name: "Bash", Input: map[string]interface{}{"command": text}},
+			{Type: "tool_use", ID: "t2", Name: "Write", Input: map[string]interface{}{"file_path": "notes.txt", "content": text}},
+		}}},
+	}
+
+	for _, style := range []OutputStyle{StyleDefault, StyleCompact, StyleMinimal, StylePlain, StyleHTML, StyleMarkdown} {
+		t.Run(string(style), func(t *testing.T) {
+			var buf bytes.Buffer
+			r := NewRenderer(&buf, &Config{Style: style, HeadLines: 2, TailLines: 1})
+			for i, msg := range messages {
+				Render(r, msg, i+1)
+			}
+			output := stripANSI(buf.String())
+			// Summaries such as the compact style's keep only the start
+			if strings.Contains(output, "line 9") {
+				t.Errorf("long input shown in full\nGot:\n%s", output)
+			}
+		})
+	}
+}
+
+func TestFilters(t *testing.T) {
+	messages := []*parser.StreamMessage{
+		{Type: "system", Subtype: "init", Model: "claude-test"},
+		{Type: "assistant", Message: &parser.MessageContent{Content: []parser.ContentBlock{
+			{Type: "text", Text: "Starting"},
+			{Type: "tool_use", ID: "t1", Name: "Bash", Input: map[string]interface{}{"command": "make"}},
+			{Type: "tool_use", ID: "t2", Name: "Read", Input: map[string]interface{}{"file_path": "main.go"}},
+			{Type: "tool_use", ID: "task", Name: "Task", Input: map[string]interface{}{"subagent_type": "Explore", "description": "look around"}},
+		}}},
+		{Type: "assistant", ParentToolUseID: "task", Message: &parser.MessageContent{Content: []parser.ContentBlock{
+			{Type: "tool_use", ID: "s1", Name: "Grep", Input: map[string]interface{}{"pattern": "TODO"}},
+		}}},
+		{Type: "user", ParentToolUseID: "task", Message: &parser.MessageContent{Content: []parser.ContentBlock{
+			{Type: "tool_result", ToolUseID: "s1", Content: "grep failed", IsError: true},
+		}}},
+		{Type: "user", Message: &parser.MessageContent{Content: []parser.ContentBlock{
+			{Type: "tool_result", ToolUseID: "t1", Content: "build broke", IsError: true},
+			{Type: "tool_result", ToolUseID: "t2", Content: "package main"},
+			{Type: "tool_result", ToolUseID: "task", Content: "explored"},
+		}}},
+		{Type: "result", Subtype: "success", NumTurns: 2},
+	}
+
+	tests := []struct {
+		name   string
+		cfg    Config
+		shown  []string
+		hidden []string
+	}{
+		{
+			name:   "only types",
+			cfg:    Config{OnlyTypes: []string{"assistant", "result"}},
+			shown:  []string{"Starting", "TOOL: Bash", "RESULT: SUCCESS"},
+			hidden: []string{"claude-test", "build broke", "package main", "NO RESULT"},
+		},
+		{
+			name:   "hide types",
+			cfg:    Config{HideTypes: []string{"system", "assistant"}},
+			shown:  []string{"build broke", "package main", "RESULT: SUCCESS"},
+			hidden: []string{"claude-test", "Starting"},
+		},
+		{
+			name:   "only tools",
+			cfg:    Config{OnlyTools: []string{"Bash", "G*"}},
+			shown:  []string{"Starting", "TOOL: Bash", "build broke", "TOOL: Grep", "grep failed"},
+			hidden: []string{"main.go", "package main", "explored", "NO RESULT"},
+		},
+		{
+			name:   "errors only",
+			cfg:    Config{ErrorsOnly: true},
+			shown:  []string{"build broke", "grep failed", "RESULT: SUCCESS"},
+			hidden: []string{"claude-test", "Starting", "package main", "explored", "NO RESULT"},
+		},
+		{
+			name:   "depth",
+			cfg:    Config{HideDepth: 1},
+			shown:  []string{"Starting", "build broke", "explored"},
+			hidden: []string{"SUBAGENT", "TOOL: Grep", "grep failed", "NO RESULT"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			cfg := tt.cfg
+			cfg.Style = StylePlain
+			r := NewRenderer(&buf, &cfg)
+			r.Start()
+			for i, msg := range messages {
+				Render(r, msg, i+1)
+			}
+			r.Finish()
+			output := buf.String()
+
+			for _, want := range tt.shown {
+				if !strings.Contains(output, want) {
+					t.Errorf("output missing %q\nGot:\n%s", want, output)
+				}
+			}
+			for _, hidden := range tt.hidden {
+				if strings.Contains(output, hidden) {
+					t.Errorf("output contains hidden %q\nGot:\n%s", hidden, output)
+				}
+			}
+		})
+	}
+}
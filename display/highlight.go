@@ -0,0 +1,114 @@
+package display
+
+import "strings"
+
+// codeLanguage describes enough of a language's syntax to highlight it line by line
+type codeLanguage struct {
+	comment  string // line comment marker
+	keywords map[string]bool
+	foldCase bool // keywords are case-insensitive
+}
+
+func newCodeLanguage(comment, keywords string) *codeLanguage {
+	l := &codeLanguage{comment: comment, keywords: make(map[string]bool)}
+	for _, k := range strings.Fields(keywords) {
+		l.keywords[k] = true
+	}
+	return l
+}
+
+func newCaseInsensitiveLanguage(comment, keywords string) *codeLanguage {
+	l := newCodeLanguage(comment, keywords)
+	l.foldCase = true
+	return l
+}
+
+var (
+	goLanguage   = newCodeLanguage("//", "break case chan const continue default defer else fallthrough for func go goto if import interface map package range return select struct switch type var nil true false iota")
+	pyLanguage   = newCodeLanguage("#", "and as assert async await break class continue def del elif else except finally for from global if import in is lambda nonlocal not or pass raise return try while with yield None True False self")
+	jsLanguage   = newCodeLanguage("//", "async await break case catch class const continue debugger default delete do else export extends finally for function if import in instanceof interface let new of return static super switch this throw try type typeof var void while yield null undefined true false")
+	shLanguage   = newCodeLanguage("#", "if then else elif fi for while until do done case esac in function return local export set unset echo cd exit source")
+	rsLanguage   = newCodeLanguage("//", "as async await break const continue crate dyn else enum extern fn for if impl in let loop match mod move mut pub ref return self Self static struct super trait type unsafe use where while true false None Some Ok Err")
+	cLanguage    = newCodeLanguage("//", "auto break case char class const continue default delete do double else enum extern final float for if import int long namespace new nullptr package private protected public return short signed sizeof static struct switch template this throw try typedef union unsigned using virtual void volatile while boolean extends implements interface null true false")
+	sqlLanguage  = newCaseInsensitiveLanguage("--", "select from where insert into values update set delete create table drop alter index join left right inner outer on and or not null as order by group having limit distinct primary key")
+	dataLanguage = newCodeLanguage("#", "true false null yes no")
+)
+
+// codeLanguages maps fenced code block languages to their syntax
+var codeLanguages = map[string]*codeLanguage{
+	"go": goLanguage, "golang": goLanguage,
+	"python": pyLanguage, "py": pyLanguage,
+	"javascript": jsLanguage, "js": jsLanguage, "jsx": jsLanguage,
+	"typescript": jsLanguage, "ts": jsLanguage, "tsx": jsLanguage,
+	"sh": shLanguage, "bash": shLanguage, "shell": shLanguage, "zsh": shLanguage, "console": shLanguage,
+	"rust": rsLanguage, "rs": rsLanguage,
+	"c": cLanguage, "h": cLanguage, "cpp": cLanguage, "c++": cLanguage, "java": cLanguage, "kotlin": cLanguage,
+	"sql":  sqlLanguage,
+	"json": dataLanguage, "yaml": dataLanguage, "yml": dataLanguage, "toml": dataLanguage,
+}
+
+// highlightCode colors a line of a fenced code block in the given language.
+// Strings, comments and numbers are found with a simple scan, so constructs
+// spanning lines are not recognized.
+func highlightCode(lang, line string) string {
+	if lang == "diff" {
+		switch {
+		case strings.HasPrefix(line, "+"):
+			return Green.Sprint(line)
+		case strings.HasPrefix(line, "-"):
+			return Red.Sprint(line)
+		case strings.HasPrefix(line, "@@"):
+			return Cyan.Sprint(line)
+		}
+		return White.Sprint(line)
+	}
+
+	l, ok := codeLanguages[lang]
+	if !ok {
+		return White.Sprint(line)
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(line); {
+		c := line[i]
+		switch {
+		case l.comment != "" && strings.HasPrefix(line[i:], l.comment) && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			sb.WriteString(Gray.Sprint(line[i:]))
+			return sb.String()
+		case c == '"' || c == '\'' || c == '`':
+			end := i + 1
+			for end < len(line) && line[end] != c {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(line))
+			sb.WriteString(Green.Sprint(line[i:end]))
+			i = end
+		case isWordByte(c):
+			end := i
+			for end < len(line) && (isWordByte(line[end]) || c >= '0' && c <= '9' && line[end] == '.') {
+				end++
+			}
+			word := line[i:end]
+			switch {
+			case c >= '0' && c <= '9':
+				sb.WriteString(Yellow.Sprint(word))
+			case l.keywords[word], l.foldCase && l.keywords[strings.ToLower(word)]:
+				sb.WriteString(Magenta.Sprint(word))
+			default:
+				sb.WriteString(White.Sprint(word))
+			}
+			i = end
+		default:
+			end := i + 1
+			for end < len(line) && !isWordByte(line[end]) && strings.IndexByte("\"'`", line[end]) < 0 && !strings.HasPrefix(line[end:], l.comment) {
+				end++
+			}
+			sb.WriteString(White.Sprint(line[i:end]))
+			i = end
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,489 @@
+package display
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+
+	"github.com/ariel-frischer/claude-clean/parser"
+)
+
+// htmlRenderer renders messages as a self-contained HTML document, using the
+// colored cards of the default style with collapsible tool inputs and results
+type htmlRenderer struct {
+	*base
+	open   int // subagent <div>s currently open
+	anchor int // last line number given an anchor, so each id is used once
+}
+
+const htmlHeader = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>cclean transcript</title>
+<style>
+:root { --bg: #1e2127; --card: #262a31; --fg: #dcdfe4; --muted: #7f848e; --cyan: #56b6c2; --green: #98c379;
+  --yellow: #e5c07b; --magenta: #c678dd; --red: #e06c75; --blue: #61afef; }
+body { margin: 0; background: var(--bg); color: var(--fg); font: 14px/1.5 ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; }
+main { max-width: 1100px; margin: 0 auto; padding: 16px; }
+nav { position: sticky; top: 0; padding: 8px 0; background: var(--bg); text-align: right; }
+button { background: var(--card); color: var(--fg); border: 1px solid var(--muted); border-radius: 4px; font: inherit; cursor: pointer; }
+.card { background: var(--card); border-left: 3px solid var(--muted); border-radius: 4px; margin: 8px 0; padding: 6px 10px; }
+.card:target { outline: 1px solid var(--fg); }
+.head { font-weight: bold; display: flex; gap: 8px; }
+.head .summary { font-weight: normal; }
+.meta { color: var(--muted); font-weight: normal; }
+.head .where { margin-left: auto; }
+a.line { color: var(--muted); text-decoration: none; font-weight: normal; }
+a.line:hover { text-decoration: underline; }
+.text, pre { white-space: pre-wrap; word-break: break-word; margin: 4px 0; }
+pre { font: inherit; }
+details > summary { cursor: pointer; color: var(--muted); }
+table { border-collapse: collapse; margin: 4px 0; }
+th, td { text-align: left; vertical-align: top; padding: 1px 12px 1px 0; }
+th { color: var(--muted); font-weight: normal; }
+ul.todos { list-style: none; padding-left: 4px; margin: 4px 0; }
+.subagent { margin-left: 24px; }
+.system { border-color: var(--cyan); } .system .head, .agent .head { color: var(--cyan); }
+.assistant { border-color: var(--green); } .assistant .head { color: var(--green); }
+.thinking { color: var(--muted); font-style: italic; }
+.tool { border-color: var(--yellow); } .tool .head, .warning .head { color: var(--yellow); }
+.result { border-color: var(--magenta); } .result .head { color: var(--magenta); }
+.error { border-color: var(--red); } .error .head, .denied { color: var(--red); }
+.final, .user { border-color: var(--blue); } .final .head, .user .head { color: var(--blue); }
+.agent { border-color: var(--cyan); } .warning { border-color: var(--yellow); }
+.add { color: var(--green); } .del { color: var(--red); } .subject { color: var(--cyan); } .ln { color: var(--muted); }
+.completed { color: var(--green); } .in_progress { color: var(--yellow); } .pending { color: var(--muted); }
+</style>
+</head>
+<body>
+<main>
+<nav><button onclick="toggleAll(true)">Expand all</button> <button onclick="toggleAll(false)">Collapse all</button></nav>
+`
+
+const htmlFooter = `</main>
+<script>
+function toggleAll(open) {
+  document.querySelectorAll("details").forEach(function (d) { d.open = open; });
+}
+function openTarget() {
+  var el = location.hash && document.getElementById(location.hash.slice(1));
+  if (el) el.querySelectorAll("details").forEach(function (d) { d.open = true; });
+}
+window.addEventListener("hashchange", openTarget);
+openTarget();
+</script>
+</body>
+</html>
+`
+
+// todoIcons are the TodoWrite status icons used by the terminal styles
+var todoIcons = map[string]string{"completed": "✓", "in_progress": "→", "pending": "○"}
+
+func (r *htmlRenderer) start() {
+	fmt.Fprint(r.w, htmlHeader)
+}
+
+func (r *htmlRenderer) finish() {
+	r.nest(0)
+	fmt.Fprint(r.w, htmlFooter)
+}
+
+// nest closes subagent <div>s until depth levels remain open
+func (r *htmlRenderer) nest(depth int) {
+	for ; r.open > depth; r.open-- {
+		fmt.Fprintln(r.w, "</div>")
+	}
+}
+
+// card opens a message card. The title is HTML; the line number gets an
+// anchor the first time it is seen.
+func (r *htmlRenderer) card(class, title string, lineNum int) {
+	fmt.Fprintf(r.w, `<div class="card %s"`, class)
+	if lineNum > 0 && lineNum != r.anchor {
+		fmt.Fprintf(r.w, ` id="L%d"`, lineNum)
+		r.anchor = lineNum
+	}
+	fmt.Fprintf(r.w, `><div class="head">%s<span class="where meta">`, title)
+	if elapsed := strings.TrimSpace(r.elapsed()); elapsed != "" {
+		fmt.Fprintf(r.w, "%s ", elapsed)
+	}
+	if lineNum > 0 {
+		fmt.Fprintf(r.w, `<a class="line" href="#L%d">L%d</a>`, lineNum, lineNum)
+	}
+	fmt.Fprintln(r.w, "</span></div>")
+}
+
+func (r *htmlRenderer) endCard() {
+	fmt.Fprintln(r.w, "</div>")
+}
+
+// line writes a single escaped line of card content
+func (r *htmlRenderer) line(class, format string, a ...interface{}) {
+	if class != "" {
+		class = fmt.Sprintf(` class="%s"`, class)
+	}
+	fmt.Fprintf(r.w, "<div%s>%s</div>\n", class, html.EscapeString(fmt.Sprintf(format, a...)))
+}
+
+func (r *htmlRenderer) system(msg *parser.StreamMessage, lineNum int) {
+	r.nest(r.depth())
+	title := "SYSTEM"
+	if msg.Subtype != "" {
+		title += fmt.Sprintf(` <span class="summary">[%s]</span>`, html.EscapeString(msg.Subtype))
+	}
+	r.card("system", title, lineNum)
+
+	if msg.CWD != "" {
+		r.line("", "Working Directory: %s", msg.CWD)
+	}
+	if msg.Model != "" {
+		r.line("", "Model: %s", msg.Model)
+	}
+	if msg.ClaudeCodeVersion != "" {
+		r.line("", "Claude Code: v%s", msg.ClaudeCodeVersion)
+	}
+	if len(msg.Tools) > 0 {
+		fmt.Fprintf(r.w, "<details><summary>Tools: %d available</summary><div class=\"text\">%s</div></details>\n",
+			len(msg.Tools), html.EscapeString(strings.Join(msg.Tools, ", ")))
+	}
+	if msg.Content != "" {
+		fmt.Fprintf(r.w, "<div class=\"text\">%s</div>\n", html.EscapeString(msg.Content))
+	}
+	r.endCard()
+}
+
+func (r *htmlRenderer) assistant(msg *parser.StreamMessage, lineNum int) {
+	r.nest(r.depth())
+
+	var textBlocks []string
+	var thinkingBlocks []parser.ContentBlock
+	var toolUses []parser.ContentBlock
+
+	for _, block := range r.unstreamed(msg) {
+		switch block.Type {
+		case "text":
+			if block.Text != "" {
+				textBlocks = append(textBlocks, block.Text)
+			}
+		case "thinking", "redacted_thinking":
+			if r.cfg.ShowThinking {
+				thinkingBlocks = append(thinkingBlocks, block)
+			}
+		case "tool_use":
+			toolUses = append(toolUses, block)
+		}
+	}
+
+	for _, block := range thinkingBlocks {
+		r.thinking(&block, lineNum)
+	}
+
+	if len(textBlocks) > 0 {
+		r.card("assistant", "ASSISTANT", lineNum)
+		for _, text := range textBlocks {
+			fmt.Fprintf(r.w, "<div class=\"text\">%s</div>\n", html.EscapeString(text))
+		}
+		if r.cfg.Verbose && msg.Message.Usage != nil {
+			r.line("meta", "%s", usageText(msg.Message.Usage))
+		}
+		r.endCard()
+	}
+
+	for _, tool := range toolUses {
+		r.toolUse(&tool, lineNum)
+	}
+}
+
+func (r *htmlRenderer) beginText(lineNum int) {
+	r.nest(r.depth())
+	r.card("assistant", "ASSISTANT", lineNum)
+	fmt.Fprint(r.w, `<div class="text">`)
+}
+
+func (r *htmlRenderer) writeText(text string) {
+	fmt.Fprint(r.w, html.EscapeString(text))
+}
+
+func (r *htmlRenderer) endText() {
+	fmt.Fprintln(r.w, "</div>")
+	r.endCard()
+}
+
+func (r *htmlRenderer) thinking(block *parser.ContentBlock, lineNum int) {
+	text := thinkingText(block)
+	if text == "" {
+		return
+	}
+
+	r.card("thinking", "THINKING", lineNum)
+	fmt.Fprintf(r.w, "<div class=\"text\">%s</div>\n", html.EscapeString(text))
+	if r.cfg.Verbose && block.Signature != "" {
+		r.line("meta", "Signature: %d bytes", len(block.Signature))
+	}
+	r.endCard()
+}
+
+func (r *htmlRenderer) subagentStart(a *subagent, lineNum int) {
+	r.nest(a.Depth - 1)
+	title := "SUBAGENT: " + html.EscapeString(subagentName(a))
+	if a.started {
+		title += ` <span class="summary">(continued)</span>`
+	} else if a.Description != "" {
+		title += fmt.Sprintf(` <span class="summary">- %s</span>`, html.EscapeString(a.Description))
+	}
+	r.card("agent", title, lineNum)
+	r.endCard()
+	fmt.Fprintln(r.w, `<div class="subagent">`)
+	r.open++
+}
+
+func (r *htmlRenderer) subagentEnd(a *subagent) {
+	r.nest(a.Depth - 1)
+	r.card("agent", fmt.Sprintf("SUBAGENT DONE: %s <span class=\"summary\">(%s)</span>",
+		html.EscapeString(subagentName(a)), subagentSummary(a)), 0)
+	r.endCard()
+}
+
+func (r *htmlRenderer) unansweredCalls(calls []*toolCall) {
+	r.nest(0)
+	r.card("warning", fmt.Sprintf("WARNING: %s never received a result", plural(len(calls), "tool call")), 0)
+	for _, call := range calls {
+		fmt.Fprintf(r.w, "<div>%s <a class=\"line\" href=\"#L%d\">L%d</a></div>\n",
+			html.EscapeString(callLabel(call)), call.LineNum, call.LineNum)
+	}
+	r.endCard()
+}
+
+// toolTitle returns the card title of a tool call or result
+func toolTitle(label, name, summary string) string {
+	title := label + ": " + html.EscapeString(name)
+	if summary != "" {
+		title += fmt.Sprintf(` <span class="summary">(%s)</span>`, html.EscapeString(summary))
+	}
+	return title
+}
+
+func (r *htmlRenderer) toolUse(tool *parser.ContentBlock, lineNum int) {
+	r.card("tool", toolTitle("TOOL", tool.Name, ToolSummary(tool)), lineNum)
+	if r.cfg.Verbose {
+		r.line("meta", "ID: %s", tool.ID)
+	}
+
+	if lines := inputLines(tool.Name, tool.Input); lines != nil {
+		fmt.Fprint(r.w, "<details open><summary>Input</summary>")
+		r.toolLines(TruncateLongOutput(lines, r.cfg.inputTruncation()))
+		fmt.Fprintln(r.w, "</details>")
+	} else if len(tool.Input) > 0 {
+		keys := make([]string, 0, len(tool.Input))
+		for key := range tool.Input {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		fmt.Fprintln(r.w, "<details open><summary>Input</summary><table>")
+		for _, key := range keys {
+			fmt.Fprintf(r.w, "<tr><th>%s</th><td>", html.EscapeString(key))
+			switch v := tool.Input[key].(type) {
+			case string:
+				fmt.Fprintf(r.w, "<pre>%s</pre>", html.EscapeString(v))
+			case []interface{}:
+				if tool.Name == "TodoWrite" && key == "todos" {
+					r.todos(v)
+					break
+				}
+				r.json(v)
+			default:
+				r.json(v)
+			}
+			fmt.Fprintln(r.w, "</td></tr>")
+		}
+		fmt.Fprintln(r.w, "</table></details>")
+	}
+	r.endCard()
+}
+
+// toolLineClasses are the CSS classes of tool input and result lines
+var toolLineClasses = map[ToolLineKind]string{
+	ToolLineMuted: "meta", ToolLineHeader: "subject", ToolLineAdded: "add", ToolLineRemoved: "del",
+}
+
+// toolLines writes tool input or result lines as preformatted text
+func (r *htmlRenderer) toolLines(lines []ToolLine) {
+	fmt.Fprint(r.w, "<pre>")
+	for _, l := range lines {
+		if l.Gutter != "" {
+			fmt.Fprintf(r.w, "<span class=\"ln\">%s</span>  ", html.EscapeString(l.Gutter))
+		}
+		if class := toolLineClasses[l.Kind]; class != "" {
+			fmt.Fprintf(r.w, "<span class=\"%s\">%s</span>\n", class, html.EscapeString(l.Text))
+		} else {
+			fmt.Fprintf(r.w, "%s\n", html.EscapeString(l.Text))
+		}
+	}
+	fmt.Fprint(r.w, "</pre>")
+}
+
+// todos writes TodoWrite items as a checklist
+func (r *htmlRenderer) todos(todos []interface{}) {
+	fmt.Fprint(r.w, `<ul class="todos">`)
+	for _, todo := range todos {
+		todoMap, ok := todo.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		content, _ := todoMap["content"].(string)
+		status, _ := todoMap["status"].(string)
+		icon, ok := todoIcons[status]
+		if !ok {
+			icon = "-"
+		}
+		fmt.Fprintf(r.w, `<li class="%s">%s %s</li>`, html.EscapeString(status), icon, html.EscapeString(content))
+	}
+	fmt.Fprint(r.w, "</ul>")
+}
+
+// json writes a non-string input value as indented JSON
+func (r *htmlRenderer) json(v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Fprintf(r.w, "<pre>%s</pre>", html.EscapeString(fmt.Sprintf("%v", v)))
+		return
+	}
+	fmt.Fprintf(r.w, "<pre>%s</pre>", html.EscapeString(string(data)))
+}
+
+func (r *htmlRenderer) user(msg *parser.StreamMessage, lineNum int) {
+	r.nest(r.depth())
+	for _, text := range r.prompts(msg) {
+		r.card("user", "USER", lineNum)
+		fmt.Fprintf(r.w, "<div class=\"text\">%s</div>\n", html.EscapeString(text))
+		r.endCard()
+	}
+	for _, block := range msg.Message.Content {
+		if block.Type == "tool_result" {
+			r.toolResult(&block, lineNum)
+		}
+	}
+}
+
+func (r *htmlRenderer) toolResult(block *parser.ContentBlock, lineNum int) {
+	call := r.takeCall(block.ToolUseID)
+
+	class, label := "result", "TOOL RESULT"
+	if block.IsError {
+		class, label = "error", "TOOL RESULT ERROR"
+	}
+	title := label
+	if call != nil {
+		title = toolTitle(label, call.Name, call.Summary) +
+			fmt.Sprintf(` <span class="meta">[%s]</span>`, formatDuration(call.Latency))
+	}
+	r.card(class, title, lineNum)
+	if r.cfg.Verbose {
+		r.line("meta", "Tool ID: %s", block.ToolUseID)
+	}
+
+	content := parser.ContentText(block.Content)
+	if !r.cfg.Verbose {
+		content = parser.StripSystemReminders(content)
+	}
+
+	if content == "" {
+		r.line("meta", "(no output)")
+	} else {
+		lines := resultLines(call, content)
+		if block.IsError {
+			lines = textLines(ToolLineText, content)
+		}
+		shown := TruncateLongOutput(lines, r.resultTruncation(call, block.IsError))
+		open := ""
+		if len(shown) >= len(lines) {
+			open = " open"
+		}
+		fmt.Fprintf(r.w, "<details%s><summary>%s</summary>", open, plural(len(lines), "line"))
+		r.toolLines(shown)
+		fmt.Fprintln(r.w, "</details>")
+	}
+	r.endCard()
+}
+
+func (r *htmlRenderer) result(msg *parser.StreamMessage, lineNum int) {
+	r.nest(r.depth())
+	if msg.IsError {
+		r.card("error", "RESULT: ERROR", lineNum)
+	} else {
+		r.card("final", "RESULT: SUCCESS", lineNum)
+	}
+
+	fmt.Fprintln(r.w, "<table>")
+	row := func(label, format string, a ...interface{}) {
+		fmt.Fprintf(r.w, "<tr><th>%s</th><td>%s</td></tr>\n", label, html.EscapeString(fmt.Sprintf(format, a...)))
+	}
+	if msg.NumTurns > 0 {
+		row("Turns", "%d", msg.NumTurns)
+	}
+	if msg.DurationMS > 0 {
+		if msg.DurationAPIMS > 0 {
+			row("Duration", "%.2fs (API: %.2fs)", float64(msg.DurationMS)/1000.0, float64(msg.DurationAPIMS)/1000.0)
+		} else {
+			row("Duration", "%.2fs", float64(msg.DurationMS)/1000.0)
+		}
+	}
+	if msg.TotalCostUSD > 0 {
+		row("Cost", "$%.4f", msg.TotalCostUSD)
+	}
+	if msg.Usage != nil {
+		row("Tokens", "%s", usageText(msg.Usage))
+	}
+	if r.cfg.Verbose && len(msg.ModelUsage) > 0 {
+		models := make([]string, 0, len(msg.ModelUsage))
+		for model := range msg.ModelUsage {
+			models = append(models, model)
+		}
+		sort.Strings(models)
+		for _, model := range models {
+			usageMap, _ := msg.ModelUsage[model].(map[string]interface{})
+			inputTokens, _ := usageMap["inputTokens"].(float64)
+			outputTokens, _ := usageMap["outputTokens"].(float64)
+			cost, _ := usageMap["costUSD"].(float64)
+			row(html.EscapeString(model), "in=%.0f out=%.0f $%.4f", inputTokens, outputTokens, cost)
+		}
+	}
+	fmt.Fprintln(r.w, "</table>")
+
+	if len(msg.PermissionDenials) > 0 {
+		r.line("denied", "Permission Denials: %d", len(msg.PermissionDenials))
+		if r.cfg.Verbose {
+			for i, denial := range msg.PermissionDenials {
+				r.line("denied", "[%d] %v", i+1, denial)
+			}
+		}
+	}
+
+	if msg.Result != "" {
+		fmt.Fprintf(r.w, "<div class=\"text\">%s</div>\n", html.EscapeString(msg.Result))
+	}
+	r.endCard()
+}
+
+func (r *htmlRenderer) unknown(msg *parser.StreamMessage, lineNum int) {
+	r.nest(r.depth())
+	r.card("", "Unknown message type: "+html.EscapeString(msg.Type), lineNum)
+	r.endCard()
+}
+
+// usageText formats token usage as in the terminal styles
+func usageText(usage *parser.Usage) string {
+	s := fmt.Sprintf("in=%d out=%d", usage.InputTokens, usage.OutputTokens)
+	if usage.CacheReadInputTokens > 0 {
+		s += fmt.Sprintf(" cache_read=%d", usage.CacheReadInputTokens)
+	}
+	if usage.CacheCreationInputTokens > 0 {
+		s += fmt.Sprintf(" cache_create=%d", usage.CacheCreationInputTokens)
+	}
+	return s
+}
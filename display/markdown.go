@@ -0,0 +1,405 @@
+package display
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/ariel-frischer/claude-clean/parser"
+)
+
+// markdownRenderer renders messages as GitHub-flavored Markdown, for pasting
+// transcripts into pull requests and wikis. Subagent messages are nested in
+// blockquotes by the base renderer.
+type markdownRenderer struct {
+	*base
+}
+
+// fenceLanguages maps file extensions to code fence languages where the
+// extension itself is not a language name GitHub recognizes
+var fenceLanguages = map[string]string{
+	"yml": "yaml", "h": "c", "hpp": "cpp", "cc": "cpp", "mjs": "javascript", "cjs": "javascript",
+	"zsh": "bash", "sh": "bash", "txt": "", "log": "",
+}
+
+// fenceLanguage returns the code fence language for a file path
+func fenceLanguage(path string) string {
+	switch base := strings.ToLower(filepath.Base(path)); base {
+	case "makefile", "dockerfile":
+		return base
+	}
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	if lang, ok := fenceLanguages[ext]; ok {
+		return lang
+	}
+	return ext
+}
+
+// writeFence writes text as a fenced code block, using a fence longer than
+// any run of backticks in the text
+func writeFence(w io.Writer, lang, text string) {
+	fence := strings.Repeat("`", max(3, longestRun(text, '`')+1))
+	fmt.Fprintf(w, "%s%s\n%s\n%s\n\n", fence, lang, strings.TrimRight(text, "\n"), fence)
+}
+
+// inlineCode formats text as a code span
+func inlineCode(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	ticks := strings.Repeat("`", longestRun(text, '`')+1)
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		text = " " + text + " "
+	}
+	return ticks + text + ticks
+}
+
+// markdownEscaper escapes the characters that start emphasis, code, links,
+// HTML or table cells
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`,
+	`<`, `\<`, `>`, `\>`, `|`, `\|`, `~`, `\~`,
+)
+
+// escapeMarkdown escapes text so that it is shown as written
+func escapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+// longestRun returns the length of the longest run of c in text
+func longestRun(text string, c byte) int {
+	longest, run := 0, 0
+	for i := 0; i < len(text); i++ {
+		if text[i] == c {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return longest
+}
+
+// heading returns the suffix of a heading with the elapsed time and line number
+func (r *markdownRenderer) heading(lineNum int) string {
+	return r.elapsed() + FormatLineNum(lineNum, r.cfg.ShowLineNum)
+}
+
+func (r *markdownRenderer) system(msg *parser.StreamMessage, lineNum int) {
+	fmt.Fprintf(r.w, "## System")
+	if msg.Subtype != "" {
+		fmt.Fprintf(r.w, " (%s)", msg.Subtype)
+	}
+	fmt.Fprintf(r.w, "%s\n\n", r.heading(lineNum))
+
+	if msg.CWD != "" {
+		fmt.Fprintf(r.w, "- Working Directory: %s\n", inlineCode(msg.CWD))
+	}
+	if msg.Model != "" {
+		fmt.Fprintf(r.w, "- Model: %s\n", inlineCode(msg.Model))
+	}
+	if msg.ClaudeCodeVersion != "" {
+		fmt.Fprintf(r.w, "- Claude Code: v%s\n", msg.ClaudeCodeVersion)
+	}
+	if len(msg.Tools) > 0 {
+		fmt.Fprintf(r.w, "- Tools: %d available\n", len(msg.Tools))
+	}
+	if msg.Content != "" {
+		fmt.Fprintf(r.w, "\n%s\n", strings.TrimSpace(msg.Content))
+	}
+	fmt.Fprintln(r.w)
+}
+
+func (r *markdownRenderer) assistant(msg *parser.StreamMessage, lineNum int) {
+	var textBlocks []string
+	var thinkingBlocks []parser.ContentBlock
+	var toolUses []parser.ContentBlock
+
+	for _, block := range r.unstreamed(msg) {
+		switch block.Type {
+		case "text":
+			if block.Text != "" {
+				textBlocks = append(textBlocks, block.Text)
+			}
+		case "thinking", "redacted_thinking":
+			if r.cfg.ShowThinking {
+				thinkingBlocks = append(thinkingBlocks, block)
+			}
+		case "tool_use":
+			toolUses = append(toolUses, block)
+		}
+	}
+
+	for _, block := range thinkingBlocks {
+		r.thinking(&block, lineNum)
+	}
+
+	if len(textBlocks) > 0 {
+		fmt.Fprintf(r.w, "## Assistant%s\n\n", r.heading(lineNum))
+		for _, text := range textBlocks {
+			fmt.Fprintf(r.w, "%s\n\n", strings.TrimSpace(text))
+		}
+		if r.cfg.Verbose && msg.Message.Usage != nil {
+			fmt.Fprintf(r.w, "*Tokens: %s*\n\n", usageText(msg.Message.Usage))
+		}
+	}
+
+	for _, tool := range toolUses {
+		r.toolUse(&tool, lineNum)
+	}
+}
+
+func (r *markdownRenderer) beginText(lineNum int) {
+	fmt.Fprintf(r.w, "## Assistant%s\n\n", r.heading(lineNum))
+}
+
+func (r *markdownRenderer) writeText(text string) {
+	r.stream.lineOpen = !strings.HasSuffix(text, "\n")
+	fmt.Fprint(r.w, text)
+}
+
+func (r *markdownRenderer) endText() {
+	if r.stream.lineOpen {
+		fmt.Fprintln(r.w)
+	}
+	fmt.Fprintln(r.w)
+}
+
+func (r *markdownRenderer) thinking(block *parser.ContentBlock, lineNum int) {
+	text := thinkingText(block)
+	if text == "" {
+		return
+	}
+
+	fmt.Fprintf(r.w, "<details>\n<summary>Thinking%s</summary>\n\n", html.EscapeString(r.heading(lineNum)))
+	fmt.Fprintf(r.w, "%s\n\n", text)
+	if r.cfg.Verbose && block.Signature != "" {
+		fmt.Fprintf(r.w, "*Signature: %d bytes*\n\n", len(block.Signature))
+	}
+	fmt.Fprint(r.w, "</details>\n\n")
+}
+
+func (r *markdownRenderer) subagentStart(a *subagent, lineNum int) {
+	fmt.Fprintf(r.w, "### Subagent: %s", inlineCode(subagentName(a)))
+	if a.started {
+		fmt.Fprint(r.w, " (continued)")
+	} else if a.Description != "" {
+		fmt.Fprintf(r.w, " - %s", escapeMarkdown(a.Description))
+	}
+	fmt.Fprintf(r.w, "%s\n\n", r.heading(lineNum))
+}
+
+func (r *markdownRenderer) subagentEnd(a *subagent) {
+	fmt.Fprintf(r.w, "*Subagent done: %s (%s)*\n\n", inlineCode(subagentName(a)), escapeMarkdown(subagentSummary(a)))
+}
+
+func (r *markdownRenderer) unansweredCalls(calls []*toolCall) {
+	fmt.Fprintf(r.w, "**Warning:** %s never received a result:\n\n", plural(len(calls), "tool call"))
+	for _, call := range calls {
+		fmt.Fprintf(r.w, "- %s%s\n", r.callTitle(call.Name, call.Summary), FormatLineNum(call.LineNum, true))
+	}
+	fmt.Fprintln(r.w)
+}
+
+// callTitle formats a tool name and its input summary for a heading
+func (r *markdownRenderer) callTitle(name, summary string) string {
+	if summary == "" {
+		return inlineCode(name)
+	}
+	return inlineCode(name) + " " + inlineCode(summary)
+}
+
+func (r *markdownRenderer) toolUse(tool *parser.ContentBlock, lineNum int) {
+	fmt.Fprintf(r.w, "### Tool: %s%s\n\n", inlineCode(tool.Name), r.heading(lineNum))
+	if r.cfg.Verbose {
+		fmt.Fprintf(r.w, "*ID: %s*\n\n", inlineCode(tool.ID))
+	}
+
+	input := tool.Input
+	str := func(key string) string {
+		return inputString(input, key)
+	}
+	truncated := func(lines []ToolLine) string {
+		return linesText(TruncateLongOutput(lines, r.cfg.inputTruncation()))
+	}
+
+	// Tools whose input is code are shown as code blocks in their language;
+	// other tools with a registered renderer as its lines
+	switch tool.Name {
+	case "Bash":
+		if description := str("description"); description != "" {
+			fmt.Fprintf(r.w, "*%s*\n\n", escapeMarkdown(description))
+		}
+		writeFence(r.w, "bash", truncated(textLines(ToolLineText, str("command"))))
+	case "Read":
+		fmt.Fprintf(r.w, "%s\n\n", inlineCode(readSummary(input)))
+	case "Write":
+		fmt.Fprintf(r.w, "%s (%s)\n\n", inlineCode(str("file_path")), plural(len(splitLines(str("content"))), "line"))
+		writeFence(r.w, fenceLanguage(str("file_path")), truncated(textLines(ToolLineText, str("content"))))
+	case "Edit", "MultiEdit":
+		r.edits(toolEdits(input))
+	case "TodoWrite":
+		todos, _ := input["todos"].([]interface{})
+		r.todos(todos)
+	default:
+		if lines := inputLines(tool.Name, input); lines != nil {
+			writeFence(r.w, linesLanguage(lines), truncated(lines))
+		} else if len(input) > 0 {
+			data, err := json.MarshalIndent(input, "", "  ")
+			if err != nil {
+				data = []byte(fmt.Sprintf("%v", input))
+			}
+			writeFence(r.w, "json", string(data))
+		}
+	}
+}
+
+// edits writes the replacements of an Edit or MultiEdit call as a diff block
+func (r *markdownRenderer) edits(path string, edits []fileEdit) {
+	fmt.Fprintf(r.w, "%s\n\n", inlineCode(path))
+	var lines []ToolLine
+	for i, e := range edits {
+		if header := editHeader(i, len(edits), e); header != "" {
+			lines = append(lines, ToolLine{Kind: ToolLineMuted, Text: header})
+		}
+		for _, l := range lineDiff(e.Old, e.New) {
+			lines = append(lines, ToolLine{Text: l.String()})
+		}
+	}
+	writeFence(r.w, "diff", linesText(TruncateLongOutput(lines, r.cfg.inputTruncation())))
+}
+
+// linesText joins tool input or result lines, each after its gutter
+func linesText(lines []ToolLine) string {
+	var sb strings.Builder
+	for _, l := range lines {
+		if l.Gutter != "" {
+			sb.WriteString(l.Gutter + "  ")
+		}
+		sb.WriteString(l.Text + "\n")
+	}
+	return sb.String()
+}
+
+// linesLanguage returns "diff" for lines with changes, so they are colored
+// when rendered, and no language otherwise
+func linesLanguage(lines []ToolLine) string {
+	for _, l := range lines {
+		if l.Kind == ToolLineAdded || l.Kind == ToolLineRemoved {
+			return "diff"
+		}
+	}
+	return ""
+}
+
+// todos writes TodoWrite items as a task list
+func (r *markdownRenderer) todos(todos []interface{}) {
+	for _, todo := range todos {
+		todoMap, ok := todo.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		content, _ := todoMap["content"].(string)
+		content = escapeMarkdown(content)
+		status, _ := todoMap["status"].(string)
+		switch status {
+		case "completed":
+			fmt.Fprintf(r.w, "- [x] %s\n", content)
+		case "in_progress":
+			fmt.Fprintf(r.w, "- [ ] %s *(in progress)*\n", content)
+		default:
+			fmt.Fprintf(r.w, "- [ ] %s\n", content)
+		}
+	}
+	fmt.Fprintln(r.w)
+}
+
+func (r *markdownRenderer) user(msg *parser.StreamMessage, lineNum int) {
+	for _, text := range r.prompts(msg) {
+		fmt.Fprintf(r.w, "## User%s\n\n%s\n\n", r.heading(lineNum), strings.TrimSpace(text))
+	}
+	for _, block := range msg.Message.Content {
+		if block.Type == "tool_result" {
+			r.toolResult(&block, lineNum)
+		}
+	}
+}
+
+func (r *markdownRenderer) toolResult(block *parser.ContentBlock, lineNum int) {
+	call := r.takeCall(block.ToolUseID)
+
+	label := "Result"
+	if block.IsError {
+		label = "Error"
+	}
+	lang := ""
+	if call != nil {
+		label += fmt.Sprintf(": %s [%s]", callLabel(call), formatDuration(call.Latency))
+		if path, ok := call.Input["file_path"].(string); ok && call.Name == "Read" {
+			lang = fenceLanguage(path)
+		}
+	}
+
+	content := parser.ContentText(block.Content)
+	if !r.cfg.Verbose {
+		content = parser.StripSystemReminders(content)
+	}
+
+	// Errors are short and matter, so they are shown expanded
+	open := ""
+	if block.IsError {
+		open = " open"
+	}
+	fmt.Fprintf(r.w, "<details%s>\n<summary>%s%s</summary>\n\n", open,
+		html.EscapeString(label), html.EscapeString(r.heading(lineNum)))
+	if r.cfg.Verbose {
+		fmt.Fprintf(r.w, "*Tool ID: %s*\n\n", inlineCode(block.ToolUseID))
+	}
+
+	if content == "" {
+		fmt.Fprint(r.w, "*(no output)*\n\n")
+	} else {
+		lines := textLines(ToolLineText, content)
+		if !block.IsError {
+			lines = resultLines(call, content)
+		}
+		writeFence(r.w, lang, linesText(TruncateLongOutput(lines, r.resultTruncation(call, block.IsError))))
+	}
+	fmt.Fprint(r.w, "</details>\n\n")
+}
+
+func (r *markdownRenderer) result(msg *parser.StreamMessage, lineNum int) {
+	status := "success"
+	if msg.IsError {
+		status = "error"
+	}
+	fmt.Fprintf(r.w, "## Result: %s%s\n\n", status, r.heading(lineNum))
+
+	var rows []string
+	if msg.NumTurns > 0 {
+		rows = append(rows, fmt.Sprintf("| Turns | %d |", msg.NumTurns))
+	}
+	if msg.DurationMS > 0 {
+		rows = append(rows, fmt.Sprintf("| Duration | %.2fs |", float64(msg.DurationMS)/1000.0))
+	}
+	if msg.DurationAPIMS > 0 {
+		rows = append(rows, fmt.Sprintf("| API Duration | %.2fs |", float64(msg.DurationAPIMS)/1000.0))
+	}
+	if msg.TotalCostUSD > 0 {
+		rows = append(rows, fmt.Sprintf("| Cost | $%.4f |", msg.TotalCostUSD))
+	}
+	if msg.Usage != nil {
+		rows = append(rows, fmt.Sprintf("| Tokens | %s |", usageText(msg.Usage)))
+	}
+	if len(msg.PermissionDenials) > 0 {
+		rows = append(rows, fmt.Sprintf("| Permission Denials | %d |", len(msg.PermissionDenials)))
+	}
+	if len(rows) > 0 {
+		fmt.Fprintf(r.w, "| Metric | Value |\n|---|---|\n%s\n\n", strings.Join(rows, "\n"))
+	}
+
+	if msg.Result != "" {
+		fmt.Fprintf(r.w, "%s\n\n", strings.TrimSpace(msg.Result))
+	}
+}
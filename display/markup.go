@@ -0,0 +1,422 @@
+package display
+
+import (
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-runewidth"
+	"golang.org/x/term"
+)
+
+// defaultWidth is the output width used when it is not a terminal
+const defaultWidth = 80
+
+var (
+	headingPattern = regexp.MustCompile(`^(#{1,6})\s+(.*?)(\s+#+)?\s*$`)
+	listPattern    = regexp.MustCompile(`^([-*+]|\d{1,9}[.)])\s+(.*)$`)
+	rulePattern    = regexp.MustCompile(`^(\*\s*){3,}$|^(-\s*){3,}$|^(_\s*){3,}$`)
+	delimPattern   = regexp.MustCompile(`^\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?$`)
+)
+
+// mdSpan is a run of inline markdown text with its terminal attributes
+type mdSpan struct {
+	Text  string
+	Attrs []color.Attribute
+}
+
+// markdownWriter renders markdown as styled terminal lines. Text may be
+// written in pieces as it streams in: each line is rendered once it is
+// complete, and table rows are held back until the table ends so that its
+// columns line up.
+type markdownWriter struct {
+	width   int               // columns available for a line
+	emit    func(line string) // called with each rendered line, without its newline
+	partial string            // incomplete last line
+	fence   string            // fence that opened the current code block, "" outside code
+	lang    string            // language of the current code block
+	table   []string          // rows of the table being read
+}
+
+func newMarkdownWriter(width int, emit func(line string)) *markdownWriter {
+	return &markdownWriter{width: width, emit: emit}
+}
+
+// writeMarkdown renders a complete markdown text, calling emit for each line
+func writeMarkdown(text string, width int, emit func(line string)) {
+	m := newMarkdownWriter(width, emit)
+	m.Write(text)
+	m.Close()
+}
+
+// Write renders the lines of text completed so far
+func (m *markdownWriter) Write(text string) {
+	lines := strings.Split(m.partial+text, "\n")
+	m.partial = lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		m.line(line)
+	}
+}
+
+// Close renders the last line and any table still being read
+func (m *markdownWriter) Close() {
+	if m.partial != "" {
+		m.line(m.partial)
+		m.partial = ""
+	}
+	m.flushTable()
+}
+
+func (m *markdownWriter) line(s string) {
+	s = strings.TrimRight(s, "\r")
+	trimmed := strings.TrimSpace(s)
+
+	if m.fence != "" {
+		if strings.HasPrefix(trimmed, m.fence) && strings.Trim(trimmed, m.fence[:1]) == "" {
+			m.emit(Gray.Sprint(trimmed))
+			m.fence, m.lang = "", ""
+			return
+		}
+		m.emit(highlightCode(m.lang, s))
+		return
+	}
+
+	if strings.HasPrefix(trimmed, "|") {
+		m.table = append(m.table, trimmed)
+		return
+	}
+	m.flushTable()
+
+	if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+		n := len(trimmed) - len(strings.TrimLeft(trimmed, trimmed[:1]))
+		m.fence = trimmed[:n]
+		if fields := strings.Fields(trimmed[n:]); len(fields) > 0 {
+			m.lang = strings.ToLower(fields[0])
+		}
+		m.emit(Gray.Sprint(trimmed))
+		return
+	}
+	m.emit(m.block(s))
+}
+
+// block renders a line outside code blocks and tables
+func (m *markdownWriter) block(s string) string {
+	rest := strings.TrimLeft(s, " \t")
+	indent := s[:len(s)-len(rest)]
+	text := palette[RoleText]
+
+	if match := headingPattern.FindStringSubmatch(rest); match != nil {
+		attrs := withAttrs(palette[RoleSystem], color.Bold)
+		if len(match[1]) == 1 {
+			attrs = append(attrs, color.Underline)
+		} else if len(match[1]) > 2 {
+			attrs = withAttrs(text, color.Bold)
+		}
+		return indent + spansString(inlineSpans(match[2], attrs))
+	}
+	if rulePattern.MatchString(rest) {
+		return indent + Gray.Sprint(strings.Repeat("─", max(m.width-len(indent), 3)))
+	}
+	if strings.HasPrefix(rest, ">") {
+		depth := 0
+		for strings.HasPrefix(rest, ">") {
+			rest = strings.TrimPrefix(strings.TrimPrefix(rest, ">"), " ")
+			depth++
+		}
+		quote := withAttrs(palette[RoleMuted], color.Italic)
+		return indent + Gray.Sprint(strings.Repeat("│ ", depth)) + spansString(inlineSpans(rest, quote))
+	}
+	if match := listPattern.FindStringSubmatch(rest); match != nil {
+		marker, item := match[1], match[2]
+		if len(marker) == 1 {
+			marker = "•"
+		}
+		switch {
+		case strings.HasPrefix(item, "[ ] "):
+			marker += " ☐"
+			item = item[4:]
+		case strings.HasPrefix(item, "[x] "), strings.HasPrefix(item, "[X] "):
+			marker += " " + Green.Sprint("☑")
+			item = item[4:]
+		}
+		return indent + Cyan.Sprint(marker) + " " + spansString(inlineSpans(item, text))
+	}
+	return indent + spansString(inlineSpans(rest, text))
+}
+
+// flushTable renders the table rows read so far. Rows without a delimiter
+// row under the first one are not a table and are rendered as text.
+func (m *markdownWriter) flushTable() {
+	rows := m.table
+	m.table = nil
+	if len(rows) == 0 {
+		return
+	}
+	if len(rows) < 2 || !delimPattern.MatchString(rows[1]) {
+		for _, row := range rows {
+			m.emit(m.block(row))
+		}
+		return
+	}
+
+	aligns := tableCells(rows[1])
+	cells := make([][][]mdSpan, 0, len(rows)-1)
+	widths := make([]int, len(aligns))
+	for i, row := range append(rows[:1:1], rows[2:]...) {
+		attrs := palette[RoleText]
+		if i == 0 {
+			attrs = withAttrs(attrs, color.Bold)
+		}
+		texts := tableCells(row)
+		line := make([][]mdSpan, len(aligns))
+		for c := range line {
+			if c < len(texts) {
+				line[c] = inlineSpans(texts[c], attrs)
+			}
+			widths[c] = max(widths[c], spansWidth(line[c]))
+		}
+		cells = append(cells, line)
+	}
+
+	// Narrow the widest columns until the table fits
+	total := 3 * (len(widths) - 1)
+	for _, w := range widths {
+		total += w
+	}
+	for total > m.width {
+		widest := 0
+		for c, w := range widths {
+			if w > widths[widest] {
+				widest = c
+			}
+		}
+		if widths[widest] <= 3 {
+			break
+		}
+		widths[widest]--
+		total--
+	}
+
+	separator := make([]string, len(widths))
+	for c, w := range widths {
+		separator[c] = strings.Repeat("─", w)
+	}
+	for i, line := range cells {
+		parts := make([]string, len(line))
+		for c, spans := range line {
+			parts[c] = alignCell(truncateSpans(spans, widths[c]), widths[c], aligns[c])
+		}
+		m.emit(strings.TrimRight(strings.Join(parts, Gray.Sprint(" │ ")), " "))
+		if i == 0 {
+			m.emit(Gray.Sprint(strings.Join(separator, "─┼─")))
+		}
+	}
+}
+
+// tableCells splits a table row into its trimmed cells
+func tableCells(row string) []string {
+	row = strings.TrimPrefix(strings.TrimSpace(row), "|")
+	if strings.HasSuffix(row, "|") && !strings.HasSuffix(row, `\|`) {
+		row = row[:len(row)-1]
+	}
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(row); i++ {
+		switch {
+		case row[i] == '\\' && i+1 < len(row) && row[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case row[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(row[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// alignCell pads a rendered cell to width according to the alignment given
+// by its delimiter row cell, such as ":--" or "--:"
+func alignCell(spans []mdSpan, width int, delim string) string {
+	pad := width - spansWidth(spans)
+	text := spansString(spans)
+	switch {
+	case strings.HasPrefix(delim, ":") && strings.HasSuffix(delim, ":"):
+		return strings.Repeat(" ", pad/2) + text + strings.Repeat(" ", pad-pad/2)
+	case strings.HasSuffix(delim, ":"):
+		return strings.Repeat(" ", pad) + text
+	default:
+		return text + strings.Repeat(" ", pad)
+	}
+}
+
+// inlineSpans splits a line of markdown into spans styled by its emphasis,
+// code and link markup, removing the markup. Text outside any markup gets attrs.
+func inlineSpans(s string, attrs []color.Attribute) []mdSpan {
+	var spans []mdSpan
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			spans = append(spans, mdSpan{Text: text.String(), Attrs: attrs})
+			text.Reset()
+		}
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch c {
+		case '\\':
+			if i+1 < len(s) && strings.IndexByte("\\`*_~[]()#|<>!", s[i+1]) >= 0 {
+				text.WriteByte(s[i+1])
+				i += 2
+				continue
+			}
+		case '`':
+			ticks := s[i : i+runLength(s[i:], '`')]
+			if end := strings.Index(s[i+len(ticks):], ticks); end >= 0 {
+				flush()
+				code := s[i+len(ticks) : i+len(ticks)+end]
+				spans = append(spans, mdSpan{Text: code, Attrs: withAttrs(attrs, palette[RoleTool]...)})
+				i += 2*len(ticks) + end
+				continue
+			}
+			// An unclosed run of backticks is text
+			text.WriteString(ticks)
+			i += len(ticks)
+			continue
+		case '*', '_', '~':
+			n := min(runLength(s[i:], c), 2)
+			if c == '~' && n < 2 || c == '_' && i > 0 && isWordByte(s[i-1]) {
+				break
+			}
+			if end := closingDelim(s, i+n, c, n); end > 0 {
+				flush()
+				attr := color.Italic
+				if c == '~' {
+					attr = color.CrossedOut
+				} else if n == 2 {
+					attr = color.Bold
+				}
+				spans = append(spans, inlineSpans(s[i+n:end], withAttrs(attrs, attr))...)
+				i = end + n
+				continue
+			}
+		case '[':
+			label := strings.Index(s[i:], "](")
+			if label < 0 {
+				break
+			}
+			end := strings.IndexByte(s[i+label+2:], ')')
+			if end < 0 {
+				break
+			}
+			flush()
+			url := s[i+label+2 : i+label+2+end]
+			spans = append(spans, inlineSpans(s[i+1:i+label], withAttrs(withAttrs(attrs, palette[RoleResult]...), color.Underline))...)
+			if url != s[i+1:i+label] {
+				spans = append(spans, mdSpan{Text: " (" + url + ")", Attrs: palette[RoleMuted]})
+			}
+			i += label + 3 + end
+			continue
+		}
+		text.WriteByte(c)
+		i++
+	}
+	flush()
+	return spans
+}
+
+// closingDelim returns the index of the run of n delimiter characters c that
+// closes emphasis opened before start, or -1 if there is none
+func closingDelim(s string, start int, c byte, n int) int {
+	if start >= len(s) || s[start] == ' ' {
+		return -1
+	}
+	for j := start + 1; j < len(s); {
+		if s[j] != c {
+			j++
+			continue
+		}
+		run := runLength(s[j:], c)
+		if run == n && s[j-1] != ' ' && !(c == '_' && j+n < len(s) && isWordByte(s[j+n])) {
+			return j
+		}
+		j += run
+	}
+	return -1
+}
+
+// runLength returns how many times c repeats at the start of s
+func runLength(s string, c byte) int {
+	n := 0
+	for n < len(s) && s[n] == c {
+		n++
+	}
+	return n
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// withAttrs returns a copy of attrs with more added
+func withAttrs(attrs []color.Attribute, more ...color.Attribute) []color.Attribute {
+	return append(append([]color.Attribute(nil), attrs...), more...)
+}
+
+// spansString returns the spans with their terminal styles applied
+func spansString(spans []mdSpan) string {
+	var sb strings.Builder
+	for _, s := range spans {
+		sb.WriteString(color.New(s.Attrs...).Sprint(s.Text))
+	}
+	return sb.String()
+}
+
+// spansWidth returns the number of terminal columns the spans take up
+func spansWidth(spans []mdSpan) int {
+	n := 0
+	for _, s := range spans {
+		n += runewidth.StringWidth(s.Text)
+	}
+	return n
+}
+
+// truncateSpans shortens spans wider than width, ending them with an ellipsis
+func truncateSpans(spans []mdSpan, width int) []mdSpan {
+	if spansWidth(spans) <= width {
+		return spans
+	}
+	var out []mdSpan
+	used := 0
+	for _, s := range spans {
+		w := runewidth.StringWidth(s.Text)
+		if used+w < width {
+			out = append(out, s)
+			used += w
+			continue
+		}
+		out = append(out, mdSpan{Text: runewidth.Truncate(s.Text, width-used, "…"), Attrs: s.Attrs})
+		break
+	}
+	return out
+}
+
+// terminalWidth returns the width of w if it is a terminal, or defaultWidth
+func terminalWidth(w io.Writer) int {
+	if f, ok := w.(*os.File); ok {
+		if width, _, err := term.GetSize(int(f.Fd())); err == nil && width > 0 {
+			return width
+		}
+	}
+	return defaultWidth
+}
+
+// textWidth returns the columns left for assistant text written after a
+// prefix of the given width, at the current subagent depth
+func (b *base) textWidth(prefix int) int {
+	return max(b.width-prefix-runewidth.StringWidth(b.indent)*b.depth(), 20)
+}
@@ -0,0 +1,140 @@
+package display
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ariel-frischer/claude-clean/parser"
+	"github.com/fatih/color"
+)
+
+const markdownSample = "# Summary\n\nFixed **two** bugs in `main.go`, see [the docs](https://example.com).\n\n" +
+	"- first\n  * nested snake_case_name\n2. [x] done\n\n> quoted\n\n" +
+	"| File | Lines |\n|:-----|------:|\n| a.go | 12 |\n| long_name.go | 3 |\n\n" +
+	"```go\nfunc main() {}\n```\n"
+
+func renderMarkdownLines(text string, width int) []string {
+	var lines []string
+	writeMarkdown(text, width, func(line string) { lines = append(lines, stripANSI(line)) })
+	return lines
+}
+
+func TestMarkdownWriter(t *testing.T) {
+	expected := []string{
+		"Summary",
+		"",
+		"Fixed two bugs in main.go, see the docs (https://example.com).",
+		"",
+		"• first",
+		"  • nested snake_case_name",
+		"2. ☑ done",
+		"",
+		"│ quoted",
+		"",
+		"File         │ Lines",
+		"─────────────┼──────",
+		"a.go         │    12",
+		"long_name.go │     3",
+		"",
+		"```go",
+		"func main() {}",
+		"```",
+	}
+	got := renderMarkdownLines(markdownSample, 80)
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(expected, "\n"))
+	}
+
+	// Streamed text renders the same once it is complete
+	var streamed []string
+	m := newMarkdownWriter(80, func(line string) { streamed = append(streamed, stripANSI(line)) })
+	for _, piece := range strings.SplitAfter(markdownSample, "e") {
+		m.Write(piece)
+	}
+	m.Close()
+	if strings.Join(streamed, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("streamed:\n%s\nwant:\n%s", strings.Join(streamed, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestMarkdownTableWidth(t *testing.T) {
+	table := "| Name | Description |\n|---|---|\n| cclean | Transforms stream-json output into readable text |\n"
+	lines := renderMarkdownLines(table, 30)
+	for _, line := range lines {
+		if n := len([]rune(line)); n > 30 {
+			t.Errorf("line %q is %d columns, want at most 30", line, n)
+		}
+	}
+	if !strings.Contains(lines[2], "…") {
+		t.Errorf("long cell not truncated: %q", lines[2])
+	}
+
+	// Pipes without a delimiter row are not a table
+	if got := renderMarkdownLines("| not a table |", 30); got[0] != "| not a table |" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestMarkdownColors(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+
+	var output string
+	writeMarkdown(markdownSample, 80, func(line string) { output += line + "\n" })
+	for _, want := range []string{
+		color.New(color.FgWhite, color.Bold).Sprint("two"),
+		color.New(color.FgWhite, color.FgYellow).Sprint("main.go"),
+		color.New(color.FgCyan, color.Bold, color.Underline).Sprint("Summary"),
+		Magenta.Sprint("func"),
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q\nGot:\n%q", want, output)
+		}
+	}
+
+	tests := []struct {
+		lang, line string
+		expected   []string
+	}{
+		{"python", `x = "a" # note`, []string{Green.Sprint(`"a"`), Gray.Sprint("# note")}},
+		{"go", "return 42", []string{Magenta.Sprint("return"), Yellow.Sprint("42")}},
+		{"sql", "SELECT id", []string{Magenta.Sprint("SELECT"), White.Sprint("id")}},
+		{"diff", "+added", []string{Green.Sprint("+added")}},
+		{"unknown", "func", []string{White.Sprint("func")}},
+	}
+	for _, tt := range tests {
+		got := highlightCode(tt.lang, tt.line)
+		for _, want := range tt.expected {
+			if !strings.Contains(got, want) {
+				t.Errorf("highlightCode(%q, %q) = %q, missing %q", tt.lang, tt.line, got, want)
+			}
+		}
+	}
+}
+
+func TestAssistantMarkdown(t *testing.T) {
+	msg := &parser.StreamMessage{Type: "assistant", Message: &parser.MessageContent{Content: []parser.ContentBlock{
+		{Type: "text", Text: "## Plan\n- **one**"},
+	}}}
+
+	tests := []struct {
+		style    OutputStyle
+		raw      bool
+		expected string
+	}{
+		{StyleDefault, false, "│ Plan\n│ • one\n"},
+		{StyleDefault, true, "│ ## Plan\n- **one**\n"},
+		{StyleMinimal, false, "  Plan\n  • one\n"},
+		{StylePlain, false, "## Plan\n- **one**"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		r := NewRenderer(&buf, &Config{Style: tt.style, NoMarkdown: tt.raw})
+		Render(r, msg, 1)
+		if output := stripANSI(buf.String()); !strings.Contains(output, tt.expected) {
+			t.Errorf("%s (raw %v): output missing %q\nGot:\n%s", tt.style, tt.raw, tt.expected, output)
+		}
+	}
+}
@@ -7,277 +7,354 @@ import (
 	"github.com/ariel-frischer/claude-clean/parser"
 )
 
-func displayMessageMinimal(msg *parser.StreamMessage, lineNum int, cfg *Config) {
-	switch msg.Type {
-	case "system":
-		displaySystemMessageMinimal(msg, lineNum, cfg)
-	case "assistant":
-		displayAssistantMessageMinimal(msg, lineNum, cfg)
-	case "user":
-		displayUserMessageMinimal(msg, lineNum, cfg)
-	case "result":
-		displayResultMessageMinimal(msg, lineNum, cfg)
-	}
+// minimalRenderer renders messages with colors but without box-drawing characters
+type minimalRenderer struct {
+	*base
 }
 
-func displaySystemMessageMinimal(msg *parser.StreamMessage, lineNum int, cfg *Config) {
-	BoldCyan.Printf("SYSTEM")
+func (r *minimalRenderer) system(msg *parser.StreamMessage, lineNum int) {
+	BoldCyan.Fprintf(r.w, "SYSTEM")
 	if msg.Subtype != "" {
-		Cyan.Printf(" [%s]", msg.Subtype)
+		Cyan.Fprintf(r.w, " [%s]", msg.Subtype)
 	}
-	Gray.Printf("%s%s\n", FormatElapsed(cfg), FormatLineNum(lineNum, cfg.ShowLineNum))
+	Gray.Fprintf(r.w, "%s%s\n", r.elapsed(), FormatLineNum(lineNum, r.cfg.ShowLineNum))
 
 	if msg.CWD != "" {
-		Cyan.Printf("  Working Directory: %s\n", msg.CWD)
+		Cyan.Fprintf(r.w, "  Working Directory: %s\n", msg.CWD)
 	}
 	if msg.Model != "" {
-		Cyan.Printf("  Model: %s\n", msg.Model)
+		Cyan.Fprintf(r.w, "  Model: %s\n", msg.Model)
 	}
 	if msg.ClaudeCodeVersion != "" {
-		Cyan.Printf("  Claude Code: v%s\n", msg.ClaudeCodeVersion)
+		Cyan.Fprintf(r.w, "  Claude Code: v%s\n", msg.ClaudeCodeVersion)
 	}
 	if len(msg.Tools) > 0 {
-		Cyan.Printf("  Tools: %d available\n", len(msg.Tools))
+		Cyan.Fprintf(r.w, "  Tools: %d available\n", len(msg.Tools))
+	}
+	for _, line := range contentLines(msg) {
+		Cyan.Fprintf(r.w, "  %s\n", line)
 	}
-	fmt.Println()
+	fmt.Fprintln(r.w)
 }
 
-func displayAssistantMessageMinimal(msg *parser.StreamMessage, lineNum int, cfg *Config) {
+func (r *minimalRenderer) assistant(msg *parser.StreamMessage, lineNum int) {
 	if msg.Message == nil || len(msg.Message.Content) == 0 {
 		return
 	}
 
 	// Group consecutive text blocks
 	var textBlocks []string
+	var thinkingBlocks []parser.ContentBlock
 	var toolUses []parser.ContentBlock
 
-	for _, block := range msg.Message.Content {
+	for _, block := range r.unstreamed(msg) {
 		switch block.Type {
 		case "text":
 			if block.Text != "" {
 				textBlocks = append(textBlocks, block.Text)
 			}
+		case "thinking", "redacted_thinking":
+			if r.cfg.ShowThinking {
+				thinkingBlocks = append(thinkingBlocks, block)
+			}
 		case "tool_use":
 			toolUses = append(toolUses, block)
 		}
 	}
 
+	// Display thinking blocks
+	for _, block := range thinkingBlocks {
+		r.thinking(&block, lineNum)
+	}
+
 	// Display text blocks
 	if len(textBlocks) > 0 {
-		BoldGreen.Printf("ASSISTANT")
-		Gray.Printf("%s%s\n", FormatElapsed(cfg), FormatLineNum(lineNum, cfg.ShowLineNum))
+		BoldGreen.Fprintf(r.w, "ASSISTANT")
+		Gray.Fprintf(r.w, "%s%s\n", r.elapsed(), FormatLineNum(lineNum, r.cfg.ShowLineNum))
 
 		for _, text := range textBlocks {
-			White.Printf("  %s\n", text)
+			if r.cfg.NoMarkdown {
+				White.Fprintf(r.w, "  %s\n", text)
+				continue
+			}
+			writeMarkdown(text, r.textWidth(2), r.textLine)
 		}
 
-		if cfg.Verbose && msg.Message.Usage != nil {
-			Gray.Printf("  Tokens: in=%d out=%d", msg.Message.Usage.InputTokens, msg.Message.Usage.OutputTokens)
+		if r.cfg.Verbose && msg.Message.Usage != nil {
+			Gray.Fprintf(r.w, "  Tokens: in=%d out=%d", msg.Message.Usage.InputTokens, msg.Message.Usage.OutputTokens)
 			if msg.Message.Usage.CacheReadInputTokens > 0 {
-				Gray.Printf(" cache_read=%d", msg.Message.Usage.CacheReadInputTokens)
+				Gray.Fprintf(r.w, " cache_read=%d", msg.Message.Usage.CacheReadInputTokens)
 			}
 			if msg.Message.Usage.CacheCreationInputTokens > 0 {
-				Gray.Printf(" cache_create=%d", msg.Message.Usage.CacheCreationInputTokens)
+				Gray.Fprintf(r.w, " cache_create=%d", msg.Message.Usage.CacheCreationInputTokens)
 			}
-			fmt.Println()
+			fmt.Fprintln(r.w)
 		}
-		fmt.Println()
+		fmt.Fprintln(r.w)
 	}
 
 	// Display tool uses
 	for _, tool := range toolUses {
-		displayToolUseMinimal(&tool, lineNum, cfg)
+		r.toolUse(&tool, lineNum)
+	}
+}
+
+func (r *minimalRenderer) beginText(lineNum int) {
+	BoldGreen.Fprintf(r.w, "ASSISTANT")
+	Gray.Fprintf(r.w, "%s%s\n", r.elapsed(), FormatLineNum(lineNum, r.cfg.ShowLineNum))
+}
+
+// textLine writes a rendered line of assistant text
+func (r *minimalRenderer) textLine(line string) {
+	fmt.Fprintf(r.w, "  %s\n", line)
+}
+
+func (r *minimalRenderer) writeText(text string) {
+	r.stream.writeLines(r.w, text,
+		func() { fmt.Fprint(r.w, "  ") },
+		func(s string) { White.Fprint(r.w, s) })
+}
+
+func (r *minimalRenderer) endText() {
+	if r.stream.lineOpen {
+		fmt.Fprintln(r.w)
+	}
+	fmt.Fprintln(r.w)
+}
+
+func (r *minimalRenderer) thinking(block *parser.ContentBlock, lineNum int) {
+	text := thinkingText(block)
+	if text == "" {
+		return
+	}
+
+	Gray.Fprintf(r.w, "THINKING%s%s\n", r.elapsed(), FormatLineNum(lineNum, r.cfg.ShowLineNum))
+
+	for _, line := range strings.Split(text, "\n") {
+		Gray.Fprintf(r.w, "  %s\n", line)
+	}
+
+	if r.cfg.Verbose && block.Signature != "" {
+		Gray.Fprintf(r.w, "  Signature: %d bytes\n", len(block.Signature))
+	}
+	fmt.Fprintln(r.w)
+}
+
+func (r *minimalRenderer) subagentStart(a *subagent, lineNum int) {
+	BoldCyan.Fprintf(r.w, "SUBAGENT: %s", subagentName(a))
+	if a.started {
+		Cyan.Fprint(r.w, " (continued)")
+	} else if a.Description != "" {
+		Cyan.Fprintf(r.w, " - %s", a.Description)
+	}
+	Gray.Fprintf(r.w, "%s%s\n", r.elapsed(), FormatLineNum(lineNum, r.cfg.ShowLineNum))
+	fmt.Fprintln(r.w)
+}
+
+func (r *minimalRenderer) subagentEnd(a *subagent) {
+	Cyan.Fprintf(r.w, "SUBAGENT DONE: %s (%s)\n", subagentName(a), subagentSummary(a))
+	fmt.Fprintln(r.w)
+}
+
+func (r *minimalRenderer) unansweredCalls(calls []*toolCall) {
+	BoldYellow.Fprintf(r.w, "WARNING: %s never received a result\n", plural(len(calls), "tool call"))
+	for _, call := range calls {
+		Yellow.Fprintf(r.w, "  %s", callLabel(call))
+		Gray.Fprintf(r.w, "%s\n", FormatLineNum(call.LineNum, true))
 	}
+	fmt.Fprintln(r.w)
 }
 
-func displayToolUseMinimal(tool *parser.ContentBlock, lineNum int, cfg *Config) {
-	BoldYellow.Printf("TOOL: %s", tool.Name)
-	Gray.Printf("%s%s\n", FormatElapsed(cfg), FormatLineNum(lineNum, cfg.ShowLineNum))
+func (r *minimalRenderer) toolUse(tool *parser.ContentBlock, lineNum int) {
+	BoldYellow.Fprintf(r.w, "TOOL: %s", tool.Name)
+	Gray.Fprintf(r.w, "%s%s\n", r.elapsed(), FormatLineNum(lineNum, r.cfg.ShowLineNum))
 
-	if cfg.Verbose {
-		Yellow.Printf("  ID: %s\n", tool.ID)
+	if r.cfg.Verbose {
+		Yellow.Fprintf(r.w, "  ID: %s\n", tool.ID)
 	}
 
-	if tool.Input != nil {
-		Yellow.Println("  Input:")
+	if lines := inputLines(tool.Name, tool.Input); lines != nil {
+		r.toolLines("    ", TruncateLongOutput(lines, r.cfg.inputTruncation()))
+	} else if tool.Input != nil {
+		Yellow.Fprintln(r.w, "  Input:")
 		for key, value := range tool.Input {
-			Yellow.Printf("    %s: ", key)
+			Yellow.Fprintf(r.w, "    %s: ", key)
 
 			switch v := value.(type) {
 			case string:
-				if len(v) > 300 {
-					White.Printf("%s ... (%d chars omitted) ... %s\n", v[:200], len(v)-300, v[len(v)-100:])
-				} else {
-					White.Println(v)
-				}
+				White.Fprintln(r.w, truncateChars(v, r.cfg.inputChars()))
 			case []interface{}:
 				if tool.Name == "TodoWrite" && key == "todos" {
-					White.Println()
-					DisplayTodosMinimal(v)
+					White.Fprintln(r.w)
+					DisplayTodosMinimal(r.w, v)
 				} else {
-					White.Printf("[%d items]\n", len(v))
+					White.Fprintf(r.w, "[%d items]\n", len(v))
 				}
 			case map[string]interface{}:
-				White.Println("{...}")
+				White.Fprintln(r.w, "{...}")
 			default:
-				White.Printf("%v\n", v)
+				White.Fprintf(r.w, "%v\n", v)
 			}
 		}
 	}
-	fmt.Println()
+	fmt.Fprintln(r.w)
+}
+
+// toolLines writes indented tool input or result lines
+func (r *minimalRenderer) toolLines(indent string, lines []ToolLine) {
+	for _, l := range lines {
+		fmt.Fprint(r.w, indent)
+		printToolLine(r.w, l)
+	}
 }
 
-func displayUserMessageMinimal(msg *parser.StreamMessage, lineNum int, cfg *Config) {
+func (r *minimalRenderer) user(msg *parser.StreamMessage, lineNum int) {
 	if msg.Message == nil || len(msg.Message.Content) == 0 {
 		return
 	}
 
+	for _, text := range r.prompts(msg) {
+		BoldBlue.Fprintf(r.w, "USER")
+		Gray.Fprintf(r.w, "%s%s\n", r.elapsed(), FormatLineNum(lineNum, r.cfg.ShowLineNum))
+		for _, line := range strings.Split(text, "\n") {
+			White.Fprintf(r.w, "  %s\n", line)
+		}
+		fmt.Fprintln(r.w)
+	}
+
 	for _, block := range msg.Message.Content {
 		if block.Type == "tool_result" {
-			displayToolResultMinimal(&block, lineNum, cfg)
+			r.toolResult(&block, lineNum)
 		}
 	}
 }
 
-func displayToolResultMinimal(block *parser.ContentBlock, lineNum int, cfg *Config) {
-	if block.IsError {
-		BoldRed.Printf("TOOL RESULT ERROR")
-		Gray.Printf("%s%s\n", FormatElapsed(cfg), FormatLineNum(lineNum, cfg.ShowLineNum))
+func (r *minimalRenderer) toolResult(block *parser.ContentBlock, lineNum int) {
+	call := r.takeCall(block.ToolUseID)
 
-		if cfg.Verbose {
-			Red.Printf("  Tool ID: %s\n", block.ToolUseID)
+	if block.IsError {
+		BoldRed.Fprintf(r.w, "TOOL RESULT ERROR")
+		if call != nil {
+			BoldRed.Fprintf(r.w, ": %s", call.Name)
+			if call.Summary != "" {
+				Red.Fprintf(r.w, " (%s)", call.Summary)
+			}
+			Gray.Fprintf(r.w, " [%s]", formatDuration(call.Latency))
 		}
+		Gray.Fprintf(r.w, "%s%s\n", r.elapsed(), FormatLineNum(lineNum, r.cfg.ShowLineNum))
 
-		contentStr := ""
-		switch v := block.Content.(type) {
-		case string:
-			contentStr = v
-		default:
-			contentStr = fmt.Sprintf("%v", v)
+		if r.cfg.Verbose {
+			Red.Fprintf(r.w, "  Tool ID: %s\n", block.ToolUseID)
 		}
 
+		contentStr := parser.ContentText(block.Content)
+
 		// Strip system reminders in non-verbose mode
-		if !cfg.Verbose {
+		if !r.cfg.Verbose {
 			contentStr = parser.StripSystemReminders(contentStr)
 		}
 
-		White.Printf("  %s\n", contentStr)
+		r.toolLines("  ", TruncateLongOutput(textLines(ToolLineText, contentStr), r.resultTruncation(call, true)))
 	} else {
-		BoldMagenta.Printf("TOOL RESULT")
-		Gray.Printf("%s%s\n", FormatElapsed(cfg), FormatLineNum(lineNum, cfg.ShowLineNum))
-
-		if cfg.Verbose {
-			Gray.Printf("  Tool ID: %s\n", block.ToolUseID)
+		BoldMagenta.Fprintf(r.w, "TOOL RESULT")
+		if call != nil {
+			BoldMagenta.Fprintf(r.w, ": %s", call.Name)
+			if call.Summary != "" {
+				Magenta.Fprintf(r.w, " (%s)", call.Summary)
+			}
+			Gray.Fprintf(r.w, " [%s]", formatDuration(call.Latency))
 		}
+		Gray.Fprintf(r.w, "%s%s\n", r.elapsed(), FormatLineNum(lineNum, r.cfg.ShowLineNum))
 
-		contentStr := ""
-		switch v := block.Content.(type) {
-		case string:
-			contentStr = v
-		default:
-			contentStr = fmt.Sprintf("%v", v)
+		if r.cfg.Verbose {
+			Gray.Fprintf(r.w, "  Tool ID: %s\n", block.ToolUseID)
 		}
 
+		contentStr := parser.ContentText(block.Content)
+
 		// Strip system reminders in non-verbose mode
-		if !cfg.Verbose {
+		if !r.cfg.Verbose {
 			contentStr = parser.StripSystemReminders(contentStr)
 		}
 
 		if contentStr == "" {
-			Gray.Println("  (no output)")
+			Gray.Fprintln(r.w, "  (no output)")
 		} else {
-			lines := strings.Split(contentStr, "\n")
-			firstLines := parser.FirstLines
-			lastLines := parser.LastLines
-			totalLines := len(lines)
-
-			if totalLines <= firstLines+lastLines {
-				for _, line := range lines {
-					White.Printf("  %s\n", line)
-				}
-			} else {
-				for i := 0; i < firstLines; i++ {
-					White.Printf("  %s\n", lines[i])
-				}
-				Gray.Printf("  ... (%d more lines) ...\n", totalLines-firstLines-lastLines)
-				for i := totalLines - lastLines; i < totalLines; i++ {
-					White.Printf("  %s\n", lines[i])
-				}
-			}
+			r.toolLines("  ", TruncateLongOutput(resultLines(call, contentStr), r.resultTruncation(call, false)))
 		}
 	}
-	fmt.Println()
+	fmt.Fprintln(r.w)
 }
 
-func displayResultMessageMinimal(msg *parser.StreamMessage, lineNum int, cfg *Config) {
+func (r *minimalRenderer) result(msg *parser.StreamMessage, lineNum int) {
 	if msg.IsError {
-		BoldRed.Printf("RESULT: ERROR")
+		BoldRed.Fprintf(r.w, "RESULT: ERROR")
 	} else {
-		BoldBlue.Printf("RESULT: SUCCESS")
+		BoldBlue.Fprintf(r.w, "RESULT: SUCCESS")
 	}
-	Gray.Printf("%s%s\n", FormatElapsed(cfg), FormatLineNum(lineNum, cfg.ShowLineNum))
+	Gray.Fprintf(r.w, "%s%s\n", r.elapsed(), FormatLineNum(lineNum, r.cfg.ShowLineNum))
 
 	if msg.NumTurns > 0 {
-		Blue.Printf("  Turns: %d\n", msg.NumTurns)
+		Blue.Fprintf(r.w, "  Turns: %d\n", msg.NumTurns)
 	}
 	if msg.DurationMS > 0 {
-		Blue.Printf("  Duration: %.2fs", float64(msg.DurationMS)/1000.0)
+		Blue.Fprintf(r.w, "  Duration: %.2fs", float64(msg.DurationMS)/1000.0)
 		if msg.DurationAPIMS > 0 {
-			Blue.Printf(" (API: %.2fs)", float64(msg.DurationAPIMS)/1000.0)
+			Blue.Fprintf(r.w, " (API: %.2fs)", float64(msg.DurationAPIMS)/1000.0)
 		}
-		Blue.Println()
+		Blue.Fprintln(r.w)
 	}
 	if msg.TotalCostUSD > 0 {
-		Blue.Printf("  Cost: $%.4f\n", msg.TotalCostUSD)
+		Blue.Fprintf(r.w, "  Cost: $%.4f\n", msg.TotalCostUSD)
 	}
 
 	if msg.Usage != nil {
-		Blue.Printf("  Tokens: in=%d out=%d", msg.Usage.InputTokens, msg.Usage.OutputTokens)
+		Blue.Fprintf(r.w, "  Tokens: in=%d out=%d", msg.Usage.InputTokens, msg.Usage.OutputTokens)
 		if msg.Usage.CacheReadInputTokens > 0 {
-			Blue.Printf(" cache_read=%d", msg.Usage.CacheReadInputTokens)
+			Blue.Fprintf(r.w, " cache_read=%d", msg.Usage.CacheReadInputTokens)
 		}
 		if msg.Usage.CacheCreationInputTokens > 0 {
-			Blue.Printf(" cache_create=%d", msg.Usage.CacheCreationInputTokens)
+			Blue.Fprintf(r.w, " cache_create=%d", msg.Usage.CacheCreationInputTokens)
 		}
-		Blue.Println()
+		Blue.Fprintln(r.w)
 	}
 
-	if cfg.Verbose && msg.ModelUsage != nil && len(msg.ModelUsage) > 0 {
-		Blue.Println()
-		Blue.Println("  Model Usage:")
+	if r.cfg.Verbose && msg.ModelUsage != nil && len(msg.ModelUsage) > 0 {
+		Blue.Fprintln(r.w)
+		Blue.Fprintln(r.w, "  Model Usage:")
 		for model, usageData := range msg.ModelUsage {
-			Blue.Printf("    %s:\n", model)
+			Blue.Fprintf(r.w, "    %s:\n", model)
 			if usageMap, ok := usageData.(map[string]interface{}); ok {
 				if inputTokens, ok := usageMap["inputTokens"].(float64); ok {
-					Blue.Printf("      Input: %.0f tokens\n", inputTokens)
+					Blue.Fprintf(r.w, "      Input: %.0f tokens\n", inputTokens)
 				}
 				if outputTokens, ok := usageMap["outputTokens"].(float64); ok {
-					Blue.Printf("      Output: %.0f tokens\n", outputTokens)
+					Blue.Fprintf(r.w, "      Output: %.0f tokens\n", outputTokens)
 				}
 				if cost, ok := usageMap["costUSD"].(float64); ok {
-					Blue.Printf("      Cost: $%.4f\n", cost)
+					Blue.Fprintf(r.w, "      Cost: $%.4f\n", cost)
 				}
 			}
 		}
 	}
 
 	if len(msg.PermissionDenials) > 0 {
-		fmt.Println()
-		Red.Printf("  Permission Denials: %d\n", len(msg.PermissionDenials))
-		if cfg.Verbose {
+		fmt.Fprintln(r.w)
+		Red.Fprintf(r.w, "  Permission Denials: %d\n", len(msg.PermissionDenials))
+		if r.cfg.Verbose {
 			for i, denial := range msg.PermissionDenials {
-				Red.Printf("    [%d] %v\n", i+1, denial)
+				Red.Fprintf(r.w, "    [%d] %v\n", i+1, denial)
 			}
 		}
 	}
 
 	if msg.Result != "" {
-		fmt.Println()
+		fmt.Fprintln(r.w)
 		lines := strings.Split(msg.Result, "\n")
 		for _, line := range lines {
-			White.Printf("  %s\n", line)
+			White.Fprintf(r.w, "  %s\n", line)
 		}
 	}
 
-	fmt.Println()
+	fmt.Fprintln(r.w)
 }
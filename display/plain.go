@@ -7,272 +7,327 @@ import (
 	"github.com/ariel-frischer/claude-clean/parser"
 )
 
-func displayMessagePlain(msg *parser.StreamMessage, lineNum int, cfg *Config) {
-	switch msg.Type {
-	case "system":
-		displaySystemMessagePlain(msg, lineNum, cfg)
-	case "assistant":
-		displayAssistantMessagePlain(msg, lineNum, cfg)
-	case "user":
-		displayUserMessagePlain(msg, lineNum, cfg)
-	case "result":
-		displayResultMessagePlain(msg, lineNum, cfg)
-	}
+// plainRenderer renders messages without colors, suitable for piping
+type plainRenderer struct {
+	*base
 }
 
-func displaySystemMessagePlain(msg *parser.StreamMessage, lineNum int, cfg *Config) {
-	fmt.Printf("SYSTEM")
+func (r *plainRenderer) system(msg *parser.StreamMessage, lineNum int) {
+	fmt.Fprintf(r.w, "SYSTEM")
 	if msg.Subtype != "" {
-		fmt.Printf(" [%s]", msg.Subtype)
+		fmt.Fprintf(r.w, " [%s]", msg.Subtype)
 	}
-	fmt.Printf("%s%s\n", FormatElapsed(cfg), FormatLineNum(lineNum, cfg.ShowLineNum))
+	fmt.Fprintf(r.w, "%s%s\n", r.elapsed(), FormatLineNum(lineNum, r.cfg.ShowLineNum))
 
 	if msg.CWD != "" {
-		fmt.Printf("  Working Directory: %s\n", msg.CWD)
+		fmt.Fprintf(r.w, "  Working Directory: %s\n", msg.CWD)
 	}
 	if msg.Model != "" {
-		fmt.Printf("  Model: %s\n", msg.Model)
+		fmt.Fprintf(r.w, "  Model: %s\n", msg.Model)
 	}
 	if msg.ClaudeCodeVersion != "" {
-		fmt.Printf("  Claude Code: v%s\n", msg.ClaudeCodeVersion)
+		fmt.Fprintf(r.w, "  Claude Code: v%s\n", msg.ClaudeCodeVersion)
 	}
 	if len(msg.Tools) > 0 {
-		fmt.Printf("  Tools: %d available\n", len(msg.Tools))
+		fmt.Fprintf(r.w, "  Tools: %d available\n", len(msg.Tools))
+	}
+	for _, line := range contentLines(msg) {
+		fmt.Fprintf(r.w, "  %s\n", line)
 	}
-	fmt.Println()
+	fmt.Fprintln(r.w)
 }
 
-func displayAssistantMessagePlain(msg *parser.StreamMessage, lineNum int, cfg *Config) {
+func (r *plainRenderer) assistant(msg *parser.StreamMessage, lineNum int) {
 	if msg.Message == nil || len(msg.Message.Content) == 0 {
 		return
 	}
 
 	// Group consecutive text blocks
 	var textBlocks []string
+	var thinkingBlocks []parser.ContentBlock
 	var toolUses []parser.ContentBlock
 
-	for _, block := range msg.Message.Content {
+	for _, block := range r.unstreamed(msg) {
 		switch block.Type {
 		case "text":
 			if block.Text != "" {
 				textBlocks = append(textBlocks, block.Text)
 			}
+		case "thinking", "redacted_thinking":
+			if r.cfg.ShowThinking {
+				thinkingBlocks = append(thinkingBlocks, block)
+			}
 		case "tool_use":
 			toolUses = append(toolUses, block)
 		}
 	}
 
+	// Display thinking blocks
+	for _, block := range thinkingBlocks {
+		r.thinking(&block, lineNum)
+	}
+
 	// Display text blocks
 	if len(textBlocks) > 0 {
-		fmt.Printf("ASSISTANT%s%s\n", FormatElapsed(cfg), FormatLineNum(lineNum, cfg.ShowLineNum))
+		fmt.Fprintf(r.w, "ASSISTANT%s%s\n", r.elapsed(), FormatLineNum(lineNum, r.cfg.ShowLineNum))
 
 		for _, text := range textBlocks {
-			fmt.Printf("  %s\n", text)
+			fmt.Fprintf(r.w, "  %s\n", text)
 		}
 
-		if cfg.Verbose && msg.Message.Usage != nil {
-			fmt.Printf("  Tokens: in=%d out=%d", msg.Message.Usage.InputTokens, msg.Message.Usage.OutputTokens)
+		if r.cfg.Verbose && msg.Message.Usage != nil {
+			fmt.Fprintf(r.w, "  Tokens: in=%d out=%d", msg.Message.Usage.InputTokens, msg.Message.Usage.OutputTokens)
 			if msg.Message.Usage.CacheReadInputTokens > 0 {
-				fmt.Printf(" cache_read=%d", msg.Message.Usage.CacheReadInputTokens)
+				fmt.Fprintf(r.w, " cache_read=%d", msg.Message.Usage.CacheReadInputTokens)
 			}
 			if msg.Message.Usage.CacheCreationInputTokens > 0 {
-				fmt.Printf(" cache_create=%d", msg.Message.Usage.CacheCreationInputTokens)
+				fmt.Fprintf(r.w, " cache_create=%d", msg.Message.Usage.CacheCreationInputTokens)
 			}
-			fmt.Println()
+			fmt.Fprintln(r.w)
 		}
-		fmt.Println()
+		fmt.Fprintln(r.w)
 	}
 
 	// Display tool uses
 	for _, tool := range toolUses {
-		displayToolUsePlain(&tool, lineNum, cfg)
+		r.toolUse(&tool, lineNum)
+	}
+}
+
+func (r *plainRenderer) beginText(lineNum int) {
+	fmt.Fprintf(r.w, "ASSISTANT%s%s\n", r.elapsed(), FormatLineNum(lineNum, r.cfg.ShowLineNum))
+}
+
+func (r *plainRenderer) writeText(text string) {
+	r.stream.writeLines(r.w, text,
+		func() { fmt.Fprint(r.w, "  ") },
+		func(s string) { fmt.Fprint(r.w, s) })
+}
+
+func (r *plainRenderer) endText() {
+	if r.stream.lineOpen {
+		fmt.Fprintln(r.w)
 	}
+	fmt.Fprintln(r.w)
 }
 
-func displayToolUsePlain(tool *parser.ContentBlock, lineNum int, cfg *Config) {
-	fmt.Printf("TOOL: %s%s%s\n", tool.Name, FormatElapsed(cfg), FormatLineNum(lineNum, cfg.ShowLineNum))
+func (r *plainRenderer) thinking(block *parser.ContentBlock, lineNum int) {
+	text := thinkingText(block)
+	if text == "" {
+		return
+	}
+
+	fmt.Fprintf(r.w, "THINKING%s%s\n", r.elapsed(), FormatLineNum(lineNum, r.cfg.ShowLineNum))
+
+	for _, line := range strings.Split(text, "\n") {
+		fmt.Fprintf(r.w, "  %s\n", line)
+	}
+
+	if r.cfg.Verbose && block.Signature != "" {
+		fmt.Fprintf(r.w, "  Signature: %d bytes\n", len(block.Signature))
+	}
+	fmt.Fprintln(r.w)
+}
 
-	if cfg.Verbose {
-		fmt.Printf("  ID: %s\n", tool.ID)
+func (r *plainRenderer) subagentStart(a *subagent, lineNum int) {
+	fmt.Fprintf(r.w, "SUBAGENT: %s", subagentName(a))
+	if a.started {
+		fmt.Fprint(r.w, " (continued)")
+	} else if a.Description != "" {
+		fmt.Fprintf(r.w, " - %s", a.Description)
 	}
+	fmt.Fprintf(r.w, "%s%s\n\n", r.elapsed(), FormatLineNum(lineNum, r.cfg.ShowLineNum))
+}
+
+func (r *plainRenderer) subagentEnd(a *subagent) {
+	fmt.Fprintf(r.w, "SUBAGENT DONE: %s (%s)\n\n", subagentName(a), subagentSummary(a))
+}
 
-	if tool.Input != nil {
-		fmt.Println("  Input:")
+func (r *plainRenderer) unansweredCalls(calls []*toolCall) {
+	fmt.Fprintf(r.w, "WARNING: %s never received a result\n", plural(len(calls), "tool call"))
+	for _, call := range calls {
+		fmt.Fprintf(r.w, "  %s%s\n", callLabel(call), FormatLineNum(call.LineNum, true))
+	}
+	fmt.Fprintln(r.w)
+}
+
+func (r *plainRenderer) toolUse(tool *parser.ContentBlock, lineNum int) {
+	fmt.Fprintf(r.w, "TOOL: %s%s%s\n", tool.Name, r.elapsed(), FormatLineNum(lineNum, r.cfg.ShowLineNum))
+
+	if r.cfg.Verbose {
+		fmt.Fprintf(r.w, "  ID: %s\n", tool.ID)
+	}
+
+	if lines := inputLines(tool.Name, tool.Input); lines != nil {
+		r.toolLines("    ", TruncateLongOutput(lines, r.cfg.inputTruncation()))
+	} else if tool.Input != nil {
+		fmt.Fprintln(r.w, "  Input:")
 		for key, value := range tool.Input {
-			fmt.Printf("    %s: ", key)
+			fmt.Fprintf(r.w, "    %s: ", key)
 
 			switch v := value.(type) {
 			case string:
-				if len(v) > 300 {
-					fmt.Printf("%s ... (%d chars omitted) ... %s\n", v[:200], len(v)-300, v[len(v)-100:])
-				} else {
-					fmt.Println(v)
-				}
+				fmt.Fprintln(r.w, truncateChars(v, r.cfg.inputChars()))
 			case []interface{}:
 				if tool.Name == "TodoWrite" && key == "todos" {
-					fmt.Println()
-					DisplayTodosPlain(v)
+					fmt.Fprintln(r.w)
+					DisplayTodosPlain(r.w, v)
 				} else {
-					fmt.Printf("[%d items]\n", len(v))
+					fmt.Fprintf(r.w, "[%d items]\n", len(v))
 				}
 			case map[string]interface{}:
-				fmt.Println("{...}")
+				fmt.Fprintln(r.w, "{...}")
 			default:
-				fmt.Printf("%v\n", v)
+				fmt.Fprintf(r.w, "%v\n", v)
 			}
 		}
 	}
-	fmt.Println()
+	fmt.Fprintln(r.w)
+}
+
+// toolLines writes indented tool input or result lines
+func (r *plainRenderer) toolLines(indent string, lines []ToolLine) {
+	for _, l := range lines {
+		if l.Gutter != "" {
+			l.Text = l.Gutter + "  " + l.Text
+		}
+		fmt.Fprintf(r.w, "%s%s\n", indent, l.Text)
+	}
 }
 
-func displayUserMessagePlain(msg *parser.StreamMessage, lineNum int, cfg *Config) {
+func (r *plainRenderer) user(msg *parser.StreamMessage, lineNum int) {
 	if msg.Message == nil || len(msg.Message.Content) == 0 {
 		return
 	}
 
+	for _, text := range r.prompts(msg) {
+		fmt.Fprintf(r.w, "USER%s%s\n", r.elapsed(), FormatLineNum(lineNum, r.cfg.ShowLineNum))
+		for _, line := range strings.Split(text, "\n") {
+			fmt.Fprintf(r.w, "  %s\n", line)
+		}
+		fmt.Fprintln(r.w)
+	}
+
 	for _, block := range msg.Message.Content {
 		if block.Type == "tool_result" {
-			displayToolResultPlain(&block, lineNum, cfg)
+			r.toolResult(&block, lineNum)
 		}
 	}
 }
 
-func displayToolResultPlain(block *parser.ContentBlock, lineNum int, cfg *Config) {
+func (r *plainRenderer) toolResult(block *parser.ContentBlock, lineNum int) {
+	label := ""
+	call := r.takeCall(block.ToolUseID)
+	if call != nil {
+		label = fmt.Sprintf(": %s [%s]", callLabel(call), formatDuration(call.Latency))
+	}
+
 	if block.IsError {
-		fmt.Printf("TOOL RESULT ERROR%s%s\n", FormatElapsed(cfg), FormatLineNum(lineNum, cfg.ShowLineNum))
+		fmt.Fprintf(r.w, "TOOL RESULT ERROR%s%s%s\n", label, r.elapsed(), FormatLineNum(lineNum, r.cfg.ShowLineNum))
 
-		if cfg.Verbose {
-			fmt.Printf("  Tool ID: %s\n", block.ToolUseID)
+		if r.cfg.Verbose {
+			fmt.Fprintf(r.w, "  Tool ID: %s\n", block.ToolUseID)
 		}
 
-		contentStr := ""
-		switch v := block.Content.(type) {
-		case string:
-			contentStr = v
-		default:
-			contentStr = fmt.Sprintf("%v", v)
-		}
+		contentStr := parser.ContentText(block.Content)
 
 		// Strip system reminders in non-verbose mode
-		if !cfg.Verbose {
+		if !r.cfg.Verbose {
 			contentStr = parser.StripSystemReminders(contentStr)
 		}
 
-		fmt.Printf("  %s\n", contentStr)
+		r.toolLines("  ", TruncateLongOutput(textLines(ToolLineText, contentStr), r.resultTruncation(call, true)))
 	} else {
-		fmt.Printf("TOOL RESULT%s%s\n", FormatElapsed(cfg), FormatLineNum(lineNum, cfg.ShowLineNum))
+		fmt.Fprintf(r.w, "TOOL RESULT%s%s%s\n", label, r.elapsed(), FormatLineNum(lineNum, r.cfg.ShowLineNum))
 
-		if cfg.Verbose {
-			fmt.Printf("  Tool ID: %s\n", block.ToolUseID)
+		if r.cfg.Verbose {
+			fmt.Fprintf(r.w, "  Tool ID: %s\n", block.ToolUseID)
 		}
 
-		contentStr := ""
-		switch v := block.Content.(type) {
-		case string:
-			contentStr = v
-		default:
-			contentStr = fmt.Sprintf("%v", v)
-		}
+		contentStr := parser.ContentText(block.Content)
 
 		// Strip system reminders in non-verbose mode
-		if !cfg.Verbose {
+		if !r.cfg.Verbose {
 			contentStr = parser.StripSystemReminders(contentStr)
 		}
 
 		if contentStr == "" {
-			fmt.Println("  (no output)")
+			fmt.Fprintln(r.w, "  (no output)")
 		} else {
-			lines := strings.Split(contentStr, "\n")
-			firstLines := parser.FirstLines
-			lastLines := parser.LastLines
-			totalLines := len(lines)
-
-			if totalLines <= firstLines+lastLines {
-				for _, line := range lines {
-					fmt.Printf("  %s\n", line)
-				}
-			} else {
-				for i := 0; i < firstLines; i++ {
-					fmt.Printf("  %s\n", lines[i])
-				}
-				fmt.Printf("  ... (%d more lines) ...\n", totalLines-firstLines-lastLines)
-				for i := totalLines - lastLines; i < totalLines; i++ {
-					fmt.Printf("  %s\n", lines[i])
-				}
-			}
+			r.toolLines("  ", TruncateLongOutput(resultLines(call, contentStr), r.resultTruncation(call, false)))
 		}
 	}
-	fmt.Println()
+	fmt.Fprintln(r.w)
 }
 
-func displayResultMessagePlain(msg *parser.StreamMessage, lineNum int, cfg *Config) {
+func (r *plainRenderer) result(msg *parser.StreamMessage, lineNum int) {
 	if msg.IsError {
-		fmt.Printf("RESULT: ERROR%s%s\n", FormatElapsed(cfg), FormatLineNum(lineNum, cfg.ShowLineNum))
+		fmt.Fprintf(r.w, "RESULT: ERROR%s%s\n", r.elapsed(), FormatLineNum(lineNum, r.cfg.ShowLineNum))
 	} else {
-		fmt.Printf("RESULT: SUCCESS%s%s\n", FormatElapsed(cfg), FormatLineNum(lineNum, cfg.ShowLineNum))
+		fmt.Fprintf(r.w, "RESULT: SUCCESS%s%s\n", r.elapsed(), FormatLineNum(lineNum, r.cfg.ShowLineNum))
 	}
 
 	if msg.NumTurns > 0 {
-		fmt.Printf("  Turns: %d\n", msg.NumTurns)
+		fmt.Fprintf(r.w, "  Turns: %d\n", msg.NumTurns)
 	}
 	if msg.DurationMS > 0 {
-		fmt.Printf("  Duration: %.2fs", float64(msg.DurationMS)/1000.0)
+		fmt.Fprintf(r.w, "  Duration: %.2fs", float64(msg.DurationMS)/1000.0)
 		if msg.DurationAPIMS > 0 {
-			fmt.Printf(" (API: %.2fs)", float64(msg.DurationAPIMS)/1000.0)
+			fmt.Fprintf(r.w, " (API: %.2fs)", float64(msg.DurationAPIMS)/1000.0)
 		}
-		fmt.Println()
+		fmt.Fprintln(r.w)
 	}
 	if msg.TotalCostUSD > 0 {
-		fmt.Printf("  Cost: $%.4f\n", msg.TotalCostUSD)
+		fmt.Fprintf(r.w, "  Cost: $%.4f\n", msg.TotalCostUSD)
 	}
 
 	if msg.Usage != nil {
-		fmt.Printf("  Tokens: in=%d out=%d", msg.Usage.InputTokens, msg.Usage.OutputTokens)
+		fmt.Fprintf(r.w, "  Tokens: in=%d out=%d", msg.Usage.InputTokens, msg.Usage.OutputTokens)
 		if msg.Usage.CacheReadInputTokens > 0 {
-			fmt.Printf(" cache_read=%d", msg.Usage.CacheReadInputTokens)
+			fmt.Fprintf(r.w, " cache_read=%d", msg.Usage.CacheReadInputTokens)
 		}
 		if msg.Usage.CacheCreationInputTokens > 0 {
-			fmt.Printf(" cache_create=%d", msg.Usage.CacheCreationInputTokens)
+			fmt.Fprintf(r.w, " cache_create=%d", msg.Usage.CacheCreationInputTokens)
 		}
-		fmt.Println()
+		fmt.Fprintln(r.w)
 	}
 
-	if cfg.Verbose && msg.ModelUsage != nil && len(msg.ModelUsage) > 0 {
-		fmt.Println()
-		fmt.Println("  Model Usage:")
+	if r.cfg.Verbose && msg.ModelUsage != nil && len(msg.ModelUsage) > 0 {
+		fmt.Fprintln(r.w)
+		fmt.Fprintln(r.w, "  Model Usage:")
 		for model, usageData := range msg.ModelUsage {
-			fmt.Printf("    %s:\n", model)
+			fmt.Fprintf(r.w, "    %s:\n", model)
 			if usageMap, ok := usageData.(map[string]interface{}); ok {
 				if inputTokens, ok := usageMap["inputTokens"].(float64); ok {
-					fmt.Printf("      Input: %.0f tokens\n", inputTokens)
+					fmt.Fprintf(r.w, "      Input: %.0f tokens\n", inputTokens)
 				}
 				if outputTokens, ok := usageMap["outputTokens"].(float64); ok {
-					fmt.Printf("      Output: %.0f tokens\n", outputTokens)
+					fmt.Fprintf(r.w, "      Output: %.0f tokens\n", outputTokens)
 				}
 				if cost, ok := usageMap["costUSD"].(float64); ok {
-					fmt.Printf("      Cost: $%.4f\n", cost)
+					fmt.Fprintf(r.w, "      Cost: $%.4f\n", cost)
 				}
 			}
 		}
 	}
 
 	if len(msg.PermissionDenials) > 0 {
-		fmt.Println()
-		fmt.Printf("  Permission Denials: %d\n", len(msg.PermissionDenials))
-		if cfg.Verbose {
+		fmt.Fprintln(r.w)
+		fmt.Fprintf(r.w, "  Permission Denials: %d\n", len(msg.PermissionDenials))
+		if r.cfg.Verbose {
 			for i, denial := range msg.PermissionDenials {
-				fmt.Printf("    [%d] %v\n", i+1, denial)
+				fmt.Fprintf(r.w, "    [%d] %v\n", i+1, denial)
 			}
 		}
 	}
 
 	if msg.Result != "" {
-		fmt.Println()
+		fmt.Fprintln(r.w)
 		lines := strings.Split(msg.Result, "\n")
 		for _, line := range lines {
-			fmt.Printf("  %s\n", line)
+			fmt.Fprintf(r.w, "  %s\n", line)
 		}
 	}
 
-	fmt.Println()
+	fmt.Fprintln(r.w)
 }
@@ -0,0 +1,255 @@
+package display
+
+import (
+	"io"
+	"time"
+
+	"github.com/ariel-frischer/claude-clean/parser"
+)
+
+// Renderer renders stream messages to an output destination.
+// Each output style implements Renderer; use NewRenderer to construct one.
+type Renderer interface {
+	// Start is called once before the first message is rendered
+	Start()
+	// System renders a system message (init, config, session info)
+	System(msg *parser.StreamMessage, lineNum int)
+	// Assistant renders an assistant message (text and tool calls)
+	Assistant(msg *parser.StreamMessage, lineNum int)
+	// User renders a user message (tool results)
+	User(msg *parser.StreamMessage, lineNum int)
+	// StreamEvent renders a partial message event (--include-partial-messages)
+	StreamEvent(msg *parser.StreamMessage, lineNum int)
+	// Result renders the final result message
+	Result(msg *parser.StreamMessage, lineNum int)
+	// Unknown renders a message with an unrecognized type
+	Unknown(msg *parser.StreamMessage, lineNum int)
+	// Finish is called once after the last message has been rendered
+	Finish()
+}
+
+// NewRenderer returns a Renderer for cfg.Style that writes to w.
+// The config is copied, so a single Config may be shared between renderers.
+func NewRenderer(w io.Writer, cfg *Config) Renderer {
+	c := *cfg
+	b := &base{out: w, w: w, cfg: &c, clock: time.Now, indent: subagentIndent, width: c.Width}
+	if b.width <= 0 {
+		b.width = terminalWidth(w)
+	}
+
+	switch c.Style {
+	case StyleCompact:
+		b.style = &compactRenderer{b}
+	case StyleMinimal:
+		b.style = &minimalRenderer{b}
+	case StylePlain:
+		b.style = &plainRenderer{b}
+	case StyleHTML:
+		b.indent = "" // subagents are nested <div>s
+		b.style = &htmlRenderer{base: b}
+	case StyleMarkdown:
+		b.indent = "> " // subagents are nested blockquotes
+		b.style = &markdownRenderer{b}
+	default: // StyleDefault
+		// Long lines are wrapped to keep the box borders intact
+		b.out = &wrapWriter{w: w, width: b.width}
+		b.style = &defaultRenderer{b}
+	}
+	if len(c.Redact) > 0 {
+		b.out = &redactWriter{w: b.out, redact: c.Redact}
+	}
+	b.w = b.out
+	return b
+}
+
+// Render routes a message to the Renderer method for its type
+func Render(r Renderer, msg *parser.StreamMessage, lineNum int) {
+	switch msg.Type {
+	case "system":
+		r.System(msg, lineNum)
+	case "assistant":
+		r.Assistant(msg, lineNum)
+	case "user":
+		r.User(msg, lineNum)
+	case "result":
+		r.Result(msg, lineNum)
+	case "stream_event":
+		r.StreamEvent(msg, lineNum)
+	default:
+		r.Unknown(msg, lineNum)
+	}
+}
+
+// style is implemented by each output style to format individual messages.
+// The state shared across messages lives in base, which every style embeds.
+type style interface {
+	textStreamer
+	start()
+	finish()
+	system(msg *parser.StreamMessage, lineNum int)
+	assistant(msg *parser.StreamMessage, lineNum int)
+	user(msg *parser.StreamMessage, lineNum int)
+	result(msg *parser.StreamMessage, lineNum int)
+	unknown(msg *parser.StreamMessage, lineNum int)
+	subagentStart(a *subagent, lineNum int)
+	subagentEnd(a *subagent)
+	unansweredCalls(calls []*toolCall)
+}
+
+// base implements Renderer on top of a style, tracking the state shared by all styles
+type base struct {
+	out    io.Writer // destination passed to NewRenderer
+	w      io.Writer // current writer, indented for subagent messages
+	cfg    *Config
+	style  style
+	stream stream
+	agents agents
+	calls  map[string]*toolCall // tool calls waiting for a result, by ID
+	clock  func() time.Time
+	indent string          // prefix added per level of subagent nesting, "" if the style nests itself
+	width  int             // output width in columns
+	hidden map[string]bool // IDs of tool calls hidden by Config.HideTools or OnlyTools
+
+	msgTime   time.Time // recorded time of the message being rendered, zero if none was seen
+	prevTime  time.Time // recorded time of the message before it
+	firstTime time.Time // first recorded time seen
+}
+
+func (b *base) Start() {
+	if b.cfg.ShowTimestamps && b.cfg.StartTime.IsZero() {
+		b.cfg.StartTime = time.Now()
+	}
+	b.style.start()
+}
+
+func (b *base) System(msg *parser.StreamMessage, lineNum int) {
+	b.at(msg)
+	if !b.showMessage(msg) {
+		return
+	}
+	b.enterAgent(msg, lineNum)
+	b.style.system(msg, lineNum)
+}
+
+// Assistant and User track tool calls and subagents in hidden messages too,
+// so that the messages shown are labeled and nested correctly
+
+func (b *base) Assistant(msg *parser.StreamMessage, lineNum int) {
+	b.at(msg)
+	if msg.Message == nil {
+		return
+	}
+	b.trackAssistant(msg)
+	if msg = b.hideTools(msg); msg == nil {
+		return
+	}
+	b.trackCalls(msg, lineNum)
+	if !b.showMessage(msg) {
+		return
+	}
+	b.enterAgent(msg, lineNum)
+	b.style.assistant(msg, lineNum)
+}
+
+func (b *base) User(msg *parser.StreamMessage, lineNum int) {
+	b.at(msg)
+	if msg.Message == nil {
+		return
+	}
+	shown := b.hideResults(msg)
+	if shown != nil {
+		b.enterAgent(msg, lineNum)
+	}
+	b.trackUser(msg)
+	if shown != nil {
+		b.style.user(shown, lineNum)
+	}
+}
+
+func (b *base) StreamEvent(msg *parser.StreamMessage, lineNum int) {
+	b.at(msg)
+	if !b.showMessage(msg) {
+		return
+	}
+	b.enterAgent(msg, lineNum)
+	b.streamEvent(msg, lineNum)
+}
+
+func (b *base) Result(msg *parser.StreamMessage, lineNum int) {
+	b.at(msg)
+	if !b.showMessage(msg) {
+		return
+	}
+	b.enterAgent(msg, lineNum)
+	b.style.result(msg, lineNum)
+}
+
+func (b *base) Unknown(msg *parser.StreamMessage, lineNum int) {
+	b.at(msg)
+	if !b.showMessage(msg) {
+		return
+	}
+	b.enterAgent(msg, lineNum)
+	b.style.unknown(msg, lineNum)
+}
+
+func (b *base) Finish() {
+	b.endStream()
+	if calls := b.pendingCalls(); len(calls) > 0 {
+		b.setDepth(0)
+		b.style.unansweredCalls(calls)
+	}
+	b.style.finish()
+	if f, ok := b.out.(flusher); ok {
+		f.Flush()
+	}
+}
+
+// at records the time of the message about to be rendered. A message
+// without one, such as a summary line, keeps the time of the one before it.
+func (b *base) at(msg *parser.StreamMessage) {
+	t := msg.Time()
+	b.prevTime = b.msgTime
+	if t.IsZero() {
+		return
+	}
+	b.msgTime = t
+	if b.firstTime.IsZero() {
+		b.firstTime = t
+	}
+}
+
+// now returns the time of the message being rendered: the time it was
+// recorded for the messages of a session transcript or a tee, the current
+// time otherwise
+func (b *base) now() time.Time {
+	if !b.msgTime.IsZero() {
+		return b.msgTime
+	}
+	return b.clock()
+}
+
+// elapsed returns the time of the message being rendered relative to the
+// start, if ShowTimestamps is enabled. Recorded times are relative to the
+// first one, so a saved session shows when each message was sent, followed
+// by the time since the message before it.
+func (b *base) elapsed() string {
+	if !b.cfg.ShowTimestamps {
+		return ""
+	}
+	if b.msgTime.IsZero() {
+		return FormatElapsed(b.cfg)
+	}
+	elapsed := formatElapsed(b.msgTime.Sub(b.firstTime))
+	if !b.prevTime.IsZero() {
+		elapsed += " Δ" + formatDuration(b.msgTime.Sub(b.prevTime))
+	}
+	return elapsed
+}
+
+// start and finish write nothing; styles that wrap the output in a document override them
+func (b *base) start()  {}
+func (b *base) finish() {}
+
+// unknown ignores messages of unrecognized types; styles may override it
+func (b *base) unknown(msg *parser.StreamMessage, lineNum int) {}
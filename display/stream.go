@@ -0,0 +1,145 @@
+package display
+
+import (
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/ariel-frischer/claude-clean/parser"
+)
+
+// textStreamer is implemented by each style to render assistant text
+// incrementally as stream_event deltas arrive
+type textStreamer interface {
+	beginText(lineNum int)
+	writeText(text string)
+	endText()
+}
+
+// stream tracks assistant text rendered from stream_event messages so that
+// the final assistant message does not print it a second time
+type stream struct {
+	partial  *parser.PartialMessage
+	open     bool                // a text block is being written
+	index    int                 // index of the open text block
+	lineOpen bool                // the current output line has been started
+	chars    int                 // characters written for the open block
+	rendered map[string][]string // message ID -> text blocks already rendered
+}
+
+// streamEvent applies a stream_event message and renders its text deltas
+func (b *base) streamEvent(msg *parser.StreamMessage, lineNum int) {
+	ev := msg.Event
+	if ev == nil {
+		return
+	}
+
+	s := &b.stream
+	if ev.Type == "message_start" || s.partial == nil {
+		b.endStream()
+		s.partial = parser.NewPartialMessage(ev)
+	}
+
+	block := s.partial.Apply(ev)
+	if block == nil || block.Type != "text" {
+		return
+	}
+
+	switch ev.Type {
+	case "content_block_start":
+		b.endStream()
+		s.open, s.index, s.lineOpen, s.chars = true, ev.Index, false, 0
+		b.style.beginText(lineNum)
+		if block.Text != "" {
+			b.style.writeText(block.Text)
+			b.syncText()
+		}
+	case "content_block_delta":
+		if s.open && s.index == ev.Index && ev.Delta.Text != "" {
+			b.style.writeText(ev.Delta.Text)
+			b.syncText()
+		}
+	case "content_block_stop":
+		if s.open && s.index == ev.Index {
+			b.endStream()
+		}
+	}
+}
+
+// syncText writes streamed text that the output holds back until its line
+// ends, so it shows up as it arrives
+func (b *base) syncText() {
+	if s, ok := b.out.(syncer); ok {
+		s.Sync()
+	}
+}
+
+// endStream closes the text block being streamed, if any, and remembers its text
+func (b *base) endStream() {
+	s := &b.stream
+	if !s.open {
+		return
+	}
+	b.style.endText()
+	s.open = false
+
+	if s.rendered == nil {
+		s.rendered = make(map[string][]string)
+	}
+	text := strings.TrimSpace(s.partial.Blocks[s.index].Text)
+	s.rendered[s.partial.ID] = append(s.rendered[s.partial.ID], text)
+}
+
+// unstreamed returns the content blocks of an assistant message, leaving out
+// text blocks that were already rendered from stream events
+func (b *base) unstreamed(msg *parser.StreamMessage) []parser.ContentBlock {
+	s := &b.stream
+	id := msg.Message.ID
+	if s.open && s.partial.ID == id {
+		b.endStream()
+	}
+
+	rendered := s.rendered[id]
+	if len(rendered) == 0 {
+		return msg.Message.Content
+	}
+
+	var content []parser.ContentBlock
+	for _, block := range msg.Message.Content {
+		if block.Type == "text" {
+			if i := slices.Index(rendered, strings.TrimSpace(block.Text)); i >= 0 {
+				rendered = append(rendered[:i], rendered[i+1:]...)
+				continue
+			}
+		}
+		content = append(content, block)
+	}
+
+	if len(rendered) == 0 {
+		delete(s.rendered, id)
+	} else {
+		s.rendered[id] = rendered
+	}
+	return content
+}
+
+// writeLines writes streamed text to w, calling prefix before each new line of output
+func (s *stream) writeLines(w io.Writer, text string, prefix func(), write func(string)) {
+	for i, part := range strings.Split(text, "\n") {
+		if i > 0 {
+			if !s.lineOpen {
+				prefix()
+			}
+			io.WriteString(w, "\n")
+			s.lineOpen = false
+		}
+		if part == "" {
+			continue
+		}
+		if !s.lineOpen {
+			prefix()
+			s.lineOpen = true
+		}
+		write(part)
+	}
+}
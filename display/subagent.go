@@ -0,0 +1,294 @@
+package display
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ariel-frischer/claude-clean/parser"
+)
+
+// subagentIndent is the indentation added for each level of subagent nesting
+// in the terminal styles
+const subagentIndent = "    "
+
+// subagent tracks a Task tool call and the messages of the subagent it spawned
+type subagent struct {
+	ID          string
+	Type        string // subagent_type input of the Task call
+	Description string
+	Prompt      string
+	Depth       int // nesting level of the subagent's messages (1 for a direct subagent)
+	Turns       int
+	ToolCalls   int
+	Errors      int
+
+	started   bool
+	done      bool
+	messageID string // ID of the last assistant message, to count turns
+}
+
+// agents tracks the subagents seen so far and which one is being rendered
+type agents struct {
+	tasks   map[string]*subagent // Task tool_use ID -> subagent
+	order   []*subagent          // subagents in the order of their Task calls
+	chains  map[string]*subagent // transcript message UUID -> subagent, for sidechain messages
+	current *subagent
+}
+
+// isTaskTool reports whether a tool call spawns a subagent
+func isTaskTool(name string) bool {
+	return name == "Task" || name == "Agent"
+}
+
+// enterAgent switches the output to the subagent that produced msg,
+// printing a header when the subagent starts or resumes
+func (b *base) enterAgent(msg *parser.StreamMessage, lineNum int) {
+	a := b.agentOf(msg)
+	if a != b.agents.current {
+		b.endStream()
+		if a != nil {
+			b.setDepth(a.Depth - 1)
+			b.style.subagentStart(a, lineNum)
+			a.started = true
+		}
+		b.agents.current = a
+	}
+
+	if a != nil {
+		b.setDepth(a.Depth)
+	} else {
+		b.setDepth(0)
+	}
+}
+
+// agentOf returns the subagent that produced msg, or nil for the main agent
+func (b *base) agentOf(msg *parser.StreamMessage) *subagent {
+	switch {
+	case msg.ParentToolUseID != "":
+		return b.agents.lookup(msg.ParentToolUseID)
+	case msg.IsSidechain:
+		return b.agents.sidechain(msg)
+	}
+	return nil
+}
+
+// sidechain returns the subagent of a sidechain message of a session
+// transcript, which has no parent_tool_use_id. A message belongs to the
+// subagent of the message it follows. The first message of a sidechain is
+// the subagent's prompt, and belongs to the unfinished Task call with that
+// prompt, or else the latest one. A sidechain without a Task call, as in the
+// transcript of a subagent alone, gets a subagent of its own.
+func (as *agents) sidechain(msg *parser.StreamMessage) *subagent {
+	if a, ok := as.chains[msg.UUID]; ok {
+		return a
+	}
+	a, ok := as.chains[msg.ParentUUID]
+	if !ok || msg.ParentUUID == "" {
+		a = as.task(promptText(msg))
+	}
+	if msg.UUID != "" {
+		if as.chains == nil {
+			as.chains = make(map[string]*subagent)
+		}
+		as.chains[msg.UUID] = a
+	}
+	return a
+}
+
+// task returns the unfinished subagent with a prompt, or else the latest
+// unfinished one
+func (as *agents) task(prompt string) *subagent {
+	var latest *subagent
+	for i := len(as.order) - 1; i >= 0; i-- {
+		a := as.order[i]
+		if a.done {
+			continue
+		}
+		if prompt != "" && a.Prompt == prompt {
+			return a
+		}
+		if latest == nil {
+			latest = a
+		}
+	}
+	if latest == nil {
+		latest = as.lookup("sidechain")
+	}
+	return latest
+}
+
+// promptText returns the text of a user message
+func promptText(msg *parser.StreamMessage) string {
+	if msg.Type != "user" || msg.Message == nil {
+		return ""
+	}
+	for _, block := range msg.Message.Content {
+		if block.Type == "text" {
+			return strings.TrimSpace(block.Text)
+		}
+	}
+	return ""
+}
+
+// trackAssistant registers Task calls and counts subagent turns and tool calls
+func (b *base) trackAssistant(msg *parser.StreamMessage) {
+	current := b.agentOf(msg)
+	depth := 0
+	if current != nil {
+		depth = current.Depth
+		if msg.Message.ID == "" || msg.Message.ID != current.messageID {
+			current.Turns++
+			current.messageID = msg.Message.ID
+		}
+	}
+
+	for _, block := range msg.Message.Content {
+		if block.Type != "tool_use" {
+			continue
+		}
+		if current != nil {
+			current.ToolCalls++
+		}
+		if isTaskTool(block.Name) {
+			a := b.agents.lookup(block.ID)
+			a.Type, _ = block.Input["subagent_type"].(string)
+			a.Description, _ = block.Input["description"].(string)
+			a.Prompt, _ = block.Input["prompt"].(string)
+			a.Prompt = strings.TrimSpace(a.Prompt)
+			a.Depth = depth + 1
+		}
+	}
+}
+
+// trackUser counts subagent errors and prints a subagent's summary when its Task returns
+func (b *base) trackUser(msg *parser.StreamMessage) {
+	current := b.agentOf(msg)
+	for _, block := range msg.Message.Content {
+		if block.Type != "tool_result" {
+			continue
+		}
+		if block.IsError && current != nil {
+			current.Errors++
+		}
+
+		a, ok := b.agents.tasks[block.ToolUseID]
+		if !ok || !a.started || a.done {
+			continue
+		}
+		a.done = true
+		b.endStream()
+		w := b.w
+		b.setDepth(a.Depth - 1)
+		b.style.subagentEnd(a)
+		b.w = w
+	}
+}
+
+// lookup returns the subagent for a Task tool_use ID, creating it if needed
+func (as *agents) lookup(id string) *subagent {
+	if as.tasks == nil {
+		as.tasks = make(map[string]*subagent)
+	}
+	a, ok := as.tasks[id]
+	if !ok {
+		a = &subagent{ID: id, Depth: 1}
+		as.tasks[id] = a
+		as.order = append(as.order, a)
+	}
+	return a
+}
+
+// setDepth indents all further output by depth levels of subagent nesting
+func (b *base) setDepth(depth int) {
+	if depth <= 0 || b.indent == "" {
+		b.w = b.out
+		return
+	}
+	prefix := strings.Repeat(b.indent, depth)
+	if iw, ok := b.w.(*indentWriter); ok && iw.prefix == prefix {
+		return
+	}
+	b.w = &indentWriter{w: b.out, prefix: prefix, lineStart: true}
+}
+
+// depth returns the nesting level of the subagent being rendered, 0 for the main agent
+func (b *base) depth() int {
+	if b.agents.current == nil {
+		return 0
+	}
+	return b.agents.current.Depth
+}
+
+// subagentName returns the display name of a subagent
+func subagentName(a *subagent) string {
+	if a.Type == "" {
+		return "subagent"
+	}
+	return a.Type
+}
+
+// subagentSummary returns a one-line summary of a finished subagent's activity
+func subagentSummary(a *subagent) string {
+	return fmt.Sprintf("%s, %s, %s",
+		plural(a.Turns, "turn"), plural(a.ToolCalls, "tool call"), plural(a.Errors, "error"))
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// indentWriter prefixes every line written through it. The prefix is written
+// lazily before the first visible character of a line, so color escape codes
+// at the end of the previous line do not leave a dangling prefix behind.
+type indentWriter struct {
+	w         io.Writer
+	prefix    string
+	lineStart bool
+}
+
+func (iw *indentWriter) Write(p []byte) (int, error) {
+	total := len(p)
+	for len(p) > 0 {
+		var n int
+		switch {
+		case iw.lineStart && p[0] == '\x1b':
+			// Pass escape sequences through without starting the line
+			n = escapeLen(p)
+		case iw.lineStart:
+			if _, err := io.WriteString(iw.w, iw.prefix); err != nil {
+				return 0, err
+			}
+			iw.lineStart = false
+			continue
+		default:
+			n = len(p)
+			if i := bytes.IndexByte(p, '\n'); i >= 0 {
+				n = i + 1
+				iw.lineStart = true
+			}
+		}
+		if _, err := iw.w.Write(p[:n]); err != nil {
+			return 0, err
+		}
+		p = p[n:]
+	}
+	return total, nil
+}
+
+// escapeLen returns the length of the ANSI escape sequence at the start of p
+func escapeLen(p []byte) int {
+	if len(p) < 2 || p[1] != '[' {
+		return 1
+	}
+	for i := 2; i < len(p); i++ {
+		if p[i] >= 0x40 && p[i] <= 0x7e {
+			return i + 1
+		}
+	}
+	return len(p)
+}
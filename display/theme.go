@@ -0,0 +1,170 @@
+package display
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// Role is the part a color plays in the terminal styles
+type Role string
+
+const (
+	RoleSystem     Role = "system"      // system messages, markdown headings
+	RoleAssistant  Role = "assistant"   // assistant messages, added lines
+	RoleTool       Role = "tool"        // tool calls, inline code
+	RoleToolResult Role = "tool_result" // tool results, code keywords
+	RoleError      Role = "error"       // errors, removed lines
+	RoleResult     Role = "result"      // the final result, links
+	RoleMuted      Role = "muted"       // timings, truncation notes, thinking
+	RoleText       Role = "text"        // message text
+)
+
+// Roles lists every role a theme can color
+var Roles = []Role{RoleSystem, RoleAssistant, RoleTool, RoleToolResult, RoleError, RoleResult, RoleMuted, RoleText}
+
+// Theme maps roles to color specs. A spec is a color name such as "cyan" or
+// "bright-red", a 256-color number such as "208", or a truecolor "#rrggbb",
+// optionally preceded by attributes: "bold", "italic", "underline", "faint".
+type Theme map[Role]string
+
+// DefaultTheme is the name of the theme used unless another is selected
+const DefaultTheme = "dark"
+
+// Themes are the built-in themes, by name
+var Themes = map[string]Theme{
+	"dark": {
+		RoleSystem: "cyan", RoleAssistant: "green", RoleTool: "yellow", RoleToolResult: "magenta",
+		RoleError: "red", RoleResult: "blue", RoleMuted: "bright-black", RoleText: "white",
+	},
+	"light": {
+		RoleSystem: "25", RoleAssistant: "28", RoleTool: "130", RoleToolResult: "90",
+		RoleError: "160", RoleResult: "24", RoleMuted: "244", RoleText: "235",
+	},
+	"high-contrast": {
+		RoleSystem: "bright-cyan", RoleAssistant: "bright-green", RoleTool: "bright-yellow", RoleToolResult: "bright-magenta",
+		RoleError: "bright-red", RoleResult: "bright-blue", RoleMuted: "white", RoleText: "bright-white",
+	},
+	"solarized": {
+		RoleSystem: "#2aa198", RoleAssistant: "#859900", RoleTool: "#b58900", RoleToolResult: "#6c71c4",
+		RoleError: "#dc322f", RoleResult: "#268bd2", RoleMuted: "#586e75", RoleText: "#93a1a1",
+	},
+}
+
+// ThemeNames returns the names of the built-in themes in alphabetical order
+func ThemeNames() []string {
+	names := make([]string, 0, len(Themes))
+	for name := range Themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// palette holds the attributes of each role in the current theme
+var palette map[Role][]color.Attribute
+
+var colorNames = map[string]color.Attribute{
+	"black": color.FgBlack, "red": color.FgRed, "green": color.FgGreen, "yellow": color.FgYellow,
+	"blue": color.FgBlue, "magenta": color.FgMagenta, "cyan": color.FgCyan, "white": color.FgWhite,
+	"bright-black": color.FgHiBlack, "bright-red": color.FgHiRed, "bright-green": color.FgHiGreen,
+	"bright-yellow": color.FgHiYellow, "bright-blue": color.FgHiBlue, "bright-magenta": color.FgHiMagenta,
+	"bright-cyan": color.FgHiCyan, "bright-white": color.FgHiWhite,
+	"gray": color.FgHiBlack, "grey": color.FgHiBlack,
+}
+
+var attributeNames = map[string]color.Attribute{
+	"bold": color.Bold, "faint": color.Faint, "italic": color.Italic, "underline": color.Underline,
+}
+
+// ParseColor returns the terminal attributes of a color spec, as described for Theme
+func ParseColor(spec string) ([]color.Attribute, error) {
+	var attrs []color.Attribute
+	fields := strings.Fields(strings.ToLower(spec))
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty color")
+	}
+	for i, f := range fields {
+		if a, ok := attributeNames[f]; ok && i < len(fields)-1 {
+			attrs = append(attrs, a)
+			continue
+		}
+		if i < len(fields)-1 {
+			return nil, fmt.Errorf("unknown attribute %q in color %q", f, spec)
+		}
+
+		fg, err := parseForeground(f)
+		if err != nil {
+			return nil, fmt.Errorf("color %q: %w", spec, err)
+		}
+		attrs = append(fg, attrs...)
+	}
+	return attrs, nil
+}
+
+// parseForeground returns the attributes of a color name, number or hex code
+func parseForeground(s string) ([]color.Attribute, error) {
+	if a, ok := colorNames[s]; ok {
+		return []color.Attribute{a}, nil
+	}
+	if hex, ok := strings.CutPrefix(s, "#"); ok {
+		v, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil || len(hex) != 6 {
+			return nil, fmt.Errorf("invalid hex color")
+		}
+		return []color.Attribute{38, 2, color.Attribute(v >> 16), color.Attribute(v >> 8 & 0xff), color.Attribute(v & 0xff)}, nil
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 0 || n > 255 {
+			return nil, fmt.Errorf("256-color number out of range")
+		}
+		return []color.Attribute{38, 5, color.Attribute(n)}, nil
+	}
+	return nil, fmt.Errorf("unknown color")
+}
+
+// SetTheme sets the colors of the terminal styles, and of the exported color
+// variables, for all renderers. Roles missing from the theme keep the
+// colors of DefaultTheme.
+func SetTheme(t Theme) error {
+	p := make(map[Role][]color.Attribute, len(Roles))
+	for _, role := range Roles {
+		spec, ok := t[role]
+		if !ok {
+			spec = Themes[DefaultTheme][role]
+		}
+		attrs, err := ParseColor(spec)
+		if err != nil {
+			return fmt.Errorf("%s: %w", role, err)
+		}
+		p[role] = attrs
+	}
+	for role := range t {
+		if _, ok := p[role]; !ok {
+			return fmt.Errorf("unknown role %q", role)
+		}
+	}
+
+	palette = p
+	roleColor := func(role Role, more ...color.Attribute) *color.Color {
+		return color.New(withAttrs(p[role], more...)...)
+	}
+	BoldCyan, Cyan = roleColor(RoleSystem, color.Bold), roleColor(RoleSystem)
+	BoldGreen, Green = roleColor(RoleAssistant, color.Bold), roleColor(RoleAssistant)
+	BoldYellow, Yellow = roleColor(RoleTool, color.Bold), roleColor(RoleTool)
+	BoldMagenta, Magenta = roleColor(RoleToolResult, color.Bold), roleColor(RoleToolResult)
+	BoldRed, Red = roleColor(RoleError, color.Bold), roleColor(RoleError)
+	BoldBlue, Blue = roleColor(RoleResult, color.Bold), roleColor(RoleResult)
+	Gray = roleColor(RoleMuted)
+	White = roleColor(RoleText)
+	return nil
+}
+
+func init() {
+	if err := SetTheme(Themes[DefaultTheme]); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,80 @@
+package display
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected []color.Attribute
+		err      string
+	}{
+		{"cyan", []color.Attribute{color.FgCyan}, ""},
+		{"Bright-Red", []color.Attribute{color.FgHiRed}, ""},
+		{"bold underline green", []color.Attribute{color.FgGreen, color.Bold, color.Underline}, ""},
+		{"208", []color.Attribute{38, 5, 208}, ""},
+		{"#2aa198", []color.Attribute{38, 2, 42, 161, 152}, ""},
+		{"", nil, "empty color"},
+		{"pink", nil, "unknown color"},
+		{"256", nil, "out of range"},
+		{"#12345", nil, "invalid hex"},
+		{"shiny red", nil, `unknown attribute "shiny"`},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseColor(tt.spec)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ParseColor(%q) error %v, want %q", tt.spec, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("ParseColor(%q) = %v, %v, want %v", tt.spec, got, err, tt.expected)
+		}
+	}
+}
+
+func TestSetTheme(t *testing.T) {
+	defer SetTheme(Themes[DefaultTheme])
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+
+	for _, name := range ThemeNames() {
+		if err := SetTheme(Themes[name]); err != nil {
+			t.Errorf("theme %s: %v", name, err)
+		}
+	}
+
+	if err := SetTheme(Theme{RoleSystem: "bold 33", RoleText: "#000000"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := BoldCyan.Sprint("x"); !strings.HasPrefix(got, "\x1b[38;5;33;1;1mx") {
+		t.Errorf("BoldCyan = %q, want the system color", got)
+	}
+	if got := White.Sprint("x"); !strings.HasPrefix(got, "\x1b[38;2;0;0;0mx") {
+		t.Errorf("White = %q, want the text color", got)
+	}
+	if got := Red.Sprint("x"); got != color.New(color.FgRed).Sprint("x") {
+		t.Errorf("Red = %q, want the default theme's error color", got)
+	}
+
+	// Markdown follows the theme as well
+	var lines []string
+	writeMarkdown("plain `code`", 80, func(line string) { lines = append(lines, line) })
+	if !strings.Contains(lines[0], "\x1b[38;2;0;0;0mplain ") {
+		t.Errorf("markdown text not in the theme's text color: %q", lines[0])
+	}
+
+	for _, bad := range []Theme{{RoleError: "nope"}, {Role("header"): "red"}} {
+		if err := SetTheme(bad); err == nil {
+			t.Errorf("SetTheme(%v) succeeded, want an error", bad)
+		}
+	}
+}
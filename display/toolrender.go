@@ -0,0 +1,370 @@
+package display
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ToolLineKind tells the output styles how to present a line of tool input or output
+type ToolLineKind int
+
+const (
+	ToolLineText    ToolLineKind = iota // regular content
+	ToolLineMuted                       // secondary details, such as a description or options
+	ToolLineHeader                      // the subject of the call, such as a file path or URL
+	ToolLineAdded                       // a line added by a file change
+	ToolLineRemoved                     // a line removed by a file change
+)
+
+// ToolLine is a line of a tool call's input or result
+type ToolLine struct {
+	Kind   ToolLineKind
+	Text   string
+	Gutter string // shown dimmed before the text, such as a line number
+}
+
+// ToolRenderer formats the calls and results of a tool for every output
+// style. Any function may be nil to keep the generic rendering of that part.
+type ToolRenderer struct {
+	// Summary returns a short single-line description of the input, shown in
+	// tool result headers and in the compact style
+	Summary func(input map[string]interface{}) string
+	// Input returns the lines shown for a tool call
+	Input func(input map[string]interface{}) []ToolLine
+	// Result returns the lines shown for a successful tool result, given the
+	// input of its call and the result text
+	Result func(input map[string]interface{}, content string) []ToolLine
+}
+
+// toolRegistry holds the tool renderers by exact name and by glob pattern
+var toolRegistry = struct {
+	sync.RWMutex
+	names map[string]ToolRenderer
+	globs []toolGlob // in registration order
+}{names: make(map[string]ToolRenderer)}
+
+type toolGlob struct {
+	pattern  string
+	renderer ToolRenderer
+}
+
+// RegisterTool sets the renderer for tools matching pattern, which is either a
+// tool name or a glob such as "mcp__github__*" (see path.Match). Exact names
+// take precedence over globs, and among globs the latest registration wins,
+// so registering a renderer replaces the built-in one for the same tools.
+func RegisterTool(pattern string, r ToolRenderer) {
+	toolRegistry.Lock()
+	defer toolRegistry.Unlock()
+
+	if !strings.ContainsAny(pattern, `*?[\`) {
+		toolRegistry.names[pattern] = r
+		return
+	}
+	for i, g := range toolRegistry.globs {
+		if g.pattern == pattern {
+			toolRegistry.globs = append(toolRegistry.globs[:i], toolRegistry.globs[i+1:]...)
+			break
+		}
+	}
+	toolRegistry.globs = append(toolRegistry.globs, toolGlob{pattern, r})
+}
+
+// LookupTool returns the renderer registered for a tool name
+func LookupTool(name string) (ToolRenderer, bool) {
+	toolRegistry.RLock()
+	defer toolRegistry.RUnlock()
+
+	if r, ok := toolRegistry.names[name]; ok {
+		return r, true
+	}
+	for i := len(toolRegistry.globs) - 1; i >= 0; i-- {
+		if ok, _ := path.Match(toolRegistry.globs[i].pattern, name); ok {
+			return toolRegistry.globs[i].renderer, true
+		}
+	}
+	return ToolRenderer{}, false
+}
+
+// inputLines returns the lines of a tool call's input from its registered
+// renderer, or nil if the tool has no input renderer
+func inputLines(name string, input map[string]interface{}) []ToolLine {
+	if r, ok := LookupTool(name); ok && r.Input != nil {
+		return r.Input(input)
+	}
+	return nil
+}
+
+// resultLines returns the lines of a successful tool result, from the
+// renderer of the call when it has one and otherwise as plain text
+func resultLines(call *toolCall, content string) []ToolLine {
+	if call != nil {
+		if r, ok := LookupTool(call.Name); ok && r.Result != nil {
+			return r.Result(call.Input, content)
+		}
+	}
+	return textLines(ToolLineText, content)
+}
+
+// textLines splits text into lines of the same kind
+func textLines(kind ToolLineKind, text string) []ToolLine {
+	var lines []ToolLine
+	for _, l := range strings.Split(text, "\n") {
+		lines = append(lines, ToolLine{Kind: kind, Text: l})
+	}
+	return lines
+}
+
+func init() {
+	RegisterTool("Bash", ToolRenderer{Input: bashInput})
+	RegisterTool("Read", ToolRenderer{Summary: readSummary, Input: readInput})
+	RegisterTool("Grep", ToolRenderer{Summary: grepSummary, Input: grepInput})
+	RegisterTool("Glob", ToolRenderer{Summary: globSummary, Input: globInput, Result: fileListResult})
+	RegisterTool("WebFetch", ToolRenderer{Input: webFetchInput})
+	RegisterTool("WebSearch", ToolRenderer{Input: webSearchInput})
+	RegisterTool("Task", ToolRenderer{Input: taskInput})
+	RegisterTool("Agent", ToolRenderer{Input: taskInput})
+	RegisterTool("NotebookEdit", ToolRenderer{Input: notebookEditInput})
+	RegisterTool("Edit", ToolRenderer{Input: editInput})
+	RegisterTool("MultiEdit", ToolRenderer{Input: editInput})
+	RegisterTool("Write", ToolRenderer{Input: writeInput})
+	RegisterTool("mcp__*", ToolRenderer{Summary: mcpSummary, Input: mcpInput})
+}
+
+// inputString returns a string input value, or "" if it is missing or not a string
+func inputString(input map[string]interface{}, key string) string {
+	s, _ := input[key].(string)
+	return s
+}
+
+// inputInt returns a numeric input value, or 0 if it is missing or not a number
+func inputInt(input map[string]interface{}, key string) int {
+	n, _ := input[key].(float64)
+	return int(n)
+}
+
+// options formats the given input values as "key: value" pairs on one line,
+// skipping those that are not set
+func options(input map[string]interface{}, keys ...string) string {
+	var parts []string
+	for _, key := range keys {
+		switch v := input[key].(type) {
+		case nil:
+		case bool:
+			if v {
+				parts = append(parts, key)
+			}
+		case string:
+			if v != "" {
+				parts = append(parts, key+": "+v)
+			}
+		default:
+			parts = append(parts, fmt.Sprintf("%s: %s", key, compactJSON(v)))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// withOptions appends a muted line of options when any are set
+func withOptions(lines []ToolLine, input map[string]interface{}, keys ...string) []ToolLine {
+	if opts := options(input, keys...); opts != "" {
+		lines = append(lines, ToolLine{Kind: ToolLineMuted, Text: opts})
+	}
+	return lines
+}
+
+// compactJSON formats a value as single-line JSON
+func compactJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
+
+// bashInput shows the command as a shell prompt, preceded by its description
+func bashInput(input map[string]interface{}) []ToolLine {
+	var lines []ToolLine
+	if description := inputString(input, "description"); description != "" {
+		lines = append(lines, ToolLine{Kind: ToolLineMuted, Text: "# " + description})
+	}
+	for i, l := range strings.Split(inputString(input, "command"), "\n") {
+		prompt := "$ "
+		if i > 0 {
+			prompt = "  "
+		}
+		lines = append(lines, ToolLine{Kind: ToolLineText, Text: prompt + l})
+	}
+	return withOptions(lines, input, "timeout", "run_in_background")
+}
+
+// readSummary shows the file path with the range of lines read, as path:offset-end
+func readSummary(input map[string]interface{}) string {
+	path := inputString(input, "file_path")
+	offset, limit := inputInt(input, "offset"), inputInt(input, "limit")
+	switch {
+	case limit > 0:
+		return fmt.Sprintf("%s:%d-%d", path, max(offset, 1), max(offset, 1)+limit-1)
+	case offset > 0:
+		return fmt.Sprintf("%s:%d-", path, offset)
+	}
+	return path
+}
+
+func readInput(input map[string]interface{}) []ToolLine {
+	return withOptions([]ToolLine{{Kind: ToolLineHeader, Text: readSummary(input)}}, input, "pages")
+}
+
+// grepSummary shows the pattern and where it is searched
+func grepSummary(input map[string]interface{}) string {
+	summary := inputString(input, "pattern")
+	for _, key := range []string{"glob", "type", "path"} {
+		if v := inputString(input, key); v != "" {
+			return summary + " in " + v
+		}
+	}
+	return summary
+}
+
+func grepInput(input map[string]interface{}) []ToolLine {
+	lines := []ToolLine{{Kind: ToolLineHeader, Text: "/" + inputString(input, "pattern") + "/"}}
+	return withOptions(lines, input,
+		"path", "glob", "type", "output_mode", "-i", "-n", "-A", "-B", "-C", "multiline", "head_limit")
+}
+
+// globSummary shows the pattern and the directory it is matched in
+func globSummary(input map[string]interface{}) string {
+	if dir := inputString(input, "path"); dir != "" {
+		return inputString(input, "pattern") + " in " + dir
+	}
+	return inputString(input, "pattern")
+}
+
+func globInput(input map[string]interface{}) []ToolLine {
+	return []ToolLine{{Kind: ToolLineHeader, Text: globSummary(input)}}
+}
+
+// fileListResult counts the files of a result listing one file per line
+func fileListResult(input map[string]interface{}, content string) []ToolLine {
+	files := splitLines(strings.TrimSpace(content))
+	if len(files) == 0 || strings.HasPrefix(content, "No files found") {
+		return textLines(ToolLineText, content)
+	}
+	lines := []ToolLine{{Kind: ToolLineMuted, Text: plural(len(files), "file")}}
+	for _, f := range files {
+		lines = append(lines, ToolLine{Kind: ToolLineText, Text: f})
+	}
+	return lines
+}
+
+func webFetchInput(input map[string]interface{}) []ToolLine {
+	lines := []ToolLine{{Kind: ToolLineHeader, Text: inputString(input, "url")}}
+	if prompt := inputString(input, "prompt"); prompt != "" {
+		lines = append(lines, textLines(ToolLineMuted, prompt)...)
+	}
+	return lines
+}
+
+func webSearchInput(input map[string]interface{}) []ToolLine {
+	lines := []ToolLine{{Kind: ToolLineHeader, Text: inputString(input, "query")}}
+	return withOptions(lines, input, "allowed_domains", "blocked_domains")
+}
+
+// taskInput shows the subagent type and description, followed by the prompt
+func taskInput(input map[string]interface{}) []ToolLine {
+	header := inputString(input, "description")
+	if agent := inputString(input, "subagent_type"); agent != "" {
+		header = agent + ": " + header
+	}
+	lines := []ToolLine{{Kind: ToolLineHeader, Text: header}}
+	if prompt := inputString(input, "prompt"); prompt != "" {
+		lines = append(lines, textLines(ToolLineText, strings.TrimSpace(prompt))...)
+	}
+	return withOptions(lines, input, "model", "run_in_background")
+}
+
+func notebookEditInput(input map[string]interface{}) []ToolLine {
+	lines := []ToolLine{{Kind: ToolLineHeader, Text: inputString(input, "notebook_path")}}
+	lines = withOptions(lines, input, "cell_id", "cell_type", "edit_mode")
+	if inputString(input, "edit_mode") == "delete" {
+		return lines
+	}
+	for _, l := range splitLines(inputString(input, "new_source")) {
+		lines = append(lines, ToolLine{Kind: ToolLineAdded, Text: "+" + l})
+	}
+	return lines
+}
+
+// diffKinds maps diff operations to line kinds
+var diffKinds = map[byte]ToolLineKind{
+	diffEqual: ToolLineText, diffRemove: ToolLineRemoved, diffAdd: ToolLineAdded, diffSkip: ToolLineMuted,
+}
+
+// editInput shows the replacements of an Edit or MultiEdit call as unified diffs
+func editInput(input map[string]interface{}) []ToolLine {
+	path, edits := toolEdits(input)
+	lines := []ToolLine{{Kind: ToolLineHeader, Text: path}}
+	for i, e := range edits {
+		if header := editHeader(i, len(edits), e); header != "" {
+			lines = append(lines, ToolLine{Kind: ToolLineMuted, Text: header})
+		}
+		for _, l := range lineDiff(e.Old, e.New) {
+			lines = append(lines, ToolLine{Kind: diffKinds[l.Op], Text: l.String()})
+		}
+	}
+	return lines
+}
+
+// writeInput shows the content of a Write call with line numbers
+func writeInput(input map[string]interface{}) []ToolLine {
+	content := splitLines(inputString(input, "content"))
+	lines := []ToolLine{{Kind: ToolLineHeader, Text: fmt.Sprintf("%s (%s)", inputString(input, "file_path"), plural(len(content), "line"))}}
+	width := len(strconv.Itoa(len(content)))
+	for i, l := range content {
+		lines = append(lines, ToolLine{Kind: ToolLineText, Text: l, Gutter: fmt.Sprintf("%*d", width, i+1)})
+	}
+	return lines
+}
+
+// mcpSummary shows the first string argument of an MCP tool call
+func mcpSummary(input map[string]interface{}) string {
+	for _, key := range sortedKeys(input) {
+		if v := inputString(input, key); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// mcpInput shows every argument of an MCP tool call, with non-string values as JSON
+func mcpInput(input map[string]interface{}) []ToolLine {
+	var lines []ToolLine
+	for _, key := range sortedKeys(input) {
+		v, ok := input[key].(string)
+		if !ok {
+			v = compactJSON(input[key])
+		}
+		if !strings.Contains(v, "\n") {
+			lines = append(lines, ToolLine{Kind: ToolLineText, Text: key + ": " + v})
+			continue
+		}
+		lines = append(lines, ToolLine{Kind: ToolLineText, Text: key + ":"})
+		for _, l := range strings.Split(v, "\n") {
+			lines = append(lines, ToolLine{Kind: ToolLineText, Text: "  " + l})
+		}
+	}
+	return lines
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
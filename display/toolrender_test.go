@@ -0,0 +1,132 @@
+package display
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ariel-frischer/claude-clean/parser"
+)
+
+func TestBuiltinToolSummaries(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    map[string]interface{}
+		expected string
+	}{
+		{"Read", map[string]interface{}{"file_path": "/repo/main.go"}, "/repo/main.go"},
+		{"Read", map[string]interface{}{"file_path": "/repo/main.go", "offset": 10.0, "limit": 50.0}, "/repo/main.go:10-59"},
+		{"Read", map[string]interface{}{"file_path": "/repo/main.go", "limit": 20.0}, "/repo/main.go:1-20"},
+		{"Read", map[string]interface{}{"file_path": "/repo/main.go", "offset": 100.0}, "/repo/main.go:100-"},
+		{"Grep", map[string]interface{}{"pattern": "func \\w+", "glob": "*.go"}, "func \\w+ in *.go"},
+		{"Glob", map[string]interface{}{"pattern": "**/*.go", "path": "/repo"}, "**/*.go in /repo"},
+		{"mcp__github__get_issue", map[string]interface{}{"repo": "cclean", "number": 12.0}, "cclean"},
+		{"Bash", map[string]interface{}{"command": "go test ./..."}, "go test ./..."},
+	}
+
+	for _, tt := range tests {
+		tool := &parser.ContentBlock{Type: "tool_use", Name: tt.name, Input: tt.input}
+		if got := ToolSummary(tool); got != tt.expected {
+			t.Errorf("ToolSummary(%s %v) = %q, want %q", tt.name, tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestBuiltinToolRenderers(t *testing.T) {
+	tools := []parser.ContentBlock{
+		{Type: "tool_use", ID: "t1", Name: "Bash", Input: map[string]interface{}{
+			"command": "go test ./...", "description": "Run the tests", "timeout": 60000.0,
+		}},
+		{Type: "tool_use", ID: "t2", Name: "Grep", Input: map[string]interface{}{
+			"pattern": "TODO", "glob": "*.go", "-i": true,
+		}},
+		{Type: "tool_use", ID: "t3", Name: "Glob", Input: map[string]interface{}{"pattern": "*.md"}},
+		{Type: "tool_use", ID: "t4", Name: "mcp__github__create_issue", Input: map[string]interface{}{
+			"title": "Broken build", "labels": []interface{}{"bug"},
+		}},
+	}
+	messages := []*parser.StreamMessage{
+		{Type: "assistant", Message: &parser.MessageContent{Content: tools}},
+		{Type: "user", Message: &parser.MessageContent{Content: []parser.ContentBlock{
+			{Type: "tool_result", ToolUseID: "t3", Content: "README.md\nUSAGE.md"},
+		}}},
+	}
+
+	var buf bytes.Buffer
+	r := NewRenderer(&buf, &Config{Style: StylePlain})
+	for i, msg := range messages {
+		Render(r, msg, i+1)
+	}
+	output := buf.String()
+
+	expected := []string{
+		"TOOL: Bash\n    # Run the tests\n    $ go test ./...\n    timeout: 60000\n",
+		"TOOL: Grep\n    /TODO/\n    glob: *.go, -i\n",
+		"TOOL: Glob\n    *.md\n",
+		"TOOL: mcp__github__create_issue\n    labels: [\"bug\"]\n    title: Broken build\n",
+		"TOOL RESULT: Glob (*.md) [0ms]\n  2 files\n  README.md\n  USAGE.md\n",
+	}
+	for _, want := range expected {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q\nGot:\n%s", want, output)
+		}
+	}
+}
+
+func TestRegisterTool(t *testing.T) {
+	RegisterTool("Deploy", ToolRenderer{
+		Summary: func(input map[string]interface{}) string { return "to " + inputString(input, "env") },
+		Input: func(input map[string]interface{}) []ToolLine {
+			return []ToolLine{{Kind: ToolLineHeader, Text: "deploying " + inputString(input, "service")}}
+		},
+		Result: func(input map[string]interface{}, content string) []ToolLine {
+			return []ToolLine{{Kind: ToolLineAdded, Text: "ok: " + content}}
+		},
+	})
+	RegisterTool("mcp__acme__*", ToolRenderer{
+		Input: func(input map[string]interface{}) []ToolLine {
+			return []ToolLine{{Text: "acme call"}}
+		},
+	})
+
+	// The more specific glob registered later wins over the built-in mcp__*
+	if r, ok := LookupTool("mcp__acme__ping"); !ok || r.Summary != nil {
+		t.Errorf("mcp__acme__ping should use the acme renderer")
+	}
+	if r, ok := LookupTool("mcp__other__ping"); !ok || r.Summary == nil {
+		t.Errorf("mcp__other__ping should use the built-in MCP renderer")
+	}
+	if _, ok := LookupTool("Unregistered"); ok {
+		t.Errorf("LookupTool found a renderer for an unregistered tool")
+	}
+
+	messages := []*parser.StreamMessage{
+		{Type: "assistant", Message: &parser.MessageContent{Content: []parser.ContentBlock{
+			{Type: "tool_use", ID: "t1", Name: "Deploy", Input: map[string]interface{}{"service": "api", "env": "staging"}},
+			{Type: "tool_use", ID: "t2", Name: "mcp__acme__ping", Input: map[string]interface{}{"host": "a"}},
+			{Type: "tool_use", ID: "t3", Name: "Unregistered", Input: map[string]interface{}{"key": "value"}},
+		}}},
+		{Type: "user", Message: &parser.MessageContent{Content: []parser.ContentBlock{
+			{Type: "tool_result", ToolUseID: "t1", Content: "v2 live"},
+		}}},
+	}
+
+	var buf bytes.Buffer
+	r := NewRenderer(&buf, &Config{Style: StylePlain})
+	for i, msg := range messages {
+		Render(r, msg, i+1)
+	}
+	output := buf.String()
+
+	expected := []string{
+		"TOOL: Deploy\n    deploying api\n",
+		"TOOL: mcp__acme__ping\n    acme call\n",
+		"TOOL: Unregistered\n  Input:\n    key: value\n",
+		"TOOL RESULT: Deploy (to staging) [0ms]\n  ok: v2 live\n",
+	}
+	for _, want := range expected {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q\nGot:\n%s", want, output)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package display
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ariel-frischer/claude-clean/parser"
+)
+
+// toolCall is a tool_use block, tracked until its tool_result arrives
+type toolCall struct {
+	ID      string
+	Name    string
+	Summary string // short description of the input, see ToolSummary
+	Input   map[string]interface{}
+	LineNum int
+	Start   time.Time
+	Latency time.Duration // time until the result arrived
+}
+
+// summaryKeys are the input keys that best describe a tool call, in order of preference
+var summaryKeys = []string{
+	"command", "file_path", "notebook_path", "path", "pattern", "url", "query", "description", "prompt",
+}
+
+// ToolSummary returns a short single-line description of a tool call's input,
+// such as the command of a Bash call or the file path of a Read call. Tools
+// with a registered Summary function use it; see RegisterTool.
+func ToolSummary(tool *parser.ContentBlock) string {
+	if todos, ok := tool.Input["todos"].([]interface{}); ok && tool.Name == "TodoWrite" {
+		return plural(len(todos), "todo")
+	}
+	if r, ok := LookupTool(tool.Name); ok && r.Summary != nil {
+		return shortSummary(r.Summary(tool.Input))
+	}
+	for _, key := range summaryKeys {
+		if v, ok := tool.Input[key].(string); ok && v != "" {
+			return shortSummary(v)
+		}
+	}
+	return ""
+}
+
+// shortSummary joins a summary into a single line of at most 60 characters
+func shortSummary(s string) string {
+	return truncateEnd(strings.Join(strings.Fields(s), " "), 60)
+}
+
+// trackCalls records the tool calls of an assistant message so results can be paired with them
+func (b *base) trackCalls(msg *parser.StreamMessage, lineNum int) {
+	for _, block := range msg.Message.Content {
+		if block.Type != "tool_use" || block.ID == "" {
+			continue
+		}
+		if b.calls == nil {
+			b.calls = make(map[string]*toolCall)
+		}
+		b.calls[block.ID] = &toolCall{
+			ID:      block.ID,
+			Name:    block.Name,
+			Summary: ToolSummary(&block),
+			Input:   block.Input,
+			LineNum: lineNum,
+			Start:   b.now(),
+		}
+	}
+}
+
+// takeCall returns the tool call answered by a tool_result with the given ID,
+// or nil if the call is unknown. The call is no longer pending afterwards.
+func (b *base) takeCall(toolUseID string) *toolCall {
+	call, ok := b.calls[toolUseID]
+	if !ok {
+		return nil
+	}
+	delete(b.calls, toolUseID)
+	call.Latency = b.now().Sub(call.Start)
+	return call
+}
+
+// pendingCalls returns the tool calls still waiting for a result, in stream order
+func (b *base) pendingCalls() []*toolCall {
+	calls := make([]*toolCall, 0, len(b.calls))
+	for _, call := range b.calls {
+		calls = append(calls, call)
+	}
+	sort.Slice(calls, func(i, j int) bool {
+		if calls[i].LineNum != calls[j].LineNum {
+			return calls[i].LineNum < calls[j].LineNum
+		}
+		return calls[i].ID < calls[j].ID
+	})
+	return calls
+}
+
+// callLabel describes a tool call as its name followed by its input summary
+func callLabel(call *toolCall) string {
+	if call.Summary == "" {
+		return call.Name
+	}
+	return fmt.Sprintf("%s (%s)", call.Name, call.Summary)
+}
+
+// formatDuration formats a duration compactly: 850ms, 12.3s or 2m5s
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+	secs := d.Seconds()
+	if secs < 60 {
+		return fmt.Sprintf("%.1fs", secs)
+	}
+	return fmt.Sprintf("%dm%ds", int(secs)/60, int(secs)%60)
+}
@@ -0,0 +1,130 @@
+package display
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// DefaultLineChars is the default length at which single lines of tool
+// results, such as minified JSON, are shortened
+const DefaultLineChars = 1000
+
+// Truncation is a policy for shortening long tool output. Its limits are used
+// as they are: with HeadLines and TailLines 0 only the number of lines is
+// shown, and LineChars 0 leaves long lines whole.
+type Truncation struct {
+	HeadLines      int  // lines shown at the start of long output
+	TailLines      int  // lines shown at the end of long output
+	LineChars      int  // characters shown of a long line, from its start and end
+	Full           bool // show the output in full
+	TruncateErrors bool // truncate error results too, which are otherwise shown in full
+}
+
+func (c *Config) lineChars() int {
+	if c.LineChars > 0 {
+		return c.LineChars
+	}
+	return DefaultLineChars
+}
+
+// inputTruncation returns the policy for tool inputs, which is the default one
+func (c *Config) inputTruncation() Truncation {
+	if c.Full {
+		return Truncation{Full: true}
+	}
+	return Truncation{HeadLines: c.headLines(), TailLines: c.tailLines(), LineChars: c.lineChars()}
+}
+
+// resultTruncation returns the policy for the results of a tool: the one in
+// TruncateTools for its name, or the first pattern matching it in
+// alphabetical order, or else the default one
+func (c *Config) resultTruncation(tool string, isError bool) Truncation {
+	if c.Full {
+		return Truncation{Full: true}
+	}
+
+	t, ok := c.TruncateTools[tool]
+	if !ok {
+		t = c.inputTruncation()
+		t.TruncateErrors = c.TruncateErrors
+		for _, pattern := range slices.Sorted(maps.Keys(c.TruncateTools)) {
+			if tool != "" && MatchTool([]string{pattern}, tool) {
+				t = c.TruncateTools[pattern]
+				break
+			}
+		}
+	}
+	if isError && !t.TruncateErrors {
+		t.Full = true
+	}
+	return t
+}
+
+// resultTruncation returns the policy for the result of a call, the default
+// one for a result without a known call
+func (b *base) resultTruncation(call *toolCall, isError bool) Truncation {
+	name := ""
+	if call != nil {
+		name = call.Name
+	}
+	return b.cfg.resultTruncation(name, isError)
+}
+
+// TruncateLongOutput returns the lines of a tool input or result that t
+// shows: the first and last lines of long output, with a muted line counting
+// those left out in between, and long lines shortened in the middle.
+func TruncateLongOutput(lines []ToolLine, t Truncation) []ToolLine {
+	if t.Full {
+		return lines
+	}
+
+	shown := make([]ToolLine, 0, min(len(lines), t.HeadLines+t.TailLines+1))
+	add := func(l ToolLine) {
+		l.Text = truncateChars(l.Text, t.LineChars)
+		shown = append(shown, l)
+	}
+	n := len(lines)
+	if n <= t.HeadLines+t.TailLines {
+		for _, l := range lines {
+			add(l)
+		}
+		return shown
+	}
+	for _, l := range lines[:t.HeadLines] {
+		add(l)
+	}
+	shown = append(shown, ToolLine{Kind: ToolLineMuted, Text: fmt.Sprintf("... (%d more lines) ...", n-t.HeadLines-t.TailLines)})
+	for _, l := range lines[n-t.TailLines:] {
+		add(l)
+	}
+	return shown
+}
+
+// truncateChars shortens a string longer than limit characters, keeping its
+// start and end. A limit of 0 leaves it whole.
+func truncateChars(s string, limit int) string {
+	if limit <= 0 || len(s) <= limit {
+		return s
+	}
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	head := limit * 2 / 3
+	return fmt.Sprintf("%s ... (%d chars omitted) ... %s", string(runes[:head]), len(runes)-limit, string(runes[len(runes)-(limit-head):]))
+}
+
+// truncateEnd shortens a string longer than limit characters to its start
+// followed by "...", limit characters in all. It cuts between characters, not
+// inside a multibyte one.
+func truncateEnd(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:max(limit-3, 0)]) + "..."
+}
@@ -0,0 +1,105 @@
+package display
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func numberedLines(n int) []ToolLine {
+	var lines []ToolLine
+	for i := 1; i <= n; i++ {
+		lines = append(lines, ToolLine{Kind: ToolLineText, Text: fmt.Sprintf("line %d", i)})
+	}
+	return lines
+}
+
+func TestTruncateLongOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []ToolLine
+		policy   Truncation
+		expected string
+	}{
+		{"short", numberedLines(3), Truncation{HeadLines: 2, TailLines: 1}, "line 1|line 2|line 3"},
+		{"long", numberedLines(5), Truncation{HeadLines: 2, TailLines: 1}, "line 1|line 2|... (2 more lines) ...|line 5"},
+		{"head only", numberedLines(5), Truncation{HeadLines: 1}, "line 1|... (4 more lines) ..."},
+		{"no lines", numberedLines(5), Truncation{}, "... (5 more lines) ..."},
+		{"full", numberedLines(5), Truncation{Full: true}, "line 1|line 2|line 3|line 4|line 5"},
+		{"long line", []ToolLine{{Text: "abcdefghijklmnopqrstuvwxyz"}}, Truncation{HeadLines: 1, LineChars: 9},
+			"abcdef ... (17 chars omitted) ... xyz"},
+		{"wide characters", []ToolLine{{Text: "αβγδεζηθικ"}}, Truncation{HeadLines: 1, LineChars: 6},
+			"αβγδ ... (4 chars omitted) ... ικ"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, l := range TruncateLongOutput(tt.lines, tt.policy) {
+				got = append(got, l.Text)
+			}
+			if strings.Join(got, "|") != tt.expected {
+				t.Errorf("got %q, want %q", strings.Join(got, "|"), tt.expected)
+			}
+		})
+	}
+}
+
+func TestResultTruncation(t *testing.T) {
+	cfg := &Config{
+		HeadLines: 3,
+		TruncateTools: map[string]Truncation{
+			"Read":   {HeadLines: 5},
+			"Bash":   {HeadLines: 20, TailLines: 20, TruncateErrors: true},
+			"mcp__*": {HeadLines: 1},
+		},
+	}
+	tests := []struct {
+		tool     string
+		isError  bool
+		expected Truncation
+	}{
+		{"Read", false, Truncation{HeadLines: 5}},
+		{"Read", true, Truncation{HeadLines: 5, Full: true}},
+		{"Bash", false, Truncation{HeadLines: 20, TailLines: 20, TruncateErrors: true}},
+		{"Bash", true, Truncation{HeadLines: 20, TailLines: 20, TruncateErrors: true}},
+		{"mcp__github__get_issue", false, Truncation{HeadLines: 1}},
+		{"Grep", false, Truncation{HeadLines: 3, TailLines: 20, LineChars: DefaultLineChars}},
+		{"Grep", true, Truncation{HeadLines: 3, TailLines: 20, LineChars: DefaultLineChars, Full: true}},
+		{"", false, Truncation{HeadLines: 3, TailLines: 20, LineChars: DefaultLineChars}},
+	}
+	for _, tt := range tests {
+		if got := cfg.resultTruncation(tt.tool, tt.isError); got != tt.expected {
+			t.Errorf("resultTruncation(%q, %v) = %+v, want %+v", tt.tool, tt.isError, got, tt.expected)
+		}
+	}
+
+	cfg.Full = true
+	if got := cfg.resultTruncation("Read", false); !got.Full {
+		t.Errorf("Full config gave %+v for Read", got)
+	}
+	if got := cfg.inputChars(); got != 0 {
+		t.Errorf("Full config limits inputs to %d chars", got)
+	}
+}
+
+func TestTruncateEnd(t *testing.T) {
+	tests := []struct {
+		in       string
+		limit    int
+		expected string
+	}{
+		{"short", 10, "short"},
+		{"exactly ten", 11, "exactly ten"},
+		{"a longer line of text", 10, "a longe..."},
+		{"日本語のファイル名です", 8, "日本語のフ..."},
+		{"héllo wörld", 11, "héllo wörld"},
+	}
+	for _, tt := range tests {
+		got := truncateEnd(tt.in, tt.limit)
+		if got != tt.expected || !utf8.ValidString(got) {
+			t.Errorf("truncateEnd(%q, %d) = %q, want %q", tt.in, tt.limit, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package display
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/rivo/uniseg"
+)
+
+// tabWidth is the distance between tab stops
+const tabWidth = 8
+
+// wrapWriter soft-wraps lines longer than width. A wrapped line continues
+// after the same border and indentation as the line it started on, with its
+// colors restored. Lines are held until their newline is written, since a
+// line can only be broken at a space once its end is known, unless Sync
+// writes them early for text that streams in.
+type wrapWriter struct {
+	w      io.Writer
+	width  int
+	line   []byte // the current line, without its newline
+	sent   string // the wrapped start of the line written by Sync
+	synced int    // bytes of line that sent covers
+}
+
+func (ww *wrapWriter) Write(p []byte) (int, error) {
+	total := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			ww.line = append(ww.line, p...)
+			break
+		}
+		ww.line = append(ww.line, p[:i]...)
+		if err := ww.endLine("\n"); err != nil {
+			return 0, err
+		}
+		p = p[i+1:]
+	}
+	return total, nil
+}
+
+// Sync writes the current line as far as it goes without waiting for its
+// newline, so that streamed text shows up as it arrives. Text written by
+// Sync is not moved by wrapping the rest of the line.
+func (ww *wrapWriter) Sync() error {
+	out, err := ww.wrapped()
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(ww.w, out[len(ww.sent):]); err != nil {
+		return err
+	}
+	ww.sent, ww.synced = out, len(ww.line)
+	return nil
+}
+
+// Flush writes the current line, if any, without waiting for its newline
+func (ww *wrapWriter) Flush() error {
+	if len(ww.line) == 0 {
+		return nil
+	}
+	return ww.endLine("")
+}
+
+// endLine writes the rest of the current line followed by end
+func (ww *wrapWriter) endLine(end string) error {
+	out, err := ww.wrapped()
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(ww.w, out[len(ww.sent):]+end)
+	ww.line, ww.sent, ww.synced = ww.line[:0], "", 0
+	return err
+}
+
+// wrapped returns the current line wrapped to width. If wrapping would move
+// text already written by Sync, the line is broken after that text instead
+// and the break is written.
+func (ww *wrapWriter) wrapped() (string, error) {
+	out := wrapLine(string(ww.line), ww.width)
+	if strings.HasPrefix(out, ww.sent) {
+		return out, nil
+	}
+
+	toks := lineTokens(string(ww.line[:ww.synced]))
+	lead, _, cont := continuation(toks, ww.width)
+	sgr := activeSGR(toks[lead:])
+	brk := "\n" + cont + sgr
+	if activeSGR(toks) != "" {
+		brk = "\x1b[0m" + brk
+	}
+	if _, err := io.WriteString(ww.w, brk); err != nil {
+		return "", err
+	}
+
+	rest := bytes.TrimLeft(ww.line[ww.synced:], " \t")
+	ww.line = append([]byte(cont+sgr), rest...)
+	ww.sent, ww.synced = cont+sgr, len(cont)+len(sgr)
+	return wrapLine(string(ww.line), ww.width), nil
+}
+
+// wrapToken is an escape sequence or a grapheme cluster of a line
+type wrapToken struct {
+	s     string
+	width int
+	esc   bool
+}
+
+func (t wrapToken) space() bool {
+	return t.s == " " || t.s == "\t"
+}
+
+// lineTokens splits a line into escape sequences and grapheme clusters, so
+// that wide characters and emoji sequences are measured as the terminal shows them
+func lineTokens(line string) []wrapToken {
+	var toks []wrapToken
+	state := -1
+	for len(line) > 0 {
+		if line[0] == '\x1b' {
+			n := escapeLen([]byte(line))
+			toks = append(toks, wrapToken{s: line[:n], esc: true})
+			line = line[n:]
+			state = -1
+			continue
+		}
+		var cluster string
+		var width int
+		cluster, line, width, state = uniseg.FirstGraphemeClusterInString(line, state)
+		toks = append(toks, wrapToken{s: cluster, width: width})
+	}
+	return toks
+}
+
+// leadTokens returns the number of tokens at the start of a line that make
+// up its box border and indentation, including the escape sequences that
+// color them but not those that color the text after them
+func leadTokens(toks []wrapToken) int {
+	lead, prev := 0, ""
+	for i, t := range toks {
+		switch {
+		case t.esc:
+			if lead == i && isReset(t.s) {
+				lead++
+			}
+			continue
+		case t.s == " ", t.s == "│", t.s == "┌", t.s == "└":
+		case t.s == "─" && (prev == "┌" || prev == "└" || prev == "─"):
+		default:
+			return lead
+		}
+		prev = t.s
+		lead = i + 1
+	}
+	return lead
+}
+
+// continuation returns the number and width of the tokens that lead a line,
+// and the border and indentation that start the rows it wraps onto. A lead
+// wider than half the width is not repeated.
+func continuation(toks []wrapToken, width int) (lead, leadWidth int, cont string) {
+	lead = leadTokens(toks)
+	var sb strings.Builder
+	for _, t := range toks[:lead] {
+		leadWidth += t.width
+		switch t.s {
+		case "┌", "└":
+			sb.WriteString("│")
+		case "─":
+			sb.WriteString(" ")
+		default:
+			sb.WriteString(t.s)
+		}
+	}
+	if leadWidth > width/2 {
+		return 0, 0, ""
+	}
+	return lead, leadWidth, sb.String()
+}
+
+// wrapLine breaks a line wider than width into several lines, at the last
+// space that fits or, in a word too long for a line of its own, at the width
+func wrapLine(line string, width int) string {
+	if len(line) <= width && !strings.Contains(line, "\t") {
+		return line // no wider than its bytes
+	}
+	toks := lineTokens(line)
+	lead, leadWidth, cont := continuation(toks, width)
+
+	var out strings.Builder
+	write := func(toks []wrapToken) {
+		for _, t := range toks {
+			out.WriteString(t.s)
+		}
+	}
+	write(toks[:lead])
+
+	rowStart, col, lastSpace := lead, leadWidth, -1
+	for i := lead; i < len(toks); {
+		t := toks[i]
+		w := t.width
+		if t.s == "\t" {
+			w = tabWidth - col%tabWidth
+		}
+		if t.esc || col+w <= width || col == leadWidth {
+			if t.space() {
+				lastSpace = i
+			}
+			col += w
+			i++
+			continue
+		}
+
+		// Break before this token, or at the last space of the row
+		end := i
+		if !t.space() && lastSpace > rowStart {
+			end = lastSpace
+		}
+		write(toks[rowStart:end])
+		i = end
+		for i < len(toks) && (toks[i].esc || toks[i].space()) {
+			if toks[i].esc {
+				out.WriteString(toks[i].s)
+			}
+			i++
+		}
+
+		if activeSGR(toks[:i]) != "" {
+			out.WriteString("\x1b[0m")
+		}
+		out.WriteString("\n" + cont + activeSGR(toks[lead:i]))
+		rowStart, col, lastSpace = i, leadWidth, -1
+	}
+	write(toks[rowStart:])
+	return out.String()
+}
+
+// activeSGR returns the color escape sequences still in effect after toks
+func activeSGR(toks []wrapToken) string {
+	var sgr []string
+	for _, t := range toks {
+		if !t.esc || !strings.HasSuffix(t.s, "m") {
+			continue
+		}
+		if isReset(t.s) {
+			sgr = sgr[:0]
+		} else {
+			sgr = append(sgr, t.s)
+		}
+	}
+	return strings.Join(sgr, "")
+}
+
+// isReset reports whether an escape sequence resets all colors and attributes
+func isReset(esc string) bool {
+	return esc == "\x1b[m" || esc == "\x1b[0m" || strings.HasPrefix(esc, "\x1b[0;")
+}
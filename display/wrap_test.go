@@ -0,0 +1,121 @@
+package display
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ariel-frischer/claude-clean/parser"
+	"github.com/mattn/go-runewidth"
+)
+
+func TestWrapLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		width    int
+		expected string
+	}{
+		{"fits", "│ short line", 20, "│ short line"},
+		{"at spaces", "│ one two three four five", 14, "│ one two\n│ three four\n│ five"},
+		{"long word", "│ abcdefghijklmnop", 10, "│ abcdefgh\n│ ijklmnop"},
+		{"box header", "┌─ TOOL: Bash (a long summary)", 20, "┌─ TOOL: Bash (a\n│  long summary)"},
+		{"indentation", "    │   key: some value here", 20, "    │   key: some\n    │   value here"},
+		{"wide runes", "│ 日本語のテキスト", 10, "│ 日本語の\n│ テキスト"},
+		{"emoji sequence", "│ ab 👨‍👩‍👧 cd", 7, "│ ab 👨‍👩‍👧\n│ cd"},
+		{"no border", "plain words that wrap", 10, "plain\nwords that\nwrap"},
+		{
+			"colors restored",
+			"\x1b[32m│ \x1b[0m\x1b[31mred text here\x1b[0m",
+			10,
+			"\x1b[32m│ \x1b[0m\x1b[31mred text\x1b[0m\n\x1b[32m│ \x1b[0m\x1b[31mhere\x1b[0m",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := wrapLine(tt.line, tt.width)
+			if got != tt.expected {
+				t.Errorf("wrapLine(%q, %d) =\n%q\nwant:\n%q", tt.line, tt.width, got, tt.expected)
+			}
+			for _, l := range strings.Split(stripANSI(got), "\n") {
+				if w := runewidth.StringWidth(l); w > tt.width {
+					t.Errorf("line %q is %d columns wide, want at most %d", l, w, tt.width)
+				}
+			}
+		})
+	}
+}
+
+func TestDefaultStyleWraps(t *testing.T) {
+	messages := []*parser.StreamMessage{
+		{Type: "assistant", Message: &parser.MessageContent{Content: []parser.ContentBlock{
+			{Type: "text", Text: "The quick brown fox jumps over the lazy dog"},
+			{Type: "tool_use", ID: "t1", Name: "Bash", Input: map[string]interface{}{"command": "ls"}},
+		}}},
+		{Type: "user", Message: &parser.MessageContent{Content: []parser.ContentBlock{
+			{Type: "tool_result", ToolUseID: "t1", Content: "alpha beta gamma delta epsilon"},
+		}}},
+	}
+
+	var buf bytes.Buffer
+	r := NewRenderer(&buf, &Config{Style: StyleDefault, Width: 20})
+	r.Start()
+	for i, msg := range messages {
+		Render(r, msg, i+1)
+	}
+	r.Finish()
+	output := stripANSI(buf.String())
+
+	for _, want := range []string{
+		"│ The quick brown\n│ fox jumps over the\n│ lazy dog\n",
+		"│ alpha beta gamma\n│ delta epsilon\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q\nGot:\n%s", want, output)
+		}
+	}
+}
+
+func TestWrapWriterSync(t *testing.T) {
+	tests := []struct {
+		name     string
+		writes   []string
+		width    int
+		expected []string // output after each write and Sync
+	}{
+		{
+			"wraps at spaces",
+			[]string{"│ one tw", "o three four", " five\n"},
+			14,
+			[]string{"│ one tw", "│ one two\n│ three four", "│ one two\n│ three four\n│ five\n"},
+		},
+		{
+			"keeps written text in place",
+			[]string{"│ abc de", "fghij", " kl\n"},
+			10,
+			[]string{"│ abc de", "│ abc de\n│ fghij", "│ abc de\n│ fghij kl\n"},
+		},
+		{
+			"breaks at a written space",
+			[]string{"│ abcdefgh", " ", " ij\n"},
+			10,
+			[]string{"│ abcdefgh", "│ abcdefgh\n│ ", "│ abcdefgh\n│ ij\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			ww := &wrapWriter{w: &buf, width: tt.width}
+			for i, s := range tt.writes {
+				io.WriteString(ww, s)
+				ww.Sync()
+				if got := buf.String(); got != tt.expected[i] {
+					t.Errorf("after %q:\n%q\nwant:\n%q", s, got, tt.expected[i])
+				}
+			}
+		})
+	}
+}
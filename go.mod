@@ -2,10 +2,14 @@ module github.com/ariel-frischer/claude-clean
 
 go 1.24.0
 
-require github.com/fatih/color v1.18.0
-
 require (
-	github.com/mattn/go-colorable v0.1.13 // indirect
-	github.com/mattn/go-isatty v0.0.20 // indirect
-	golang.org/x/sys v0.39.0 // indirect
+	github.com/BurntSushi/toml v1.5.0
+	github.com/fatih/color v1.18.0
+	github.com/mattn/go-isatty v0.0.20
+	github.com/mattn/go-runewidth v0.0.16
+	github.com/rivo/uniseg v0.4.7
+	golang.org/x/sys v0.41.0
+	golang.org/x/term v0.40.0
 )
+
+require github.com/mattn/go-colorable v0.1.13 // indirect
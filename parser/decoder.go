@@ -0,0 +1,154 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+)
+
+// Event is a decoded stream message along with its position in the input
+type Event struct {
+	Message *StreamMessage
+	Line    int    // 1-based line number in the input
+	Offset  int64  // byte offset of the start of the line
+	Raw     []byte // the raw line, without its trailing newline
+}
+
+// LineError reports a line that could not be decoded into a StreamMessage.
+// Decoding can continue past a LineError.
+type LineError struct {
+	Line   int
+	Offset int64
+	Err    error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// Item pairs an Event with the error encountered while decoding it
+type Item struct {
+	Event Event
+	Err   error
+}
+
+// Decoder reads stream-json messages line by line from an input stream
+type Decoder struct {
+	scanner    *bufio.Scanner
+	line       int
+	pos        int64 // byte offset of the next unread line
+	lineStart  int64 // byte offset of the current line
+	transcript bool
+}
+
+// NewDecoder returns a Decoder that reads from r
+func NewDecoder(r io.Reader) *Decoder {
+	d := &Decoder{scanner: bufio.NewScanner(r)}
+	d.scanner.Buffer(make([]byte, 0, 64*1024), MaxBufferCapacity)
+	d.scanner.Split(d.scanLines)
+	return d
+}
+
+// scanLines wraps bufio.ScanLines to track the byte offset of each line
+func (d *Decoder) scanLines(data []byte, atEOF bool) (int, []byte, error) {
+	advance, token, err := bufio.ScanLines(data, atEOF)
+	if advance > 0 {
+		d.lineStart = d.pos
+		d.pos += int64(advance)
+	}
+	return advance, token, err
+}
+
+// Next returns the next message in the input, skipping blank lines.
+// A line that is not a valid message returns its Event (with a nil Message)
+// and a *LineError. At the end of the input Next returns io.EOF; any other
+// error means the input could not be read and decoding should stop.
+func (d *Decoder) Next() (Event, error) {
+	for d.scanner.Scan() {
+		d.line++
+		line := d.scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		ev := Event{
+			Line:   d.line,
+			Offset: d.lineStart,
+			Raw:    append([]byte(nil), line...),
+		}
+
+		var msg StreamMessage
+		if err := json.Unmarshal(ev.Raw, &msg); err != nil {
+			return ev, &LineError{Line: ev.Line, Offset: ev.Offset, Err: err}
+		}
+		if isTranscriptLine(ev.Raw) {
+			if err := normalizeTranscript(&msg, ev.Raw); err != nil {
+				return ev, &LineError{Line: ev.Line, Offset: ev.Offset, Err: err}
+			}
+			d.transcript = true
+		}
+		ev.Message = &msg
+		return ev, nil
+	}
+
+	if err := d.scanner.Err(); err != nil {
+		return Event{}, err
+	}
+	return Event{}, io.EOF
+}
+
+// Transcript reports whether the input read so far is a session transcript
+// saved by Claude Code, rather than the stream-json output of claude -p.
+// Transcript messages are normalized into StreamMessages as they are decoded
+// and carry the time they were recorded in StreamMessage.Timestamp.
+func (d *Decoder) Transcript() bool {
+	return d.transcript
+}
+
+// All returns an iterator over the remaining messages in the input.
+// Per-line decode errors are yielded as *LineError values and iteration
+// continues; a read error is yielded last and ends the iteration.
+func (d *Decoder) All() iter.Seq2[Event, error] {
+	return func(yield func(Event, error) bool) {
+		for {
+			ev, err := d.Next()
+			if err == io.EOF {
+				return
+			}
+			if !yield(ev, err) {
+				return
+			}
+			var lineErr *LineError
+			if err != nil && !errors.As(err, &lineErr) {
+				return
+			}
+		}
+	}
+}
+
+// Events decodes the input in a new goroutine and sends each message on the
+// returned channel, following the same error rules as All. The channel is
+// closed at the end of the input, after a read error, or when ctx is done.
+func (d *Decoder) Events(ctx context.Context) <-chan Item {
+	ch := make(chan Item)
+	go func() {
+		defer close(ch)
+		for ev, err := range d.All() {
+			select {
+			case ch <- Item{Event: ev, Err: err}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
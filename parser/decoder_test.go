@@ -0,0 +1,160 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+const decoderInput = `{"type":"system","subtype":"init","model":"claude-sonnet-4-5"}
+
+not json
+{"type":"assistant","message":{"content":[{"type":"text","text":"hi"}]}}
+{"type":"result","num_turns":1}
+`
+
+func TestDecoderNext(t *testing.T) {
+	dec := NewDecoder(strings.NewReader(decoderInput))
+
+	tests := []struct {
+		line     int
+		offset   int64
+		msgType  string
+		parseErr bool
+	}{
+		{line: 1, offset: 0, msgType: "system"},
+		{line: 3, offset: 64, parseErr: true},
+		{line: 4, offset: 73, msgType: "assistant"},
+		{line: 5, offset: 146, msgType: "result"},
+	}
+
+	for _, tt := range tests {
+		ev, err := dec.Next()
+		if ev.Line != tt.line || ev.Offset != tt.offset {
+			t.Errorf("Next() position = line %d offset %d, want line %d offset %d",
+				ev.Line, ev.Offset, tt.line, tt.offset)
+		}
+
+		if tt.parseErr {
+			var lineErr *LineError
+			if !errors.As(err, &lineErr) {
+				t.Fatalf("Next() error = %v, want *LineError", err)
+			}
+			if lineErr.Line != tt.line || lineErr.Offset != tt.offset {
+				t.Errorf("LineError position = line %d offset %d, want line %d offset %d",
+					lineErr.Line, lineErr.Offset, tt.line, tt.offset)
+			}
+			if string(ev.Raw) != "not json" {
+				t.Errorf("Event.Raw = %q, want %q", ev.Raw, "not json")
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("Next() unexpected error: %v", err)
+		}
+		if ev.Message.Type != tt.msgType {
+			t.Errorf("Next() message type = %q, want %q", ev.Message.Type, tt.msgType)
+		}
+		if !strings.HasPrefix(decoderInput[ev.Offset:], string(ev.Raw)) {
+			t.Errorf("Event.Raw %q does not start at offset %d", ev.Raw, ev.Offset)
+		}
+	}
+
+	if _, err := dec.Next(); err != io.EOF {
+		t.Errorf("Next() at end of input = %v, want io.EOF", err)
+	}
+}
+
+func TestDecoderCRLF(t *testing.T) {
+	dec := NewDecoder(strings.NewReader("{\"type\":\"system\"}\r\n{\"type\":\"result\"}\r\n"))
+
+	var offsets []int64
+	for ev, err := range dec.All() {
+		if err != nil {
+			t.Fatalf("All() unexpected error: %v", err)
+		}
+		offsets = append(offsets, ev.Offset)
+	}
+
+	if len(offsets) != 2 || offsets[0] != 0 || offsets[1] != 19 {
+		t.Errorf("offsets = %v, want [0 19]", offsets)
+	}
+}
+
+func TestDecoderAll(t *testing.T) {
+	var types []string
+	var lineErrs int
+
+	for ev, err := range NewDecoder(strings.NewReader(decoderInput)).All() {
+		if err != nil {
+			lineErrs++
+			continue
+		}
+		types = append(types, ev.Message.Type)
+	}
+
+	if got := strings.Join(types, ","); got != "system,assistant,result" {
+		t.Errorf("All() types = %q, want %q", got, "system,assistant,result")
+	}
+	if lineErrs != 1 {
+		t.Errorf("All() line errors = %d, want 1", lineErrs)
+	}
+}
+
+func TestDecoderAllReadError(t *testing.T) {
+	long := `{"type":"system","cwd":"` + strings.Repeat("x", MaxBufferCapacity) + `"}`
+	input := "{\"type\":\"system\"}\n" + long + "\n{\"type\":\"result\"}\n"
+
+	var events int
+	var lastErr error
+	for _, err := range NewDecoder(strings.NewReader(input)).All() {
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		events++
+	}
+
+	if events != 1 {
+		t.Errorf("All() events before read error = %d, want 1", events)
+	}
+	var lineErr *LineError
+	if lastErr == nil || errors.As(lastErr, &lineErr) {
+		t.Errorf("All() last error = %v, want a read error", lastErr)
+	}
+}
+
+func TestDecoderEvents(t *testing.T) {
+	var types []string
+	var lineErrs int
+
+	for item := range NewDecoder(strings.NewReader(decoderInput)).Events(context.Background()) {
+		if item.Err != nil {
+			lineErrs++
+			continue
+		}
+		types = append(types, item.Event.Message.Type)
+	}
+
+	if got := strings.Join(types, ","); got != "system,assistant,result" {
+		t.Errorf("Events() types = %q, want %q", got, "system,assistant,result")
+	}
+	if lineErrs != 1 {
+		t.Errorf("Events() line errors = %d, want 1", lineErrs)
+	}
+}
+
+func TestDecoderEventsCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := NewDecoder(strings.NewReader(decoderInput)).Events(ctx)
+
+	<-ch
+	cancel()
+
+	// The channel must be closed once the decoder notices the cancellation
+	for range ch {
+	}
+}
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 )
@@ -26,3 +27,30 @@ func StripSystemReminders(content string) string {
 	// Trim leading/trailing whitespace
 	return strings.TrimSpace(result)
 }
+
+// ContentText returns the text of a tool_result content field, which is either
+// a plain string or a list of content blocks. Non-text blocks are shown as [type].
+func ContentText(content interface{}) string {
+	switch v := content.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			block, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if text, ok := block["text"].(string); ok {
+				parts = append(parts, text)
+			} else if typ, ok := block["type"].(string); ok {
+				parts = append(parts, "["+typ+"]")
+			}
+		}
+		return strings.Join(parts, "\n")
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -136,6 +136,42 @@ Just text that mentions system-reminder
 	}
 }
 
+func TestContentText(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  interface{}
+		expected string
+	}{
+		{name: "Nil", content: nil, expected: ""},
+		{name: "String", content: "file contents", expected: "file contents"},
+		{
+			name: "Text blocks",
+			content: []interface{}{
+				map[string]interface{}{"type": "text", "text": "first"},
+				map[string]interface{}{"type": "text", "text": "second"},
+			},
+			expected: "first\nsecond",
+		},
+		{
+			name: "Image block",
+			content: []interface{}{
+				map[string]interface{}{"type": "text", "text": "screenshot:"},
+				map[string]interface{}{"type": "image", "source": map[string]interface{}{}},
+			},
+			expected: "screenshot:\n[image]",
+		},
+		{name: "Other", content: 42.0, expected: "42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ContentText(tt.content); got != tt.expected {
+				t.Errorf("ContentText() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
 func BenchmarkStripSystemReminders(b *testing.B) {
 	input := `Some code content here
 package main
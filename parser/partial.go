@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// PartialMessage accumulates the stream_event deltas of an assistant message
+// while it is being generated. Blocks holds the content received so far,
+// indexed by content block index.
+type PartialMessage struct {
+	ID     string
+	Model  string
+	Blocks []ContentBlock
+
+	inputJSON map[int]*strings.Builder
+}
+
+// NewPartialMessage returns an empty PartialMessage for the message started by ev
+func NewPartialMessage(ev *StreamEvent) *PartialMessage {
+	p := &PartialMessage{inputJSON: make(map[int]*strings.Builder)}
+	if ev != nil && ev.Message != nil {
+		p.ID = ev.Message.ID
+		p.Model = ev.Message.Model
+	}
+	return p
+}
+
+// Apply updates the partial message with a content block event.
+// It returns the affected block, or nil if ev does not change any block.
+func (p *PartialMessage) Apply(ev *StreamEvent) *ContentBlock {
+	switch ev.Type {
+	case "content_block_start":
+		if ev.ContentBlock == nil {
+			return nil
+		}
+		block := p.block(ev.Index)
+		*block = *ev.ContentBlock
+		return block
+	case "content_block_delta":
+		if ev.Delta == nil {
+			return nil
+		}
+		block := p.block(ev.Index)
+		switch ev.Delta.Type {
+		case "text_delta":
+			block.Text += ev.Delta.Text
+		case "thinking_delta":
+			block.Thinking += ev.Delta.Thinking
+		case "signature_delta":
+			block.Signature += ev.Delta.Signature
+		case "input_json_delta":
+			sb := p.inputJSON[ev.Index]
+			if sb == nil {
+				sb = &strings.Builder{}
+				p.inputJSON[ev.Index] = sb
+			}
+			sb.WriteString(ev.Delta.PartialJSON)
+		}
+		return block
+	case "content_block_stop":
+		if ev.Index >= len(p.Blocks) {
+			return nil
+		}
+		block := &p.Blocks[ev.Index]
+		if sb := p.inputJSON[ev.Index]; sb != nil {
+			var input map[string]interface{}
+			if json.Unmarshal([]byte(sb.String()), &input) == nil {
+				block.Input = input
+			}
+			delete(p.inputJSON, ev.Index)
+		}
+		return block
+	}
+	return nil
+}
+
+// block returns the block at index, growing Blocks as needed
+func (p *PartialMessage) block(index int) *ContentBlock {
+	for len(p.Blocks) <= index {
+		p.Blocks = append(p.Blocks, ContentBlock{})
+	}
+	return &p.Blocks[index]
+}
@@ -0,0 +1,52 @@
+package parser
+
+import (
+	"testing"
+)
+
+func TestPartialMessageApply(t *testing.T) {
+	p := NewPartialMessage(&StreamEvent{
+		Type:    "message_start",
+		Message: &MessageContent{ID: "msg_1", Model: "claude-sonnet-4-5"},
+	})
+	if p.ID != "msg_1" || p.Model != "claude-sonnet-4-5" {
+		t.Fatalf("NewPartialMessage() = %q %q, want msg_1 claude-sonnet-4-5", p.ID, p.Model)
+	}
+
+	events := []StreamEvent{
+		{Type: "content_block_start", Index: 0, ContentBlock: &ContentBlock{Type: "thinking"}},
+		{Type: "content_block_delta", Index: 0, Delta: &Delta{Type: "thinking_delta", Thinking: "Hmm"}},
+		{Type: "content_block_delta", Index: 0, Delta: &Delta{Type: "signature_delta", Signature: "sig"}},
+		{Type: "content_block_stop", Index: 0},
+		{Type: "content_block_start", Index: 1, ContentBlock: &ContentBlock{Type: "text"}},
+		{Type: "content_block_delta", Index: 1, Delta: &Delta{Type: "text_delta", Text: "Hello"}},
+		{Type: "content_block_delta", Index: 1, Delta: &Delta{Type: "text_delta", Text: ", world"}},
+		{Type: "content_block_stop", Index: 1},
+		{Type: "content_block_start", Index: 2, ContentBlock: &ContentBlock{Type: "tool_use", ID: "toolu_1", Name: "Bash"}},
+		{Type: "content_block_delta", Index: 2, Delta: &Delta{Type: "input_json_delta", PartialJSON: `{"command":`}},
+		{Type: "content_block_delta", Index: 2, Delta: &Delta{Type: "input_json_delta", PartialJSON: ` "ls -la"}`}},
+		{Type: "content_block_stop", Index: 2},
+	}
+	for i := range events {
+		if p.Apply(&events[i]) == nil {
+			t.Errorf("Apply(%s) returned nil block", events[i].Type)
+		}
+	}
+
+	if p.Apply(&StreamEvent{Type: "message_stop"}) != nil {
+		t.Errorf("Apply(message_stop) returned a block, want nil")
+	}
+
+	if len(p.Blocks) != 3 {
+		t.Fatalf("len(Blocks) = %d, want 3", len(p.Blocks))
+	}
+	if p.Blocks[0].Thinking != "Hmm" || p.Blocks[0].Signature != "sig" {
+		t.Errorf("thinking block = %+v", p.Blocks[0])
+	}
+	if p.Blocks[1].Text != "Hello, world" {
+		t.Errorf("text block = %q, want %q", p.Blocks[1].Text, "Hello, world")
+	}
+	if p.Blocks[2].Input["command"] != "ls -la" {
+		t.Errorf("tool_use input = %v, want command ls -la", p.Blocks[2].Input)
+	}
+}
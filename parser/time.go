@@ -0,0 +1,33 @@
+package parser
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ReceivedAtField is the side-channel field holding StreamMessage.ReceivedAt
+const ReceivedAtField = "cclean_received_at"
+
+// Time returns when the message was sent: the timestamp recorded in a
+// session transcript, or else the time a tee received it. It is zero for
+// messages without either.
+func (m *StreamMessage) Time() time.Time {
+	if !m.Timestamp.IsZero() {
+		return m.Timestamp
+	}
+	return m.ReceivedAt
+}
+
+// LineTime returns the Time of the message on a line of JSON without
+// decoding the rest of it, zero if the line has none
+func LineTime(line []byte) time.Time {
+	var msg struct {
+		Timestamp  time.Time `json:"timestamp"`
+		ReceivedAt time.Time `json:"cclean_received_at"`
+	}
+	if json.Unmarshal(line, &msg) != nil {
+		return time.Time{}
+	}
+	m := StreamMessage{Timestamp: msg.Timestamp, ReceivedAt: msg.ReceivedAt}
+	return m.Time()
+}
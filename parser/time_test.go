@@ -0,0 +1,28 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLineTime(t *testing.T) {
+	at := time.Date(2025, 10, 20, 14, 2, 5, 0, time.UTC)
+	tests := []struct {
+		name string
+		line string
+		want time.Time
+	}{
+		{"transcript", `{"type":"user","timestamp":"2025-10-20T14:02:05Z"}`, at},
+		{"tee", `{"type":"assistant","cclean_received_at":"2025-10-20T14:02:05Z"}`, at},
+		{"both", `{"type":"user","timestamp":"2025-10-20T14:02:05Z","cclean_received_at":"2025-10-21T00:00:00Z"}`, at},
+		{"none", `{"type":"result"}`, time.Time{}},
+		{"invalid", `{"type":`, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LineTime([]byte(tt.line)); !got.Equal(tt.want) {
+				t.Errorf("LineTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Claude Code saves interactive sessions as JSONL under
+// ~/.claude/projects/<encoded-cwd>/<session-id>.jsonl. Their messages are
+// those of stream-json wrapped in an envelope (uuid, parentUuid, timestamp,
+// isSidechain, ...), and the files also hold summary lines and system lines
+// with a content string. The Decoder recognizes these lines and normalizes
+// them into StreamMessages.
+
+// transcriptEnvelope holds the transcript fields that do not map directly
+// onto a StreamMessage
+type transcriptEnvelope struct {
+	SessionID string          `json:"sessionId"`
+	Summary   string          `json:"summary"`
+	Content   json.RawMessage `json:"content"`
+}
+
+// isTranscriptLine reports whether a line is in the session transcript format
+func isTranscriptLine(line []byte) bool {
+	return bytes.Contains(line, []byte(`"sessionId"`)) || bytes.Contains(line, []byte(`"leafUuid"`))
+}
+
+// normalizeTranscript fills in the StreamMessage fields of a message decoded
+// from a session transcript line. Summary lines become system messages with
+// the summary subtype.
+func normalizeTranscript(msg *StreamMessage, line []byte) error {
+	var env transcriptEnvelope
+	if err := json.Unmarshal(line, &env); err != nil {
+		return err
+	}
+	if msg.SessionID == "" {
+		msg.SessionID = env.SessionID
+	}
+	switch msg.Type {
+	case "summary":
+		msg.Type, msg.Subtype = "system", "summary"
+		msg.Content = env.Summary
+	case "system":
+		// Not every system line has text content
+		json.Unmarshal(env.Content, &msg.Content)
+	}
+	return nil
+}
+
+// UnmarshalJSON decodes a message whose content is either a list of content
+// blocks or, as for the prompts in session transcripts, a plain string,
+// which becomes a single text block
+func (m *MessageContent) UnmarshalJSON(data []byte) error {
+	type plain MessageContent
+	var raw struct {
+		plain
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*m = MessageContent(raw.plain)
+
+	if len(raw.Content) == 0 || string(raw.Content) == "null" {
+		return nil
+	}
+	var text string
+	if json.Unmarshal(raw.Content, &text) == nil {
+		if text != "" {
+			m.Content = []ContentBlock{{Type: "text", Text: text}}
+		}
+		return nil
+	}
+	return json.Unmarshal(raw.Content, &m.Content)
+}
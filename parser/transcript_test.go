@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestDecoderTranscript(t *testing.T) {
+	file, err := os.Open("../mocks/claude-session-transcript.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	dec := NewDecoder(file)
+	var msgs []*StreamMessage
+	for ev, err := range dec.All() {
+		if err != nil {
+			t.Fatalf("line %d: %v", ev.Line, err)
+		}
+		msgs = append(msgs, ev.Message)
+	}
+	if !dec.Transcript() {
+		t.Error("Transcript() = false, want true")
+	}
+	if len(msgs) != 8 {
+		t.Fatalf("decoded %d messages, want 8", len(msgs))
+	}
+
+	summary := msgs[0]
+	if summary.Type != "system" || summary.Subtype != "summary" || summary.Content != "Fix failing parser test" {
+		t.Errorf("summary line = %s/%s %q, want a system summary", summary.Type, summary.Subtype, summary.Content)
+	}
+	if !msgs[1].IsMeta {
+		t.Error("meta user message not marked")
+	}
+
+	prompt := msgs[2]
+	if prompt.SessionID != "0f9e8d7c-6b5a-4321-8765-43210fedcba9" || prompt.ParentUUID != "11111111-1111-4111-8111-111111111111" {
+		t.Errorf("envelope = session %q parent %q", prompt.SessionID, prompt.ParentUUID)
+	}
+	expected := time.Date(2025, 10, 20, 14, 2, 5, 0, time.UTC)
+	if !prompt.Timestamp.Equal(expected) {
+		t.Errorf("Timestamp = %v, want %v", prompt.Timestamp, expected)
+	}
+	if c := prompt.Message.Content; len(c) != 1 || c[0].Type != "text" || c[0].Text != "The parser test fails, can you fix it?" {
+		t.Errorf("string content = %+v, want one text block", c)
+	}
+
+	if result := msgs[5].Message.Content; len(result) != 1 || !result[0].IsError || result[0].ToolUseID != "toolu_01" {
+		t.Errorf("tool result = %+v", result)
+	}
+	if boundary := msgs[7]; boundary.Type != "system" || boundary.Content != "Conversation compacted" {
+		t.Errorf("system line = %s %q, want its content", boundary.Type, boundary.Content)
+	}
+}
+
+func TestDecoderStreamJSONIsNotTranscript(t *testing.T) {
+	file, err := os.Open("../mocks/claude-stream-json-simple.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	dec := NewDecoder(file)
+	for _, err := range dec.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if dec.Transcript() {
+		t.Error("Transcript() = true for stream-json")
+	}
+}
@@ -1,5 +1,7 @@
 package parser
 
+import "time"
+
 // StreamMessage represents the top-level JSON message from Claude stream
 type StreamMessage struct {
 	Type              string          `json:"type"`
@@ -21,6 +23,19 @@ type StreamMessage struct {
 	Usage             *Usage                 `json:"usage,omitempty"`
 	ModelUsage        map[string]interface{} `json:"modelUsage,omitempty"`
 	PermissionDenials []interface{}          `json:"permission_denials,omitempty"`
+	// Stream event fields (emitted with --include-partial-messages)
+	Event *StreamEvent `json:"event,omitempty"`
+	// Session transcript fields, from the sessions Claude Code saves under
+	// ~/.claude/projects (see Decoder.Transcript)
+	Timestamp   time.Time `json:"timestamp,omitzero"`
+	UUID        string    `json:"uuid,omitempty"`
+	ParentUUID  string    `json:"parentUuid,omitempty"`
+	IsSidechain bool      `json:"isSidechain,omitempty"` // sent by a subagent
+	IsMeta      bool      `json:"isMeta,omitempty"`      // user message added by Claude Code, not typed by the user
+	Content     string    `json:"-"`                     // text of a transcript system message or summary
+	// ReceivedAt is when cclean received the message, recorded by
+	// --tee-time so that streams without timestamps can be replayed
+	ReceivedAt time.Time `json:"cclean_received_at,omitzero"`
 }
 
 // MessageContent contains the message content container
@@ -35,10 +50,13 @@ type MessageContent struct {
 	Usage        *Usage         `json:"usage"`
 }
 
-// ContentBlock represents individual content pieces (text, tool_use, tool_result)
+// ContentBlock represents individual content pieces (text, thinking, tool_use, tool_result)
 type ContentBlock struct {
 	Type      string                 `json:"type"`
 	Text      string                 `json:"text,omitempty"`
+	Thinking  string                 `json:"thinking,omitempty"`
+	Signature string                 `json:"signature,omitempty"`
+	Data      string                 `json:"data,omitempty"` // Encrypted payload of a redacted_thinking block
 	ID        string                 `json:"id,omitempty"`
 	Name      string                 `json:"name,omitempty"`
 	Input     map[string]interface{} `json:"input,omitempty"`
@@ -47,6 +65,26 @@ type ContentBlock struct {
 	IsError   bool                   `json:"is_error,omitempty"`
 }
 
+// StreamEvent is a raw Messages API streaming event carried by a stream_event message
+type StreamEvent struct {
+	Type         string          `json:"type"`
+	Index        int             `json:"index"`
+	Message      *MessageContent `json:"message,omitempty"`
+	ContentBlock *ContentBlock   `json:"content_block,omitempty"`
+	Delta        *Delta          `json:"delta,omitempty"`
+	Usage        *Usage          `json:"usage,omitempty"`
+}
+
+// Delta is an incremental update to a content block or message
+type Delta struct {
+	Type        string  `json:"type"`
+	Text        string  `json:"text,omitempty"`
+	PartialJSON string  `json:"partial_json,omitempty"`
+	Thinking    string  `json:"thinking,omitempty"`
+	Signature   string  `json:"signature,omitempty"`
+	StopReason  *string `json:"stop_reason,omitempty"`
+}
+
 // Usage represents token usage statistics
 type Usage struct {
 	InputTokens              int                  `json:"input_tokens"`
@@ -0,0 +1,228 @@
+// Package sessions finds the interactive sessions Claude Code saves under
+// ~/.claude/projects and summarizes them for cclean sessions.
+package sessions
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ariel-frischer/claude-clean/parser"
+	"github.com/ariel-frischer/claude-clean/stats"
+)
+
+// Session summarizes a saved session
+type Session struct {
+	ID            string    `json:"id"`
+	Path          string    `json:"path"`
+	Project       string    `json:"project"` // working directory of the session
+	Start         time.Time `json:"start"`
+	Model         string    `json:"model"`
+	Turns         int       `json:"turns"`
+	CostUSD       float64   `json:"cost_usd"`
+	CostEstimated bool      `json:"cost_estimated,omitempty"`
+	CostUnknown   bool      `json:"cost_unknown,omitempty"` // a model used has no known price
+	Prompt        string    `json:"prompt"`                 // first prompt typed by the user
+
+	sidechain bool // transcript of a subagent rather than a session
+}
+
+// Filter selects sessions
+type Filter struct {
+	Project string    // part of the project path, case-insensitive
+	Since   time.Time // earliest start, zero for any
+	Until   time.Time // latest start, zero for any
+}
+
+// Match reports whether a session passes the filter
+func (f Filter) Match(s *Session) bool {
+	switch {
+	case f.Project != "" && !strings.Contains(strings.ToLower(s.Project), strings.ToLower(f.Project)):
+		return false
+	case !f.Since.IsZero() && s.Start.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && s.Start.After(f.Until):
+		return false
+	}
+	return true
+}
+
+// DefaultRoot returns the directory where Claude Code saves sessions:
+// projects under $CLAUDE_CONFIG_DIR, or under ~/.claude
+func DefaultRoot() (string, error) {
+	if dir := os.Getenv("CLAUDE_CONFIG_DIR"); dir != "" {
+		return filepath.Join(dir, "projects"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".claude", "projects"), nil
+}
+
+// Find returns the sessions saved in the project directories under root
+// that pass the filter, most recent first. Files that cannot be read, such
+// as one with a line too long to decode, are left out and their errors
+// returned in skipped.
+func Find(root string, f Filter) (sessions []Session, skipped []error, err error) {
+	paths, err := filepath.Glob(filepath.Join(root, "*", "*.jsonl"))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(paths) == 0 {
+		if _, err := os.Stat(root); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, path := range paths {
+		// Subagents are saved in agent-<id>.jsonl files next to the session
+		if strings.HasPrefix(filepath.Base(path), "agent-") {
+			continue
+		}
+		s, err := Read(path)
+		if err != nil {
+			skipped = append(skipped, err)
+			continue
+		}
+		if !s.sidechain && f.Match(s) {
+			sessions = append(sessions, *s)
+		}
+	}
+	slices.SortFunc(sessions, func(a, b Session) int {
+		return cmp.Or(b.Start.Compare(a.Start), cmp.Compare(a.ID, b.ID))
+	})
+	return sessions, skipped, nil
+}
+
+// Read summarizes the session saved in a file
+func Read(path string) (*Session, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	s := &Session{
+		ID:   strings.TrimSuffix(filepath.Base(path), ".jsonl"),
+		Path: path,
+	}
+	c := stats.NewCollector()
+	messages := false
+	for ev, err := range parser.NewDecoder(file).All() {
+		if err != nil {
+			var lineErr *parser.LineError
+			if errors.As(err, &lineErr) {
+				continue
+			}
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+		msg := ev.Message
+		c.Add(msg)
+		if msg.UUID != "" && !messages {
+			// Summary lines come before the first message
+			messages = true
+			s.sidechain = msg.IsSidechain
+		}
+
+		if s.Project == "" {
+			s.Project = msg.CWD
+		}
+		if s.Start.IsZero() {
+			s.Start = msg.Timestamp
+		}
+		if s.Model == "" && msg.Type == "assistant" && msg.Message != nil {
+			s.Model = msg.Message.Model
+		}
+		if s.Prompt == "" && msg.Type == "user" && !msg.IsMeta && msg.ParentToolUseID == "" && !msg.IsSidechain {
+			s.Prompt = prompt(msg)
+		}
+	}
+
+	st := c.Stats()
+	s.Turns, s.CostUSD, s.CostEstimated = st.Turns, st.CostUSD, st.CostEstimated
+	s.CostUnknown = len(st.UnpricedModels) > 0
+	if s.Project == "" {
+		// The directory name is the path with its separators replaced
+		s.Project = filepath.Base(filepath.Dir(path))
+	}
+	return s, nil
+}
+
+// prompt returns the text typed in a user message, on one line
+func prompt(msg *parser.StreamMessage) string {
+	if msg.Message == nil {
+		return ""
+	}
+	var parts []string
+	for _, block := range msg.Message.Content {
+		if block.Type == "text" {
+			if text := parser.StripSystemReminders(block.Text); text != "" {
+				parts = append(parts, text)
+			}
+		}
+	}
+	return strings.Join(strings.Fields(strings.Join(parts, " ")), " ")
+}
+
+// Lookup returns the session whose ID starts with prefix
+func Lookup(sessions []Session, prefix string) (*Session, error) {
+	var found []*Session
+	for i := range sessions {
+		if strings.HasPrefix(sessions[i].ID, prefix) {
+			found = append(found, &sessions[i])
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("no session %s", prefix)
+	case 1:
+		return found[0], nil
+	}
+	ids := make([]string, len(found))
+	for i, s := range found {
+		ids[i] = s.ID
+	}
+	return nil, fmt.Errorf("session %s is ambiguous: %s", prefix, strings.Join(ids, ", "))
+}
+
+// promptWidth is the number of characters of the first prompt listed
+const promptWidth = 60
+
+// WriteTable lists sessions as an aligned table
+func WriteTable(w io.Writer, sessions []Session) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTART\tPROJECT\tMODEL\tTURNS\tCOST\tPROMPT")
+	for _, s := range sessions {
+		start := "-"
+		if !s.Start.IsZero() {
+			start = s.Start.Local().Format("2006-01-02 15:04")
+		}
+		cost := fmt.Sprintf("$%.2f", s.CostUSD)
+		switch {
+		case s.CostUnknown:
+			cost = "unknown"
+		case s.CostEstimated:
+			cost = "~" + cost
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			s.ID[:min(len(s.ID), 8)], start, s.Project, cmp.Or(s.Model, "-"), s.Turns, cost, shorten(s.Prompt, promptWidth))
+	}
+	return tw.Flush()
+}
+
+// shorten cuts text to at most n characters
+func shorten(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,162 @@
+package sessions
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ariel-frischer/claude-clean/parser"
+)
+
+// root holds two projects with one saved session each, and two subagent
+// transcripts that Find leaves out
+const root = "testdata/projects"
+
+func TestFind(t *testing.T) {
+	found, _, err := Find(root, Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 {
+		t.Fatalf("found %d sessions, want 2", len(found))
+	}
+
+	// Most recent first
+	webapp, project := found[0], found[1]
+	if webapp.ID != "0f3a2b1c-1d2e-4f3a-8b4c-5d6e7f8a9b0c" || project.ID != "0f9e8d7c-6b5a-4321-8765-43210fedcba9" {
+		t.Fatalf("sessions %s, %s in the wrong order", webapp.ID, project.ID)
+	}
+	want := Session{
+		ID:            "0f9e8d7c-6b5a-4321-8765-43210fedcba9",
+		Path:          project.Path,
+		Project:       "/home/user/project",
+		Start:         time.Date(2025, 10, 20, 14, 2, 3, 120_000_000, time.UTC),
+		Model:         "claude-sonnet-4-5-20250929",
+		Turns:         2,
+		CostUSD:       project.CostUSD,
+		CostEstimated: true,
+		Prompt:        "The parser test fails, can you fix it?",
+	}
+	if !project.Start.Equal(want.Start) {
+		t.Errorf("Start = %v, want %v", project.Start, want.Start)
+	}
+	project.Start = want.Start
+	if project != want {
+		t.Errorf("Find() session = %+v\nwant %+v", project, want)
+	}
+	if project.CostUSD <= 0 {
+		t.Errorf("CostUSD = %v, want an estimate", project.CostUSD)
+	}
+}
+
+func TestFindMissingRoot(t *testing.T) {
+	if _, _, err := Find("testdata/missing", Filter{}); err == nil {
+		t.Error("Find() on a missing directory succeeded")
+	}
+}
+
+func TestFindSkipsUnreadable(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "-home-user-project")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	good, err := os.ReadFile(filepath.Join(root, "-home-user-project", "0f9e8d7c-6b5a-4321-8765-43210fedcba9.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	long := `{"type":"user","cwd":"` + strings.Repeat("x", parser.MaxBufferCapacity) + `"}` + "\n"
+	for name, data := range map[string]string{"good.jsonl": string(good), "long.jsonl": long} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	found, skipped, err := Find(filepath.Dir(dir), Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].ID != "good" {
+		t.Errorf("Find() = %+v, want the readable session", found)
+	}
+	if len(skipped) != 1 || !strings.Contains(skipped[0].Error(), "long.jsonl") {
+		t.Errorf("skipped = %v, want the error for long.jsonl", skipped)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 10, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"project", Filter{Project: "WEBAPP"}, []string{"0f3a2b1c"}},
+		{"since", Filter{Since: day(21)}, []string{"0f3a2b1c"}},
+		{"until", Filter{Until: day(21)}, []string{"0f9e8d7c"}},
+		{"range", Filter{Since: day(19), Until: day(23)}, []string{"0f3a2b1c", "0f9e8d7c"}},
+		{"none", Filter{Project: "api"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, _, err := Find(root, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, s := range found {
+				got = append(got, s.ID[:8])
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Find() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	found, _, err := Find(root, Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := Lookup(found, "0f9e")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Project != "/home/user/project" {
+		t.Errorf("Lookup(0f9e) = %s", s.ID)
+	}
+	if _, err := Lookup(found, "0f"); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("Lookup(0f) error = %v, want ambiguous", err)
+	}
+	if _, err := Lookup(found, "ff"); err == nil {
+		t.Error("Lookup(ff) found a session")
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	found, _, err := Find(root, Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteTable(&buf, found); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		"ID        START",
+		"0f3a2b1c",
+		"/home/user/webapp",
+		"claude-opus-4-1-20250805",
+		"~$0.03",
+		"Add a dark mode toggle to the settings page",
+		"The parser test fails, can you fix it?",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q\nGot:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package stats
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Price is the price of a model in USD per million tokens
+type Price struct {
+	Input         float64
+	Output        float64
+	CacheRead     float64
+	CacheCreation float64
+}
+
+// prices are the list prices of the models cclean knows, by model name.
+// Cache creation is priced for the default 5-minute cache. Models left out,
+// including any released later, have no price rather than a guessed one.
+var prices = map[string]Price{
+	"claude-opus-4-5":   {5, 25, 0.50, 6.25},
+	"claude-opus-4-1":   {15, 75, 1.50, 18.75},
+	"claude-opus-4":     {15, 75, 1.50, 18.75},
+	"claude-3-opus":     {15, 75, 1.50, 18.75},
+	"claude-sonnet-4-5": {3, 15, 0.30, 3.75},
+	"claude-sonnet-4":   {3, 15, 0.30, 3.75},
+	"claude-3-7-sonnet": {3, 15, 0.30, 3.75},
+	"claude-3-5-sonnet": {3, 15, 0.30, 3.75},
+	"claude-haiku-4-5":  {1, 5, 0.10, 1.25},
+	"claude-3-5-haiku":  {0.80, 4, 0.08, 1},
+	"claude-3-haiku":    {0.25, 1.25, 0.03, 0.30},
+}
+
+// versionSuffix matches what may follow a model name in a model ID: a date
+// version, as in claude-sonnet-4-5-20250929 or claude-opus-4-1@20250805,
+// with the version suffix used by Bedrock
+var versionSuffix = regexp.MustCompile(`^[-@]\d{8}(-v\d+:\d+)?$`)
+
+// SetPrice sets the price of a model, adding a model or replacing the
+// built-in price of one
+func SetPrice(model string, p Price) {
+	prices[model] = p
+}
+
+// priceOf returns the price of a model ID: the price of its name, with or
+// without a date version and a provider prefix such as us.anthropic.
+func priceOf(model string) (Price, bool) {
+	if p, ok := prices[model]; ok {
+		return p, true
+	}
+	if i := strings.LastIndex(model, "anthropic."); i >= 0 {
+		model = model[i+len("anthropic."):]
+	}
+	for name, p := range prices {
+		if rest, ok := strings.CutPrefix(model, name); ok && (rest == "" || versionSuffix.MatchString(rest)) {
+			return p, true
+		}
+	}
+	return Price{}, false
+}
+
+// EstimateCost returns the cost of token usage at the list price of a
+// model, for sessions that do not report their cost. It returns false for
+// models it has no price for.
+func EstimateCost(model string, t Tokens) (float64, bool) {
+	p, ok := priceOf(model)
+	if !ok {
+		return 0, false
+	}
+	cost := float64(t.Input)*p.Input +
+		float64(t.Output)*p.Output +
+		float64(t.CacheRead)*p.CacheRead +
+		float64(t.CacheCreation)*p.CacheCreation
+	return cost / 1e6, true
+}
@@ -0,0 +1,95 @@
+package stats
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// TopFiles is the number of files listed in each file table of the report
+const TopFiles = 10
+
+// WriteTable writes the report as aligned plain-text tables
+func WriteTable(w io.Writer, st Stats) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Sessions:\t%d\n", st.Sessions)
+	fmt.Fprintf(tw, "Turns:\t%d\n", st.Turns)
+	fmt.Fprintf(tw, "Tool calls:\t%d\n", st.ToolCalls)
+	fmt.Fprintf(tw, "Tool errors:\t%d (%s)\n", st.ToolErrors, percent(st.ToolErrors, st.ToolCalls))
+	fmt.Fprintf(tw, "Duration:\t%s (API: %s)\n", duration(st.DurationMS), duration(st.DurationAPIMS))
+	var notes []string
+	if st.CostEstimated {
+		notes = append(notes, "estimated from token usage where not reported")
+	}
+	if len(st.UnpricedModels) > 0 {
+		notes = append(notes, "unknown for "+strings.Join(st.UnpricedModels, ", "))
+	}
+	if len(notes) > 0 {
+		fmt.Fprintf(tw, "Cost:\t$%.4f (%s)\n", st.CostUSD, strings.Join(notes, "; "))
+	} else {
+		fmt.Fprintf(tw, "Cost:\t$%.4f\n", st.CostUSD)
+	}
+	fmt.Fprintf(tw, "Tokens:\tin=%d out=%d cache_read=%d cache_creation=%d\n",
+		st.Tokens.Input, st.Tokens.Output, st.Tokens.CacheRead, st.Tokens.CacheCreation)
+	fmt.Fprintf(tw, "Cache hit ratio:\t%.1f%%\n", st.CacheHitRatio*100)
+	fmt.Fprintf(tw, "Permission denials:\t%d\n", st.PermissionDenials)
+
+	if len(st.Models) > 0 {
+		fmt.Fprintln(tw, "\nMODEL\tIN\tOUT\tCACHE READ\tCACHE CREATION\tCOST")
+		for _, m := range st.Models {
+			cost := fmt.Sprintf("$%.4f", m.CostUSD)
+			if m.CostUSD == 0 && slices.Contains(st.UnpricedModels, m.Model) {
+				cost = "unknown"
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%s\n",
+				m.Model, m.Tokens.Input, m.Tokens.Output, m.Tokens.CacheRead, m.Tokens.CacheCreation, cost)
+		}
+	}
+
+	if len(st.Tools) > 0 {
+		fmt.Fprintln(tw, "\nTOOL\tCALLS\tERRORS\tERROR RATE")
+		for _, t := range st.Tools {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f%%\n", t.Name, t.Calls, t.Errors, t.ErrorRate*100)
+		}
+	}
+
+	writeFiles(tw, "MOST READ", st.FilesRead)
+	writeFiles(tw, "MOST EDITED", st.FilesEdited)
+
+	if len(st.DeniedTools) > 0 {
+		fmt.Fprintln(tw, "\nDENIED TOOL\tDENIALS")
+		for _, d := range st.DeniedTools {
+			fmt.Fprintf(tw, "%s\t%d\n", d.Name, d.Count)
+		}
+	}
+	return tw.Flush()
+}
+
+// writeFiles writes the first TopFiles files of a file table
+func writeFiles(w io.Writer, title string, files []FileCount) {
+	if len(files) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%s\tTIMES\n", title)
+	for _, f := range files[:min(len(files), TopFiles)] {
+		fmt.Fprintf(w, "%s\t%d\n", f.Path, f.Count)
+	}
+	if len(files) > TopFiles {
+		fmt.Fprintf(w, "(%d more)\t\n", len(files)-TopFiles)
+	}
+}
+
+func percent(n, total int) string {
+	if total == 0 {
+		return "0.0%"
+	}
+	return fmt.Sprintf("%.1f%%", float64(n)/float64(total)*100)
+}
+
+func duration(ms int) string {
+	return (time.Duration(ms) * time.Millisecond).Round(100 * time.Millisecond).String()
+}
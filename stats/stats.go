@@ -0,0 +1,373 @@
+// Package stats aggregates sessions into the report printed by cclean stats:
+// turns, tool calls and errors per tool, the files read and edited, token
+// usage per model, cost, duration and permission denials.
+package stats
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+
+	"github.com/ariel-frischer/claude-clean/parser"
+)
+
+// Stats is the report for one or more sessions
+type Stats struct {
+	Sessions          int               `json:"sessions"`
+	Turns             int               `json:"turns"`
+	ToolCalls         int               `json:"tool_calls"`
+	ToolErrors        int               `json:"tool_errors"`
+	Tools             []ToolStats       `json:"tools"`
+	FilesRead         []FileCount       `json:"files_read"`
+	FilesEdited       []FileCount       `json:"files_edited"`
+	Tokens            Tokens            `json:"tokens"`
+	Models            []ModelStats      `json:"models"`
+	CacheHitRatio     float64           `json:"cache_hit_ratio"`
+	CostUSD           float64           `json:"cost_usd"`
+	CostEstimated     bool              `json:"cost_estimated,omitempty"`  // part of the cost is estimated from token usage
+	UnpricedModels    []string          `json:"unpriced_models,omitempty"` // models whose cost is unknown, left out of the cost
+	DurationMS        int               `json:"duration_ms"`
+	DurationAPIMS     int               `json:"duration_api_ms"`
+	PermissionDenials int               `json:"permission_denials"`
+	DeniedTools       []ToolDenialCount `json:"denied_tools,omitempty"`
+}
+
+// ToolStats counts the calls of a tool and how many of them failed
+type ToolStats struct {
+	Name      string  `json:"name"`
+	Calls     int     `json:"calls"`
+	Errors    int     `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+}
+
+// FileCount counts the calls that read or edited a file
+type FileCount struct {
+	Path  string `json:"path"`
+	Count int    `json:"count"`
+}
+
+// ToolDenialCount counts the permission denials of a tool
+type ToolDenialCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Tokens sums token usage
+type Tokens struct {
+	Input         int `json:"input"`
+	Output        int `json:"output"`
+	CacheRead     int `json:"cache_read"`
+	CacheCreation int `json:"cache_creation"`
+}
+
+// ModelStats is the token usage and cost of one model
+type ModelStats struct {
+	Model   string  `json:"model"`
+	Tokens  Tokens  `json:"tokens"`
+	CostUSD float64 `json:"cost_usd"`
+}
+
+// editTools maps the tools that change files to the input holding the path
+var editTools = map[string]string{
+	"Edit":         "file_path",
+	"MultiEdit":    "file_path",
+	"Write":        "file_path",
+	"NotebookEdit": "notebook_path",
+}
+
+// Collector builds Stats from the messages of one or more sessions. Call
+// Session before the messages of each session, then Add for each message.
+type Collector struct {
+	stats  Stats
+	tools  map[string]*ToolStats
+	read   map[string]int
+	edited map[string]int
+	denied map[string]int
+	models map[string]*ModelStats
+	calls  map[string]string // tool_use ID -> tool name
+
+	unpriced map[string]bool // models used in sessions without a cost that have no price
+
+	session *session
+}
+
+// session is the state of the session being collected, for the totals that
+// come from the result message when it has one and from the assistant
+// messages otherwise
+type session struct {
+	result  bool
+	turns   int
+	lastID  string // ID of the last assistant message, to count turns
+	tokens  Tokens
+	models  map[string]Tokens
+	counted map[string]bool // assistant message IDs whose usage was added
+}
+
+// NewCollector returns an empty Collector
+func NewCollector() *Collector {
+	return &Collector{
+		tools:  make(map[string]*ToolStats),
+		read:   make(map[string]int),
+		edited: make(map[string]int),
+		denied: make(map[string]int),
+		models: make(map[string]*ModelStats),
+		calls:  make(map[string]string),
+
+		unpriced: make(map[string]bool),
+	}
+}
+
+// Session starts a new session
+func (c *Collector) Session() {
+	c.endSession()
+	c.stats.Sessions++
+	c.session = &session{models: make(map[string]Tokens), counted: make(map[string]bool)}
+}
+
+// Add adds a message of the current session
+func (c *Collector) Add(msg *parser.StreamMessage) {
+	if c.session == nil {
+		c.Session()
+	}
+	switch msg.Type {
+	case "assistant":
+		c.assistant(msg)
+	case "user":
+		c.user(msg)
+	case "result":
+		c.result(msg)
+	}
+}
+
+func (c *Collector) assistant(msg *parser.StreamMessage) {
+	if msg.Message == nil {
+		return
+	}
+	s := c.session
+	// A response with several content blocks arrives as several messages
+	// sharing its ID and usage
+	id := msg.Message.ID
+	if msg.ParentToolUseID == "" && (id == "" || id != s.lastID) {
+		s.turns++
+		s.lastID = id
+	}
+	if u := msg.Message.Usage; u != nil && (id == "" || !s.counted[id]) {
+		s.counted[id] = true
+		t := tokensOf(u)
+		s.tokens.add(t)
+		model := s.models[msg.Message.Model]
+		model.add(t)
+		s.models[msg.Message.Model] = model
+	}
+
+	for _, block := range msg.Message.Content {
+		if block.Type != "tool_use" {
+			continue
+		}
+		c.calls[block.ID] = block.Name
+		c.tool(block.Name).Calls++
+		c.stats.ToolCalls++
+
+		if block.Name == "Read" {
+			if path, ok := block.Input["file_path"].(string); ok {
+				c.read[path]++
+			}
+		}
+		if key, ok := editTools[block.Name]; ok {
+			if path, ok := block.Input[key].(string); ok {
+				c.edited[path]++
+			}
+		}
+	}
+}
+
+func (c *Collector) user(msg *parser.StreamMessage) {
+	if msg.Message == nil {
+		return
+	}
+	for _, block := range msg.Message.Content {
+		if block.Type != "tool_result" || !block.IsError {
+			continue
+		}
+		c.stats.ToolErrors++
+		if name, ok := c.calls[block.ToolUseID]; ok {
+			c.tool(name).Errors++
+		}
+	}
+}
+
+func (c *Collector) result(msg *parser.StreamMessage) {
+	s := c.session
+	s.result = true
+	c.stats.Turns += msg.NumTurns
+	c.stats.CostUSD += msg.TotalCostUSD
+	c.stats.DurationMS += msg.DurationMS
+	c.stats.DurationAPIMS += msg.DurationAPIMS
+
+	c.stats.PermissionDenials += len(msg.PermissionDenials)
+	for _, d := range msg.PermissionDenials {
+		name := "unknown"
+		if denial, ok := d.(map[string]interface{}); ok {
+			if n, ok := denial["tool_name"].(string); ok {
+				name = n
+			}
+		}
+		c.denied[name]++
+	}
+
+	// The per-model usage includes subagents and is the most complete, so
+	// it replaces what was counted from the assistant messages
+	if len(msg.ModelUsage) > 0 {
+		s.tokens = Tokens{}
+		clear(s.models)
+		for model, data := range msg.ModelUsage {
+			usage, ok := data.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			t := Tokens{
+				Input:         number(usage["inputTokens"]),
+				Output:        number(usage["outputTokens"]),
+				CacheRead:     number(usage["cacheReadInputTokens"]),
+				CacheCreation: number(usage["cacheCreationInputTokens"]),
+			}
+			s.tokens.add(t)
+			s.models[model] = t
+			cost, _ := usage["costUSD"].(float64)
+			c.model(model).CostUSD += cost
+		}
+	} else if msg.Usage != nil {
+		s.tokens = tokensOf(msg.Usage)
+	}
+}
+
+// endSession adds the totals of the current session
+func (c *Collector) endSession() {
+	s := c.session
+	if s == nil {
+		return
+	}
+	if !s.result {
+		// Saved interactive sessions have no result message with the cost
+		c.stats.Turns += s.turns
+		for model, t := range s.models {
+			cost, ok := EstimateCost(model, t)
+			switch {
+			case !ok && t != (Tokens{}):
+				c.unpriced[model] = true
+			case cost > 0:
+				c.stats.CostUSD += cost
+				c.model(model).CostUSD += cost
+				c.stats.CostEstimated = true
+			}
+		}
+	}
+	c.stats.Tokens.add(s.tokens)
+	for model, t := range s.models {
+		c.model(model).Tokens.add(t)
+	}
+	c.session = nil
+}
+
+// Stats returns the report once all messages have been added
+func (c *Collector) Stats() Stats {
+	c.endSession()
+	st := c.stats
+
+	st.Tools = make([]ToolStats, 0, len(c.tools))
+	for _, t := range c.tools {
+		tool := *t
+		if tool.Calls > 0 {
+			tool.ErrorRate = float64(tool.Errors) / float64(tool.Calls)
+		}
+		st.Tools = append(st.Tools, tool)
+	}
+	slices.SortFunc(st.Tools, func(a, b ToolStats) int {
+		return cmp.Or(cmp.Compare(b.Calls, a.Calls), cmp.Compare(a.Name, b.Name))
+	})
+
+	st.FilesRead = fileCounts(c.read)
+	st.FilesEdited = fileCounts(c.edited)
+
+	st.DeniedTools = nil
+	for name, n := range c.denied {
+		st.DeniedTools = append(st.DeniedTools, ToolDenialCount{Name: name, Count: n})
+	}
+	slices.SortFunc(st.DeniedTools, func(a, b ToolDenialCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Name, b.Name))
+	})
+
+	st.Models = make([]ModelStats, 0, len(c.models))
+	for _, m := range c.models {
+		if m.Model != "" {
+			st.Models = append(st.Models, *m)
+		}
+	}
+	slices.SortFunc(st.Models, func(a, b ModelStats) int { return cmp.Compare(a.Model, b.Model) })
+
+	st.UnpricedModels = slices.Sorted(maps.Keys(c.unpriced))
+	st.CacheHitRatio = st.Tokens.CacheHitRatio()
+	return st
+}
+
+// CacheHitRatio returns the share of input tokens that were read from the
+// prompt cache
+func (t Tokens) CacheHitRatio() float64 {
+	total := t.Input + t.CacheRead + t.CacheCreation
+	if total == 0 {
+		return 0
+	}
+	return float64(t.CacheRead) / float64(total)
+}
+
+func (t *Tokens) add(o Tokens) {
+	t.Input += o.Input
+	t.Output += o.Output
+	t.CacheRead += o.CacheRead
+	t.CacheCreation += o.CacheCreation
+}
+
+func tokensOf(u *parser.Usage) Tokens {
+	return Tokens{
+		Input:         u.InputTokens,
+		Output:        u.OutputTokens,
+		CacheRead:     u.CacheReadInputTokens,
+		CacheCreation: u.CacheCreationInputTokens,
+	}
+}
+
+func (c *Collector) tool(name string) *ToolStats {
+	t, ok := c.tools[name]
+	if !ok {
+		t = &ToolStats{Name: name}
+		c.tools[name] = t
+	}
+	return t
+}
+
+func (c *Collector) model(name string) *ModelStats {
+	m, ok := c.models[name]
+	if !ok {
+		m = &ModelStats{Model: name}
+		c.models[name] = m
+	}
+	return m
+}
+
+// fileCounts returns counts sorted by count, most first, then path
+func fileCounts(counts map[string]int) []FileCount {
+	files := make([]FileCount, 0, len(counts))
+	for path, n := range counts {
+		files = append(files, FileCount{Path: path, Count: n})
+	}
+	slices.SortFunc(files, func(a, b FileCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Path, b.Path))
+	})
+	return files
+}
+
+// number returns a JSON number as an int
+func number(v interface{}) int {
+	f, _ := v.(float64)
+	return int(f)
+}
@@ -0,0 +1,150 @@
+package stats
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/ariel-frischer/claude-clean/parser"
+)
+
+// decode parses a session written one JSON message per line
+func decode(t *testing.T, session string) []*parser.StreamMessage {
+	t.Helper()
+	var msgs []*parser.StreamMessage
+	for ev, err := range parser.NewDecoder(strings.NewReader(session)).All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, ev.Message)
+	}
+	return msgs
+}
+
+// withResult ends with a result message carrying per-model usage
+const withResult = `{"type":"assistant","message":{"id":"m1","model":"claude-a","content":[{"type":"text","text":"Reading"}],"usage":{"input_tokens":1,"output_tokens":1}}}
+{"type":"assistant","message":{"id":"m1","model":"claude-a","content":[{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"main.go"}},{"type":"tool_use","id":"t2","name":"Read","input":{"file_path":"main.go"}}],"usage":{"input_tokens":1,"output_tokens":1}}}
+{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"package main"},{"type":"tool_result","tool_use_id":"t2","content":"denied","is_error":true}]}}
+{"type":"assistant","message":{"id":"m2","model":"claude-a","content":[{"type":"tool_use","id":"t3","name":"Edit","input":{"file_path":"main.go"}},{"type":"tool_use","id":"t4","name":"Bash","input":{"command":"rm -rf /"}}]}}
+{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t3","content":"ok"},{"type":"tool_result","tool_use_id":"t4","content":"denied","is_error":true}]}}
+{"type":"result","subtype":"success","num_turns":2,"duration_ms":3000,"duration_api_ms":2000,"total_cost_usd":0.5,"modelUsage":{"claude-a":{"inputTokens":100,"outputTokens":50,"cacheReadInputTokens":300,"cacheCreationInputTokens":100,"costUSD":0.4},"claude-b":{"inputTokens":10,"outputTokens":5,"costUSD":0.1}},"permission_denials":[{"tool_name":"Bash","tool_use_id":"t4","tool_input":{"command":"rm -rf /"}}]}
+`
+
+// withoutResult was cut off before its result message
+const withoutResult = `{"type":"assistant","message":{"id":"m1","model":"claude-a","content":[{"type":"tool_use","id":"t1","name":"Write","input":{"file_path":"new.go"}}],"usage":{"input_tokens":10,"output_tokens":20,"cache_read_input_tokens":30}}}
+{"type":"assistant","message":{"id":"m1","model":"claude-a","content":[{"type":"text","text":"Written"}],"usage":{"input_tokens":10,"output_tokens":20,"cache_read_input_tokens":30}}}
+{"type":"assistant","message":{"id":"m2","model":"claude-a","content":[{"type":"text","text":"Done"}],"usage":{"input_tokens":5,"output_tokens":5}}}
+`
+
+func TestCollector(t *testing.T) {
+	c := NewCollector()
+	for _, session := range []string{withResult, withoutResult} {
+		c.Session()
+		for _, msg := range decode(t, session) {
+			c.Add(msg)
+		}
+	}
+	st := c.Stats()
+
+	if st.Sessions != 2 || st.Turns != 4 {
+		t.Errorf("sessions %d, turns %d, want 2 and 2+2", st.Sessions, st.Turns)
+	}
+	if st.ToolCalls != 5 || st.ToolErrors != 2 {
+		t.Errorf("tool calls %d, errors %d, want 5 and 2", st.ToolCalls, st.ToolErrors)
+	}
+	if len(st.Tools) == 0 || st.Tools[0] != (ToolStats{Name: "Read", Calls: 2, Errors: 1, ErrorRate: 0.5}) {
+		t.Errorf("tools %+v, want Read first with half its calls failed", st.Tools)
+	}
+	if len(st.FilesRead) != 1 || st.FilesRead[0] != (FileCount{"main.go", 2}) {
+		t.Errorf("files read %+v, want main.go twice", st.FilesRead)
+	}
+	if len(st.FilesEdited) != 2 || st.FilesEdited[0].Path != "main.go" || st.FilesEdited[1].Path != "new.go" {
+		t.Errorf("files edited %+v, want main.go and new.go", st.FilesEdited)
+	}
+
+	// Model usage replaces the first session's message usage; the second
+	// counts each message ID once
+	expected := Tokens{Input: 125, Output: 80, CacheRead: 330, CacheCreation: 100}
+	if st.Tokens != expected {
+		t.Errorf("tokens %+v, want %+v", st.Tokens, expected)
+	}
+	if len(st.Models) != 2 || st.Models[0].Tokens != (Tokens{Input: 115, Output: 75, CacheRead: 330, CacheCreation: 100}) || st.Models[0].CostUSD != 0.4 {
+		t.Errorf("models %+v, want claude-a with both sessions' tokens, then claude-b", st.Models)
+	}
+	if ratio := 330.0 / (125 + 330 + 100); st.CacheHitRatio != ratio {
+		t.Errorf("cache hit ratio %v, want %v", st.CacheHitRatio, ratio)
+	}
+	if st.CostUSD != 0.5 || st.DurationMS != 3000 || st.DurationAPIMS != 2000 {
+		t.Errorf("cost %v, duration %d/%d, want 0.5 and 3000/2000", st.CostUSD, st.DurationMS, st.DurationAPIMS)
+	}
+	// The session without a result used a model without a price
+	if st.CostEstimated || len(st.UnpricedModels) != 1 || st.UnpricedModels[0] != "claude-a" {
+		t.Errorf("estimated %v, unpriced %v, want claude-a unpriced", st.CostEstimated, st.UnpricedModels)
+	}
+	if st.PermissionDenials != 1 || len(st.DeniedTools) != 1 || st.DeniedTools[0] != (ToolDenialCount{"Bash", 1}) {
+		t.Errorf("denials %d %+v, want one for Bash", st.PermissionDenials, st.DeniedTools)
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	c := NewCollector()
+	for _, msg := range decode(t, withResult) {
+		c.Add(msg)
+	}
+	var buf bytes.Buffer
+	if err := WriteTable(&buf, c.Stats()); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	for _, want := range []string{
+		"Turns:               2\n",
+		"Tool errors:         2 (50.0%)\n",
+		"Duration:            3s (API: 2s)\n",
+		"Cache hit ratio:     58.8%\n",
+		"Read  2      1       50.0%\n",
+		"MOST EDITED  TIMES\nmain.go      1\n",
+		"DENIED TOOL  DENIALS\nBash         1\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q\nGot:\n%s", want, output)
+		}
+	}
+}
+
+func TestEstimateCost(t *testing.T) {
+	tokens := Tokens{Input: 1_000_000, Output: 1_000_000, CacheRead: 1_000_000, CacheCreation: 1_000_000}
+	tests := []struct {
+		model string
+		want  float64
+		ok    bool
+	}{
+		{"claude-sonnet-4-5-20250929", 3 + 15 + 0.3 + 3.75, true},
+		{"claude-opus-4-5-20251101", 5 + 25 + 0.5 + 6.25, true},
+		{"claude-opus-4-1-20250805", 15 + 75 + 1.5 + 18.75, true},
+		{"claude-opus-4-20250514", 15 + 75 + 1.5 + 18.75, true},
+		{"claude-3-5-haiku-20241022", 0.8 + 4 + 0.08 + 1, true},
+		{"claude-haiku-4-5", 1 + 5 + 0.1 + 1.25, true},
+		{"us.anthropic.claude-sonnet-4-5-20250929-v1:0", 3 + 15 + 0.3 + 3.75, true},
+		{"claude-opus-4-1@20250805", 15 + 75 + 1.5 + 18.75, true},
+		{"claude-opus-5-20270101", 0, false},
+		{"claude-sonnet-4-5-preview", 0, false},
+		{"gpt-4", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := EstimateCost(tt.model, tokens)
+		if ok != tt.ok || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("EstimateCost(%q) = %v, %v, want %v, %v", tt.model, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSetPrice(t *testing.T) {
+	defer delete(prices, "claude-next")
+	SetPrice("claude-next", Price{Input: 2, Output: 10})
+	got, ok := EstimateCost("claude-next-20270101", Tokens{Input: 1_000_000, Output: 100_000})
+	if !ok || math.Abs(got-3) > 1e-9 {
+		t.Errorf("EstimateCost() = %v, %v, want 3, true", got, ok)
+	}
+}
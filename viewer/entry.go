@@ -0,0 +1,329 @@
+package viewer
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ariel-frischer/claude-clean/display"
+	"github.com/ariel-frischer/claude-clean/parser"
+)
+
+// Kind classifies an entry for coloring and filtering
+type Kind string
+
+// Entry kinds
+const (
+	KindSystem     Kind = "system"
+	KindText       Kind = "text"
+	KindThinking   Kind = "thinking"
+	KindToolUse    Kind = "tool_use"
+	KindToolResult Kind = "tool_result"
+	KindResult     Kind = "result"
+	KindUnknown    Kind = "unknown"
+)
+
+// Entry is a single row of the message list: a text block, tool call,
+// tool result or other message, with its full content for expansion
+type Entry struct {
+	Kind    Kind
+	Type    string // type of the stream message the entry came from
+	Line    int    // input line number
+	Tool    string // tool name, for tool calls and their results
+	Title   string // one-line summary shown when collapsed
+	Body    []string
+	IsError bool
+	Depth   int // subagent nesting level
+}
+
+// Builder turns stream messages into entries, pairing tool results with
+// their calls and tracking subagent nesting across messages
+type Builder struct {
+	Verbose      bool // keep system reminders in tool results
+	ShowThinking bool
+	HeadLines    int // lines shown at the start of an expanded long body, 0 for the default
+	TailLines    int // lines shown at the end of an expanded long body, 0 for the default
+
+	// Filters, as in display.Config
+	HideTools []string            // names or glob patterns of tools whose calls and results are left out
+	OnlyTools []string            // if set, the only tools whose calls and results are shown
+	Redact    []display.Redaction // replacements applied to every line of an entry
+
+	calls  map[string]*parser.ContentBlock // tool_use ID -> call
+	depths map[string]int                  // Task tool_use ID -> depth of its subagent
+}
+
+func (b *Builder) headLines() int {
+	if b.HeadLines > 0 {
+		return b.HeadLines
+	}
+	return parser.FirstLines
+}
+
+func (b *Builder) tailLines() int {
+	if b.TailLines > 0 {
+		return b.TailLines
+	}
+	return parser.LastLines
+}
+
+// Entries returns the entries for a message read from the given input line,
+// without those of hidden tools and with redactions applied
+func (b *Builder) Entries(msg *parser.StreamMessage, line int) []Entry {
+	entries := b.entries(msg, line)
+	shown := entries[:0]
+	for _, e := range entries {
+		if e.Tool != "" && b.hiddenTool(e.Tool) {
+			continue
+		}
+		e.Title = b.redact(e.Title)
+		for i, l := range e.Body {
+			e.Body[i] = b.redact(l)
+		}
+		shown = append(shown, e)
+	}
+	return shown
+}
+
+// hiddenTool reports whether the calls and results of a tool are left out
+func (b *Builder) hiddenTool(name string) bool {
+	if len(b.OnlyTools) > 0 && !display.MatchTool(b.OnlyTools, name) {
+		return true
+	}
+	return display.MatchTool(b.HideTools, name)
+}
+
+// redact applies the redactions to a line
+func (b *Builder) redact(line string) string {
+	for _, r := range b.Redact {
+		line = r.Pattern.ReplaceAllString(line, r.Replacement)
+	}
+	return line
+}
+
+func (b *Builder) entries(msg *parser.StreamMessage, line int) []Entry {
+	if b.calls == nil {
+		b.calls = make(map[string]*parser.ContentBlock)
+		b.depths = make(map[string]int)
+	}
+	depth := b.depths[msg.ParentToolUseID]
+	e := Entry{Type: msg.Type, Line: line, Depth: depth}
+
+	switch msg.Type {
+	case "system":
+		e.Kind = KindSystem
+		e.Title = strings.TrimSpace(fmt.Sprintf("%s %s", msg.Subtype, msg.Model))
+		e.Body = systemLines(msg)
+		return []Entry{e}
+	case "assistant", "user":
+		if msg.Message == nil {
+			return nil
+		}
+		var entries []Entry
+		for i := range msg.Message.Content {
+			if be, ok := b.block(e, &msg.Message.Content[i]); ok {
+				entries = append(entries, be)
+			}
+		}
+		return entries
+	case "result":
+		e.Kind = KindResult
+		e.IsError = msg.IsError
+		e.Title = fmt.Sprintf("%s, %d turns, $%.4f", durationText(msg.DurationMS), msg.NumTurns, msg.TotalCostUSD)
+		e.Body = resultLines(msg)
+		return []Entry{e}
+	case "stream_event":
+		// The complete assistant message follows its stream events
+		return nil
+	default:
+		e.Kind = KindUnknown
+		e.Title = msg.Type
+		return []Entry{e}
+	}
+}
+
+// block returns the entry for a content block of an assistant or user message
+func (b *Builder) block(e Entry, block *parser.ContentBlock) (Entry, bool) {
+	switch block.Type {
+	case "text":
+		text := strings.TrimSpace(block.Text)
+		if text == "" {
+			return e, false
+		}
+		e.Kind = KindText
+		e.Title = firstLine(text)
+		e.Body = strings.Split(text, "\n")
+	case "thinking", "redacted_thinking":
+		if !b.ShowThinking {
+			return e, false
+		}
+		e.Kind = KindThinking
+		if block.Type == "redacted_thinking" {
+			e.Title = "(redacted)"
+			break
+		}
+		text := strings.TrimSpace(block.Thinking)
+		e.Title = firstLine(text)
+		e.Body = strings.Split(text, "\n")
+	case "tool_use":
+		b.calls[block.ID] = block
+		if block.Name == "Task" || block.Name == "Agent" {
+			b.depths[block.ID] = e.Depth + 1
+		}
+		e.Kind = KindToolUse
+		e.Tool = block.Name
+		e.Title = callTitle(block)
+		e.Body = inputLines(block)
+	case "tool_result":
+		e.Kind = KindToolResult
+		e.IsError = block.IsError
+		content := parser.ContentText(block.Content)
+		if !b.Verbose {
+			content = parser.StripSystemReminders(content)
+		}
+		if content != "" {
+			e.Body = strings.Split(content, "\n")
+		}
+		if call, ok := b.calls[block.ToolUseID]; ok {
+			e.Tool = call.Name
+			e.Title = callTitle(call)
+		} else {
+			e.Title = block.ToolUseID
+		}
+		if len(e.Body) == 1 {
+			e.Title += " - 1 line"
+		} else {
+			e.Title += fmt.Sprintf(" - %d lines", len(e.Body))
+		}
+	default:
+		return e, false
+	}
+	return e, true
+}
+
+// callTitle describes a tool call as its name followed by its input summary
+func callTitle(call *parser.ContentBlock) string {
+	if summary := display.ToolSummary(call); summary != "" {
+		return fmt.Sprintf("%s (%s)", call.Name, summary)
+	}
+	return call.Name
+}
+
+// inputLines returns the full, untruncated input of a tool call, one key at a time
+func inputLines(tool *parser.ContentBlock) []string {
+	keys := make([]string, 0, len(tool.Input))
+	for key := range tool.Input {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var lines []string
+	for _, key := range keys {
+		switch v := tool.Input[key].(type) {
+		case string:
+			if !strings.Contains(v, "\n") {
+				lines = append(lines, fmt.Sprintf("%s: %s", key, v))
+				continue
+			}
+			lines = append(lines, key+":")
+			for _, l := range strings.Split(v, "\n") {
+				lines = append(lines, "  "+l)
+			}
+		case []interface{}:
+			if tool.Name == "TodoWrite" && key == "todos" {
+				lines = append(lines, key+":")
+				lines = append(lines, todoLines(v)...)
+				continue
+			}
+			lines = append(lines, jsonLines(key, v)...)
+		default:
+			lines = append(lines, jsonLines(key, v)...)
+		}
+	}
+	return lines
+}
+
+// jsonLines formats a non-string input value as indented JSON
+func jsonLines(key string, v interface{}) []string {
+	data, err := json.MarshalIndent(v, "  ", "  ")
+	if err != nil {
+		return []string{fmt.Sprintf("%s: %v", key, v)}
+	}
+	lines := strings.Split(string(data), "\n")
+	lines[0] = key + ": " + lines[0]
+	return lines
+}
+
+// todoLines formats TodoWrite items as a checklist
+func todoLines(todos []interface{}) []string {
+	var lines []string
+	for _, todo := range todos {
+		item, ok := todo.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		content, _ := item["content"].(string)
+		status, _ := item["status"].(string)
+		mark := " "
+		switch status {
+		case "completed":
+			mark = "x"
+		case "in_progress":
+			mark = "~"
+		}
+		lines = append(lines, fmt.Sprintf("  [%s] %s", mark, content))
+	}
+	return lines
+}
+
+// systemLines returns the details of a system message
+func systemLines(msg *parser.StreamMessage) []string {
+	var lines []string
+	add := func(label, value string) {
+		if value != "" {
+			lines = append(lines, fmt.Sprintf("%s: %s", label, value))
+		}
+	}
+	add("Session", msg.SessionID)
+	add("Model", msg.Model)
+	add("CWD", msg.CWD)
+	add("Version", msg.ClaudeCodeVersion)
+	if len(msg.Tools) > 0 {
+		add("Tools", strings.Join(msg.Tools, ", "))
+	}
+	return lines
+}
+
+// resultLines returns the final result text followed by the session statistics
+func resultLines(msg *parser.StreamMessage) []string {
+	var lines []string
+	if text := strings.TrimSpace(msg.Result); text != "" {
+		lines = append(lines, strings.Split(text, "\n")...)
+		lines = append(lines, "")
+	}
+	lines = append(lines,
+		fmt.Sprintf("Duration: %s (API: %s)", durationText(msg.DurationMS), durationText(msg.DurationAPIMS)),
+		fmt.Sprintf("Turns: %d", msg.NumTurns),
+		fmt.Sprintf("Cost: $%.4f", msg.TotalCostUSD),
+	)
+	if u := msg.Usage; u != nil {
+		lines = append(lines, fmt.Sprintf("Tokens: in=%d out=%d cache_read=%d cache_create=%d",
+			u.InputTokens, u.OutputTokens, u.CacheReadInputTokens, u.CacheCreationInputTokens))
+	}
+	return lines
+}
+
+func durationText(ms int) string {
+	return fmt.Sprintf("%.2fs", float64(ms)/1000)
+}
+
+// firstLine returns the first non-empty line of text
+func firstLine(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
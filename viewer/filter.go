@@ -0,0 +1,89 @@
+package viewer
+
+import (
+	"path"
+	"strings"
+)
+
+// Filter selects the entries shown in the message list. It is parsed from a
+// space or comma separated list of terms, where each term is a message type
+// or entry kind (assistant, tool_use, ...), "error", or a tool name glob
+// (Bash, mcp__*). Terms may be written as type:X or tool:X to be explicit,
+// and a leading "-" hides matching entries instead of showing only them.
+type Filter struct {
+	expr string
+	show []filterTerm
+	hide []filterTerm
+}
+
+type filterTerm struct {
+	field string // "type", "tool" or "" to match either
+	value string
+}
+
+// ParseFilter parses a filter expression; an empty expression matches everything
+func ParseFilter(expr string) Filter {
+	f := Filter{expr: strings.TrimSpace(expr)}
+	for _, word := range strings.FieldsFunc(expr, func(r rune) bool { return r == ' ' || r == ',' }) {
+		hide := strings.HasPrefix(word, "-")
+		word = strings.TrimPrefix(word, "-")
+
+		t := filterTerm{value: strings.ToLower(word)}
+		if field, value, ok := strings.Cut(t.value, ":"); ok && (field == "type" || field == "tool") {
+			t.field, t.value = field, value
+		}
+		if t.value == "" {
+			continue
+		}
+		if hide {
+			f.hide = append(f.hide, t)
+		} else {
+			f.show = append(f.show, t)
+		}
+	}
+	return f
+}
+
+// Empty reports whether the filter matches every entry
+func (f Filter) Empty() bool {
+	return len(f.show) == 0 && len(f.hide) == 0
+}
+
+// String returns the expression the filter was parsed from
+func (f Filter) String() string {
+	return f.expr
+}
+
+// Match reports whether an entry passes the filter
+func (f Filter) Match(e *Entry) bool {
+	for _, t := range f.hide {
+		if t.match(e) {
+			return false
+		}
+	}
+	if len(f.show) == 0 {
+		return true
+	}
+	for _, t := range f.show {
+		if t.match(e) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t filterTerm) match(e *Entry) bool {
+	if t.field != "tool" {
+		switch t.value {
+		case string(e.Kind), e.Type:
+			return true
+		case "error", "errors":
+			return e.IsError
+		}
+	}
+	if t.field != "type" && e.Tool != "" {
+		ok, _ := path.Match(t.value, strings.ToLower(e.Tool))
+		return ok
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package viewer
+
+import "unicode/utf8"
+
+// escapeKeys names the escape sequences sent by common terminals for special keys
+var escapeKeys = map[string]string{
+	"[A": "up", "[B": "down", "[C": "right", "[D": "left",
+	"OA": "up", "OB": "down", "OC": "right", "OD": "left",
+	"[H": "home", "[F": "end", "OH": "home", "OF": "end",
+	"[1~": "home", "[4~": "end", "[7~": "home", "[8~": "end",
+	"[5~": "pgup", "[6~": "pgdown", "[Z": "shift+tab",
+}
+
+// parseKeys splits raw terminal input into key names. Printable characters
+// are returned as themselves and other keys as "enter", "tab", "backspace",
+// "esc", "up", "down", "left", "right", "home", "end", "pgup", "pgdown",
+// "shift+tab" or "ctrl+<letter>". Unrecognized escape sequences are dropped.
+func parseKeys(b []byte) []string {
+	var keys []string
+	for len(b) > 0 {
+		switch c := b[0]; {
+		case c == '\x1b':
+			n := escapeLen(b)
+			if n == 1 {
+				keys = append(keys, "esc")
+			} else if name, ok := escapeKeys[string(b[1:n])]; ok {
+				keys = append(keys, name)
+			}
+			b = b[n:]
+			continue
+		case c == '\r' || c == '\n':
+			keys = append(keys, "enter")
+		case c == '\t':
+			keys = append(keys, "tab")
+		case c == 0x7f || c == 0x08:
+			keys = append(keys, "backspace")
+		case c > 0 && c < 0x20:
+			keys = append(keys, "ctrl+"+string(rune('a'+c-1)))
+		default:
+			r, n := utf8.DecodeRune(b)
+			if r != utf8.RuneError {
+				keys = append(keys, string(r))
+			}
+			b = b[n:]
+			continue
+		}
+		b = b[1:]
+	}
+	return keys
+}
+
+// escapeLen returns the length of the escape sequence at the start of b,
+// or 1 for a lone escape key
+func escapeLen(b []byte) int {
+	if len(b) < 3 {
+		return 1
+	}
+	switch b[1] {
+	case 'O':
+		return 3
+	case '[':
+		for i := 2; i < len(b); i++ {
+			if b[i] >= 0x40 && b[i] <= 0x7e {
+				return i + 1
+			}
+		}
+		return len(b)
+	}
+	return 1
+}
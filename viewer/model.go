@@ -0,0 +1,604 @@
+package viewer
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ariel-frischer/claude-clean/display"
+	"github.com/ariel-frischer/claude-clean/parser"
+	"github.com/fatih/color"
+	"github.com/mattn/go-runewidth"
+)
+
+// expansion is how much of an entry's body is shown
+type expansion int
+
+const (
+	collapsed expansion = iota
+	expanded            // body shown, long bodies truncated like the other styles
+	full                // entire body shown
+)
+
+// row is a single screen line of the message list
+type row struct {
+	entry int  // index into Model.entries
+	body  int  // index into the entry's body, or -1 for the title
+	more  bool // the "N more lines" marker that follows body line `body`
+}
+
+// eventsMsg delivers a batch of decoded input lines to the model
+type eventsMsg []parser.Item
+
+// doneMsg reports that the input has been read completely
+type doneMsg struct{}
+
+// resizeMsg reports the size of the terminal
+type resizeMsg struct{ width, height int }
+
+// keyMsg is a key press, named as described in parseKeys
+type keyMsg string
+
+var (
+	reverse = color.New(color.ReverseVideo)
+	labels  = map[Kind]string{
+		KindSystem:     "SYSTEM",
+		KindText:       "TEXT",
+		KindThinking:   "THINK",
+		KindToolUse:    "TOOL",
+		KindToolResult: "RESULT",
+		KindResult:     "DONE",
+		KindUnknown:    "OTHER",
+	}
+)
+
+// kindColor returns the color of the label of an entry kind. It is looked up when
+// drawing, since display.SetTheme replaces the display colors.
+func kindColor(k Kind) *color.Color {
+	switch k {
+	case KindSystem:
+		return display.BoldCyan
+	case KindText:
+		return display.BoldGreen
+	case KindToolUse:
+		return display.BoldYellow
+	case KindToolResult:
+		return display.BoldMagenta
+	case KindResult:
+		return display.BoldBlue
+	default:
+		return display.Gray
+	}
+}
+
+const helpText = "j/k move  J/K entry  enter expand  a all  e/E error  / search  n/N match  f filter  q quit"
+
+// Model holds the state of the interactive viewer. Run feeds it input and
+// key presses through Update and draws the screen returned by View.
+type Model struct {
+	builder Builder
+	entries []Entry
+	expand  []expansion
+	filter  Filter
+	search  *regexp.Regexp
+	query   string // search text as typed
+
+	rows   []row
+	cursor int // index into rows
+	top    int // first row on screen
+	width  int
+	height int
+
+	prompt  string // prompt shown while reading a search or filter, "" otherwise
+	input   string
+	status  string // message shown in the status bar until the next key press
+	loading bool   // the input is still being read
+}
+
+// New returns a viewer model that builds its entries with b
+func New(b Builder) *Model {
+	return &Model{builder: b, loading: true}
+}
+
+// Update applies a message to the model and reports whether the viewer should exit
+func (m *Model) Update(msg interface{}) bool {
+	switch msg := msg.(type) {
+	case resizeMsg:
+		m.width, m.height = msg.width, msg.height
+	case eventsMsg:
+		m.addEvents(msg)
+	case doneMsg:
+		m.loading = false
+	case keyMsg:
+		if m.key(string(msg)) {
+			return true
+		}
+	}
+	m.scroll()
+	return false
+}
+
+// addEvents appends the entries of decoded input lines, following the end of
+// the list if the cursor is on its last row
+func (m *Model) addEvents(items []parser.Item) {
+	follow := len(m.rows) > 0 && m.cursor == len(m.rows)-1
+	for _, item := range items {
+		if item.Err != nil {
+			var lineErr *parser.LineError
+			if !errors.As(item.Err, &lineErr) {
+				m.status = fmt.Sprintf("Error reading: %v", item.Err)
+				continue
+			}
+			m.add(Entry{Kind: KindUnknown, Line: lineErr.Line, Title: lineErr.Err.Error(), IsError: true})
+			continue
+		}
+		for _, e := range m.builder.Entries(item.Event.Message, item.Event.Line) {
+			m.add(e)
+		}
+	}
+	if follow && len(m.rows) > 0 {
+		m.cursor = len(m.rows) - 1
+	}
+}
+
+func (m *Model) add(e Entry) {
+	m.entries = append(m.entries, e)
+	m.expand = append(m.expand, collapsed)
+	if i := len(m.entries) - 1; m.filter.Match(&m.entries[i]) {
+		m.rows = append(m.rows, m.entryRows(i)...)
+	}
+}
+
+// key handles a key press and reports whether the viewer should exit
+func (m *Model) key(k string) bool {
+	m.status = ""
+	if m.prompt != "" {
+		m.promptKey(k)
+		return false
+	}
+
+	switch k {
+	case "q", "ctrl+c":
+		return true
+	case "j", "down":
+		m.cursor++
+	case "k", "up":
+		m.cursor--
+	case "J", "tab":
+		m.nextEntry(1)
+	case "K", "shift+tab":
+		m.nextEntry(-1)
+	case "ctrl+d", "pgdown", "ctrl+f":
+		m.cursor += m.pageSize()
+	case "ctrl+u", "pgup", "ctrl+b":
+		m.cursor -= m.pageSize()
+	case "g", "home":
+		m.cursor = 0
+	case "G", "end":
+		m.cursor = len(m.rows) - 1
+	case "enter", " ":
+		m.toggle()
+	case "a":
+		m.toggleAll()
+	case "e":
+		m.nextError(1)
+	case "E":
+		m.nextError(-1)
+	case "n":
+		m.find(1)
+	case "N":
+		m.find(-1)
+	case "/":
+		m.prompt, m.input = "/", ""
+	case "f":
+		m.prompt, m.input = "filter: ", m.filter.String()
+	case "esc":
+		m.search, m.query = nil, ""
+	case "?":
+		m.status = helpText
+	}
+	m.clampCursor()
+	return false
+}
+
+// promptKey edits the search or filter being typed
+func (m *Model) promptKey(k string) {
+	switch k {
+	case "enter":
+		if m.prompt == "/" {
+			m.setSearch(m.input)
+		} else {
+			m.setFilter(m.input)
+		}
+		m.prompt = ""
+	case "esc", "ctrl+c":
+		m.prompt = ""
+	case "backspace":
+		if r := []rune(m.input); len(r) > 0 {
+			m.input = string(r[:len(r)-1])
+		}
+	case "ctrl+u":
+		m.input = ""
+	default:
+		if r, _ := utf8.DecodeRuneInString(k); utf8.RuneCountInString(k) == 1 && unicode.IsPrint(r) {
+			m.input += k
+		}
+	}
+}
+
+func (m *Model) setSearch(text string) {
+	m.query = text
+	if text == "" {
+		m.search = nil
+		return
+	}
+	m.search = regexp.MustCompile("(?i)" + regexp.QuoteMeta(text))
+	if !m.find(1) {
+		m.status = "Pattern not found: " + text
+	}
+}
+
+func (m *Model) setFilter(expr string) {
+	m.filter = ParseFilter(expr)
+	m.rebuild()
+}
+
+// entryRows returns the screen rows of an entry in its current expansion
+func (m *Model) entryRows(i int) []row {
+	rows := []row{{entry: i, body: -1}}
+	n := len(m.entries[i].Body)
+	head, tail := m.builder.headLines(), m.builder.tailLines()
+	switch m.expand[i] {
+	case collapsed:
+		return rows
+	case expanded:
+		if n > head+tail {
+			for j := 0; j < head; j++ {
+				rows = append(rows, row{entry: i, body: j})
+			}
+			rows = append(rows, row{entry: i, body: head - 1, more: true})
+			for j := n - tail; j < n; j++ {
+				rows = append(rows, row{entry: i, body: j})
+			}
+			return rows
+		}
+	}
+	for j := 0; j < n; j++ {
+		rows = append(rows, row{entry: i, body: j})
+	}
+	return rows
+}
+
+// truncated reports whether an expanded entry hides part of its body
+func (m *Model) truncated(i int) bool {
+	return m.expand[i] == expanded && len(m.entries[i].Body) > m.builder.headLines()+m.builder.tailLines()
+}
+
+// rebuild recomputes the rows after the filter or an expansion changed,
+// keeping the cursor on the same entry where possible
+func (m *Model) rebuild() {
+	cur := row{entry: -1, body: -1}
+	if m.cursor < len(m.rows) {
+		cur = m.rows[m.cursor]
+	}
+
+	m.rows = m.rows[:0]
+	m.cursor = -1
+	for i := range m.entries {
+		if !m.filter.Match(&m.entries[i]) {
+			continue
+		}
+		if m.cursor < 0 && i >= cur.entry {
+			m.cursor = len(m.rows)
+		}
+		m.rows = append(m.rows, m.entryRows(i)...)
+	}
+	if m.cursor < 0 {
+		m.cursor = len(m.rows) - 1
+	}
+	m.clampCursor()
+	if m.cursor >= 0 && m.cursor < len(m.rows) && m.rows[m.cursor].entry == cur.entry {
+		m.moveTo(cur.entry, cur.body)
+	}
+}
+
+// moveTo puts the cursor on a line of an entry, or on its title if the line is not shown
+func (m *Model) moveTo(entry, body int) {
+	title := -1
+	for r, rw := range m.rows {
+		if rw.entry != entry {
+			continue
+		}
+		if title < 0 {
+			title = r
+		}
+		if rw.body == body && !rw.more {
+			m.cursor = r
+			return
+		}
+	}
+	if title >= 0 {
+		m.cursor = title
+	}
+}
+
+// current returns the row under the cursor
+func (m *Model) current() (row, bool) {
+	if m.cursor < 0 || m.cursor >= len(m.rows) {
+		return row{}, false
+	}
+	return m.rows[m.cursor], true
+}
+
+// toggle cycles the entry under the cursor through collapsed, expanded and,
+// for long bodies, fully expanded
+func (m *Model) toggle() {
+	cur, ok := m.current()
+	if !ok || len(m.entries[cur.entry].Body) == 0 {
+		return
+	}
+	i := cur.entry
+	switch {
+	case m.expand[i] == collapsed:
+		m.expand[i] = expanded
+	case m.truncated(i):
+		m.expand[i] = full
+	default:
+		m.expand[i] = collapsed
+	}
+	m.rebuild()
+	m.moveTo(i, -1)
+}
+
+// toggleAll expands every shown entry, or collapses them all if none is collapsed
+func (m *Model) toggleAll() {
+	next := collapsed
+	for _, rw := range m.rows {
+		if rw.body == -1 && m.expand[rw.entry] == collapsed && len(m.entries[rw.entry].Body) > 0 {
+			next = expanded
+			break
+		}
+	}
+	for _, rw := range m.rows {
+		if rw.body == -1 {
+			m.expand[rw.entry] = next
+		}
+	}
+	m.rebuild()
+}
+
+// nextEntry moves the cursor to the title of the next or previous entry
+func (m *Model) nextEntry(dir int) {
+	for r := m.cursor + dir; r >= 0 && r < len(m.rows); r += dir {
+		if m.rows[r].body == -1 {
+			m.cursor = r
+			return
+		}
+	}
+}
+
+// shownEntries returns the indexes of the entries that pass the filter and
+// the position of the entry under the cursor among them
+func (m *Model) shownEntries() ([]int, int) {
+	cur, _ := m.current()
+	var shown []int
+	pos := 0
+	for i := range m.entries {
+		if m.filter.Match(&m.entries[i]) {
+			if i == cur.entry {
+				pos = len(shown)
+			}
+			shown = append(shown, i)
+		}
+	}
+	return shown, pos
+}
+
+// nextError moves the cursor to the next or previous error entry
+func (m *Model) nextError(dir int) {
+	shown, pos := m.shownEntries()
+	for k := 1; k <= len(shown); k++ {
+		i := shown[(pos+dir*k+len(shown)*k)%len(shown)]
+		if m.entries[i].IsError {
+			m.moveTo(i, -1)
+			return
+		}
+	}
+	m.status = "No errors"
+}
+
+// find moves the cursor to the next or previous line matching the search,
+// expanding the entry if the line is hidden. It reports whether a match was found.
+func (m *Model) find(dir int) bool {
+	if m.search == nil {
+		return false
+	}
+	shown, pos := m.shownEntries()
+	if len(shown) == 0 {
+		return false
+	}
+	cur, _ := m.current()
+	n := len(shown)
+	for k := 0; k <= n; k++ {
+		i := shown[(pos+dir*k+n*(k+1))%n]
+		e := &m.entries[i]
+		for j := range len(e.Body) + 1 {
+			line := j - 1
+			if dir < 0 {
+				line = len(e.Body) - 1 - j
+			}
+			if k == 0 && (line-cur.body)*dir <= 0 {
+				continue
+			}
+			if k == n && (line-cur.body)*dir > 0 {
+				continue
+			}
+			text := e.Title
+			if line >= 0 {
+				text = e.Body[line]
+			}
+			if m.search.MatchString(text) {
+				m.reveal(i, line)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// reveal expands an entry so that a body line is shown and moves the cursor to it
+func (m *Model) reveal(i, line int) {
+	if line >= 0 {
+		n := len(m.entries[i].Body)
+		head, tail := m.builder.headLines(), m.builder.tailLines()
+		hidden := line >= head && line < n-tail
+		switch {
+		case m.expand[i] == collapsed && n > head+tail && hidden:
+			m.expand[i] = full
+		case m.expand[i] == collapsed:
+			m.expand[i] = expanded
+		case m.truncated(i) && hidden:
+			m.expand[i] = full
+		}
+		m.rebuild()
+	}
+	m.moveTo(i, line)
+}
+
+func (m *Model) clampCursor() {
+	if m.cursor >= len(m.rows) {
+		m.cursor = len(m.rows) - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+}
+
+// listHeight is the number of screen lines available to the message list
+func (m *Model) listHeight() int {
+	return max(m.height-1, 1)
+}
+
+func (m *Model) pageSize() int {
+	return max(m.listHeight()-1, 1)
+}
+
+// scroll adjusts the first row on screen so the cursor stays visible
+func (m *Model) scroll() {
+	h := m.listHeight()
+	if m.cursor < m.top {
+		m.top = m.cursor
+	}
+	if m.cursor >= m.top+h {
+		m.top = m.cursor - h + 1
+	}
+	if m.top > len(m.rows)-h {
+		m.top = max(len(m.rows)-h, 0)
+	}
+}
+
+func (m *Model) View() string {
+	if m.width == 0 || m.height == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	h := m.listHeight()
+	for r := m.top; r < m.top+h; r++ {
+		if r < len(m.rows) {
+			sb.WriteString(m.renderRow(r))
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString(m.statusLine())
+	return sb.String()
+}
+
+// renderRow renders a single row of the list, fitted to the screen width
+func (m *Model) renderRow(r int) string {
+	rw := m.rows[r]
+	e := &m.entries[rw.entry]
+	indent := strings.Repeat("  ", e.Depth)
+
+	var prefix, label, text string
+	var labelColor *color.Color
+	switch {
+	case rw.more:
+		prefix = fmt.Sprintf("%6s%s    ", "", indent)
+		text = fmt.Sprintf("... (%d more lines, enter to show all) ...",
+			len(e.Body)-m.builder.headLines()-m.builder.tailLines())
+	case rw.body >= 0:
+		prefix = fmt.Sprintf("%6s%s    ", "", indent)
+		text = strings.ReplaceAll(e.Body[rw.body], "\t", "    ")
+	default:
+		marker := " "
+		if len(e.Body) > 0 {
+			marker = "▸"
+			if m.expand[rw.entry] != collapsed {
+				marker = "▾"
+			}
+		}
+		prefix = fmt.Sprintf("%5d %s%s ", e.Line, indent, marker)
+		label, labelColor = fmt.Sprintf("%-7s", labels[e.Kind]), kindColor(e.Kind)
+		if e.IsError {
+			label, labelColor = fmt.Sprintf("%-7s", "ERROR"), display.BoldRed
+		}
+		text = e.Title
+	}
+
+	avail := m.width - runewidth.StringWidth(prefix) - runewidth.StringWidth(label)
+	text = runewidth.Truncate(text, max(avail, 0), "…")
+
+	if r == m.cursor {
+		line := prefix + label + text
+		pad := max(m.width-runewidth.StringWidth(line), 0)
+		return reverse.Sprint(line + strings.Repeat(" ", pad))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(display.Gray.Sprint(prefix))
+	if label != "" {
+		sb.WriteString(labelColor.Sprint(label))
+	}
+	if rw.more {
+		sb.WriteString(display.Gray.Sprint(text))
+	} else {
+		sb.WriteString(m.highlight(text))
+	}
+	return sb.String()
+}
+
+// highlight marks the search matches in text
+func (m *Model) highlight(text string) string {
+	if m.search == nil {
+		return text
+	}
+	return m.search.ReplaceAllStringFunc(text, func(s string) string {
+		return reverse.Sprint(s)
+	})
+}
+
+// statusLine renders the prompt being typed, or the position, filter and search state
+func (m *Model) statusLine() string {
+	if m.prompt != "" {
+		return m.prompt + m.input + "█"
+	}
+	if m.status != "" {
+		return runewidth.Truncate(m.status, m.width, "…")
+	}
+
+	parts := []string{fmt.Sprintf("%d/%d", min(m.cursor+1, len(m.rows)), len(m.rows))}
+	if !m.filter.Empty() {
+		parts = append(parts, "filter: "+m.filter.String())
+	}
+	if m.search != nil {
+		parts = append(parts, "search: "+m.query)
+	}
+	if m.loading {
+		parts = append(parts, "reading...")
+	}
+	parts = append(parts, "? help")
+	return display.Gray.Sprint(runewidth.Truncate(strings.Join(parts, "  "), m.width, "…"))
+}
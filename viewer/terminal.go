@@ -0,0 +1,78 @@
+package viewer
+
+import (
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// terminal is the screen of the viewer: the controlling terminal in raw mode,
+// showing the alternate screen so the user's scrollback is left untouched.
+// It is opened directly rather than through stdin and stdout, which may carry
+// the session being viewed.
+type terminal struct {
+	in    *os.File
+	out   *os.File
+	state *term.State
+}
+
+func openTerminal() (*terminal, error) {
+	in, out, err := openTTY()
+	if err != nil {
+		return nil, err
+	}
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		closeTTY(in, out)
+		return nil, err
+	}
+	t := &terminal{in: in, out: out, state: state}
+	t.out.WriteString("\x1b[?1049h\x1b[?25l")
+	return t, nil
+}
+
+// close restores the terminal to the state it was opened in
+func (t *terminal) close() {
+	t.out.WriteString("\x1b[?25h\x1b[?1049l")
+	term.Restore(int(t.in.Fd()), t.state)
+	closeTTY(t.in, t.out)
+}
+
+// size returns the current size of the terminal as a resizeMsg
+func (t *terminal) size() resizeMsg {
+	width, height, err := term.GetSize(int(t.out.Fd()))
+	if err != nil {
+		return resizeMsg{width: 80, height: 24}
+	}
+	return resizeMsg{width: width, height: height}
+}
+
+// draw replaces the screen with the given lines
+func (t *terminal) draw(screen string) {
+	var sb strings.Builder
+	sb.WriteString("\x1b[H")
+	sb.WriteString(strings.ReplaceAll(screen, "\n", "\x1b[K\r\n"))
+	sb.WriteString("\x1b[K\x1b[J")
+	t.out.WriteString(sb.String())
+}
+
+// keys reads key presses in a new goroutine. The channel is closed when the
+// terminal can no longer be read.
+func (t *terminal) keys() <-chan string {
+	ch := make(chan string, 16)
+	go func() {
+		defer close(ch)
+		buf := make([]byte, 256)
+		for {
+			n, err := t.in.Read(buf)
+			for _, key := range parseKeys(buf[:n]) {
+				ch <- key
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package viewer
+
+import "os"
+
+// openTTY opens the controlling terminal for reading keys and drawing the screen
+func openTTY() (in, out *os.File, err error) {
+	f, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, f, nil
+}
+
+func closeTTY(in, out *os.File) {
+	in.Close()
+}
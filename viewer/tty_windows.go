@@ -0,0 +1,35 @@
+//go:build windows
+
+package viewer
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// openTTY opens the console for reading keys and drawing the screen, with
+// virtual terminal processing enabled so escape sequences are interpreted
+func openTTY() (in, out *os.File, err error) {
+	in, err = os.OpenFile("CONIN$", os.O_RDWR, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	out, err = os.OpenFile("CONOUT$", os.O_RDWR, 0)
+	if err != nil {
+		in.Close()
+		return nil, nil, err
+	}
+
+	var mode uint32
+	handle := windows.Handle(out.Fd())
+	if windows.GetConsoleMode(handle, &mode) == nil {
+		windows.SetConsoleMode(handle, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING)
+	}
+	return in, out, nil
+}
+
+func closeTTY(in, out *os.File) {
+	in.Close()
+	out.Close()
+}
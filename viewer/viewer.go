@@ -0,0 +1,88 @@
+// Package viewer implements the interactive full-screen viewer of cclean view.
+// Messages are shown as a list of one-line entries that can be expanded to
+// their full, untruncated content, searched and filtered.
+package viewer
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/ariel-frischer/claude-clean/parser"
+)
+
+// maxBatch is the most input lines applied to the model between redraws
+const maxBatch = 1000
+
+// resizeInterval is how often the terminal size is checked
+const resizeInterval = 250 * time.Millisecond
+
+// Run shows the messages read from r until the user quits. Keys are read
+// from the terminal rather than stdin, so r may be stdin receiving a live stream.
+func Run(r io.Reader, b Builder) error {
+	t, err := openTerminal()
+	if err != nil {
+		return err
+	}
+	defer t.close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	items := parser.NewDecoder(r).Events(ctx)
+	keys := t.keys()
+	ticker := time.NewTicker(resizeInterval)
+	defer ticker.Stop()
+
+	m := New(b)
+	size := t.size()
+	m.Update(size)
+	t.draw(m.View())
+
+	for {
+		select {
+		case item, ok := <-items:
+			if !ok {
+				items = nil
+				m.Update(doneMsg{})
+				break
+			}
+			batch, done := receive(item, items)
+			m.Update(batch)
+			if done {
+				items = nil
+				m.Update(doneMsg{})
+			}
+		case key, ok := <-keys:
+			if !ok || m.Update(keyMsg(key)) {
+				return nil
+			}
+		case <-ticker.C:
+			if s := t.size(); s != size {
+				size = s
+				m.Update(size)
+			} else {
+				continue
+			}
+		}
+		t.draw(m.View())
+	}
+}
+
+// receive collects the decoded lines that are already available, up to
+// maxBatch, so a large file is not redrawn once per line. It reports whether
+// the input has been read completely.
+func receive(first parser.Item, items <-chan parser.Item) (eventsMsg, bool) {
+	batch := eventsMsg{first}
+	for len(batch) < maxBatch {
+		select {
+		case item, ok := <-items:
+			if !ok {
+				return batch, true
+			}
+			batch = append(batch, item)
+		default:
+			return batch, false
+		}
+	}
+	return batch, false
+}
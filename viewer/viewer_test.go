@@ -0,0 +1,336 @@
+package viewer
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ariel-frischer/claude-clean/display"
+	"github.com/ariel-frischer/claude-clean/parser"
+	"github.com/fatih/color"
+)
+
+// session is a short stream with a subagent, a failing tool call and a long tool result
+var session = strings.Join([]string{
+	`{"type":"system","subtype":"init","model":"claude-sonnet-4-5","cwd":"/repo","session_id":"s1"}`,
+	`{"type":"assistant","message":{"id":"m1","content":[{"type":"text","text":"Let me look around."},{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"ls -la"}}]}}`,
+	`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"` + numberedLines(50) + `"}]}}`,
+	`{"type":"assistant","message":{"id":"m2","content":[{"type":"tool_use","id":"t2","name":"Task","input":{"subagent_type":"Explore","description":"Find tests"}}]}}`,
+	`{"type":"assistant","parent_tool_use_id":"t2","message":{"id":"m3","content":[{"type":"tool_use","id":"t3","name":"Read","input":{"file_path":"/repo/missing.go"}}]}}`,
+	`{"type":"user","parent_tool_use_id":"t2","message":{"content":[{"type":"tool_result","tool_use_id":"t3","is_error":true,"content":"File does not exist."}]}}`,
+	`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t2","content":[{"type":"text","text":"No tests found"}]}]}}`,
+	`{"type":"result","subtype":"success","duration_ms":1500,"num_turns":3,"total_cost_usd":0.01,"result":"Done"}`,
+}, "\n")
+
+// numberedLines returns n JSON-escaped lines "line 1" to "line n"
+func numberedLines(n int) string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i+1)
+	}
+	return strings.Join(lines, `\n`)
+}
+
+func decode(t *testing.T, input string) eventsMsg {
+	t.Helper()
+	var items eventsMsg
+	for ev, err := range parser.NewDecoder(strings.NewReader(input)).All() {
+		items = append(items, parser.Item{Event: ev, Err: err})
+	}
+	return items
+}
+
+func newModel(t *testing.T) *Model {
+	t.Helper()
+	m := New(Builder{})
+	m.Update(resizeMsg{width: 80, height: 20})
+	m.Update(decode(t, session))
+	m.Update(doneMsg{})
+	m.Update(key("g"))
+	return m
+}
+
+func key(s string) keyMsg {
+	return keyMsg(s)
+}
+
+func typeText(m *Model, text string) {
+	for _, r := range text {
+		m.Update(key(string(r)))
+	}
+	m.Update(key("enter"))
+}
+
+func TestBuilderEntries(t *testing.T) {
+	var b Builder
+	var entries []Entry
+	for _, item := range decode(t, session) {
+		entries = append(entries, b.Entries(item.Event.Message, item.Event.Line)...)
+	}
+
+	expected := []struct {
+		kind    Kind
+		tool    string
+		title   string
+		isError bool
+		depth   int
+	}{
+		{KindSystem, "", "init claude-sonnet-4-5", false, 0},
+		{KindText, "", "Let me look around.", false, 0},
+		{KindToolUse, "Bash", "Bash (ls -la)", false, 0},
+		{KindToolResult, "Bash", "Bash (ls -la) - 50 lines", false, 0},
+		{KindToolUse, "Task", "Task (Find tests)", false, 0},
+		{KindToolUse, "Read", "Read (/repo/missing.go)", false, 1},
+		{KindToolResult, "Read", "Read (/repo/missing.go) - 1 line", true, 1},
+		{KindToolResult, "Task", "Task (Find tests) - 1 line", false, 0},
+		{KindResult, "", "1.50s, 3 turns, $0.0100", false, 0},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(expected))
+	}
+	for i, want := range expected {
+		e := entries[i]
+		if e.Kind != want.kind || e.Tool != want.tool || e.Title != want.title || e.IsError != want.isError || e.Depth != want.depth {
+			t.Errorf("entry %d = {%s %q %q %v %d}, want {%s %q %q %v %d}", i,
+				e.Kind, e.Tool, e.Title, e.IsError, e.Depth,
+				want.kind, want.tool, want.title, want.isError, want.depth)
+		}
+	}
+
+	if body := entries[7].Body; len(body) != 1 || body[0] != "No tests found" {
+		t.Errorf("Task result body = %q, want text of the content blocks", body)
+	}
+	if body := entries[2].Body; len(body) != 1 || body[0] != "command: ls -la" {
+		t.Errorf("Bash input body = %q", body)
+	}
+}
+
+func TestBuilderFilters(t *testing.T) {
+	b := Builder{
+		HideTools: []string{"Bash"},
+		Redact:    []display.Redaction{{Pattern: regexp.MustCompile(`/repo/\w+`), Replacement: "[path]"}},
+	}
+	var entries []Entry
+	for _, item := range decode(t, session) {
+		entries = append(entries, b.Entries(item.Event.Message, item.Event.Line)...)
+	}
+
+	var titles []string
+	for _, e := range entries {
+		if e.Tool == "Bash" {
+			t.Errorf("entry %q of a hidden tool", e.Title)
+		}
+		titles = append(titles, e.Title)
+		for _, l := range append([]string{e.Title}, e.Body...) {
+			if strings.Contains(l, "/repo/") {
+				t.Errorf("entry %q has an unredacted line %q", e.Title, l)
+			}
+		}
+	}
+	if !slices.Contains(titles, "Read ([path].go)") {
+		t.Errorf("titles %q, want the redacted Read call", titles)
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	bash := &Entry{Kind: KindToolUse, Type: "assistant", Tool: "Bash"}
+	mcp := &Entry{Kind: KindToolResult, Type: "user", Tool: "mcp__github__get_issue", IsError: true}
+	text := &Entry{Kind: KindText, Type: "assistant"}
+
+	tests := []struct {
+		expr     string
+		expected [3]bool // bash, mcp, text
+	}{
+		{"", [3]bool{true, true, true}},
+		{"Bash", [3]bool{true, false, false}},
+		{"bash", [3]bool{true, false, false}},
+		{"mcp__*", [3]bool{false, true, false}},
+		{"assistant", [3]bool{true, false, true}},
+		{"type:text", [3]bool{false, false, true}},
+		{"tool_use, text", [3]bool{true, false, true}},
+		{"error", [3]bool{false, true, false}},
+		{"-Bash", [3]bool{false, true, true}},
+		{"assistant -tool:Bash", [3]bool{false, false, true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			f := ParseFilter(tt.expr)
+			got := [3]bool{f.Match(bash), f.Match(mcp), f.Match(text)}
+			if got != tt.expected {
+				t.Errorf("ParseFilter(%q) matches %v, want %v", tt.expr, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestModelExpand(t *testing.T) {
+	m := newModel(t)
+	if len(m.rows) != 9 {
+		t.Fatalf("got %d rows, want one per entry", len(m.rows))
+	}
+
+	// Move to the long Bash result and expand it: truncated like the other styles
+	for range 3 {
+		m.Update(key("J"))
+	}
+	m.Update(key("enter"))
+	want := 9 + parser.FirstLines + 1 + parser.LastLines
+	if len(m.rows) != want {
+		t.Fatalf("expanded: got %d rows, want %d", len(m.rows), want)
+	}
+	if marker := m.rows[4+parser.FirstLines]; !marker.more {
+		t.Errorf("row %+v after the first lines, want the truncation marker", marker)
+	}
+
+	// Expanding again shows every line
+	m.Update(key("enter"))
+	if len(m.rows) != 9+50 {
+		t.Errorf("fully expanded: got %d rows, want %d", len(m.rows), 9+50)
+	}
+
+	// And once more collapses it
+	m.Update(key("enter"))
+	if len(m.rows) != 9 {
+		t.Errorf("collapsed: got %d rows, want 9", len(m.rows))
+	}
+	if cur, _ := m.current(); cur.entry != 3 || cur.body != -1 {
+		t.Errorf("cursor on entry %d line %d, want the title of entry 3", cur.entry, cur.body)
+	}
+}
+
+func TestModelExpandLimits(t *testing.T) {
+	m := New(Builder{HeadLines: 3, TailLines: 2})
+	m.Update(resizeMsg{width: 80, height: 20})
+	m.Update(decode(t, session))
+	m.Update(key("g"))
+	for range 3 {
+		m.Update(key("J"))
+	}
+
+	// The configured head and tail limits replace the defaults
+	m.Update(key("enter"))
+	if want := 9 + 3 + 1 + 2; len(m.rows) != want {
+		t.Fatalf("expanded: got %d rows, want %d", len(m.rows), want)
+	}
+	marker := m.rows[4+3]
+	if !marker.more {
+		t.Fatalf("row %+v after the first lines, want the truncation marker", marker)
+	}
+	if text := m.renderRow(4 + 3); !strings.Contains(text, "45 more lines") {
+		t.Errorf("marker %q, want 45 more lines", text)
+	}
+}
+
+func TestModelNextError(t *testing.T) {
+	m := newModel(t)
+	m.Update(key("e"))
+	if cur, _ := m.current(); !m.entries[cur.entry].IsError {
+		t.Fatalf("cursor on entry %d, want the failed Read", cur.entry)
+	}
+	m.Update(key("e"))
+	if cur, _ := m.current(); cur.entry != 6 {
+		t.Errorf("next error wrapped to entry %d, want 6", cur.entry)
+	}
+}
+
+func TestModelSearch(t *testing.T) {
+	m := newModel(t)
+	m.Update(key("/"))
+	typeText(m, "LINE 30")
+
+	cur, _ := m.current()
+	if cur.entry != 3 || cur.body != 29 {
+		t.Fatalf("cursor on entry %d line %d, want entry 3 line 29", cur.entry, cur.body)
+	}
+	if m.expand[3] != full {
+		t.Errorf("a match in the truncated middle should expand the entry fully")
+	}
+
+	// The next match wraps around to the same line
+	m.Update(key("n"))
+	if next, _ := m.current(); next != cur {
+		t.Errorf("n moved to %+v, want %+v", next, cur)
+	}
+
+	m.Update(key("/"))
+	typeText(m, "no such text")
+	if !strings.Contains(m.View(), "Pattern not found") {
+		t.Errorf("missing not found status:\n%s", m.View())
+	}
+}
+
+func TestModelFilter(t *testing.T) {
+	m := newModel(t)
+	m.Update(key("f"))
+	typeText(m, "Read")
+
+	if len(m.rows) != 2 {
+		t.Fatalf("got %d rows, want the Read call and its result", len(m.rows))
+	}
+	for _, rw := range m.rows {
+		if m.entries[rw.entry].Tool != "Read" {
+			t.Errorf("row for %q passed the filter", m.entries[rw.entry].Tool)
+		}
+	}
+	if !strings.Contains(m.View(), "filter: Read") {
+		t.Errorf("status line does not show the filter:\n%s", m.View())
+	}
+
+	// New entries are filtered as they arrive
+	m.Update(decode(t, `{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t9","name":"Read","input":{"file_path":"a.go"}},{"type":"tool_use","id":"t10","name":"Bash","input":{"command":"ls"}}]}}`))
+	if len(m.rows) != 3 {
+		t.Errorf("got %d rows after new input, want 3", len(m.rows))
+	}
+
+	m.Update(key("f"))
+	m.Update(key("ctrl+u"))
+	m.Update(key("enter"))
+	if len(m.rows) != 11 {
+		t.Errorf("got %d rows after clearing the filter, want 11", len(m.rows))
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"jk", []string{"j", "k"}},
+		{"\x1b[A\x1b[B", []string{"up", "down"}},
+		{"\x1bOA", []string{"up"}},
+		{"\x1b[5~\x1b[6~", []string{"pgup", "pgdown"}},
+		{"\x1b", []string{"esc"}},
+		{"\r\x7f\t\x1b[Z", []string{"enter", "backspace", "tab", "shift+tab"}},
+		{"\x03\x15", []string{"ctrl+c", "ctrl+u"}},
+		{"é/", []string{"é", "/"}},
+		{"\x1b[200~", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%q", tt.input), func(t *testing.T) {
+			got := parseKeys([]byte(tt.input))
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("parseKeys(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLabelColorsFollowTheme(t *testing.T) {
+	defer display.SetTheme(display.Themes[display.DefaultTheme])
+	if err := display.SetTheme(display.Themes["light"]); err != nil {
+		t.Fatal(err)
+	}
+	for kind, want := range map[Kind]*color.Color{
+		KindSystem:     display.BoldCyan,
+		KindToolUse:    display.BoldYellow,
+		KindToolResult: display.BoldMagenta,
+	} {
+		if kindColor(kind) != want {
+			t.Errorf("kindColor(%v) is not the color of the current theme", kind)
+		}
+	}
+}